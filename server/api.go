@@ -1,10 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm/ollama"
+	summaryCommand "github.com/EgorTarasov/summary/server/internal/commands/summary"
+	"github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
 )
 
 // ServeHTTP demonstrates a plugin that handles HTTP requests by greeting the world.
@@ -15,8 +27,16 @@ func (p *Plugin) ServeHTTP(c *plugin.Context, w http.ResponseWriter, r *http.Req
 	// Middleware to require that the user is logged in
 	router.Use(p.MattermostAuthorizationRequired)
 
-	_ = router.PathPrefix("/api/v1").Subrouter()
+	apiRouter := router.PathPrefix("/api/v1").Subrouter()
+
+	apiRouter.HandleFunc("/summary/thread/{rootId}", p.handleSummarizeThread).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/summary/channel/{channelId}", p.handleSummarizeChannel).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/summary/actions", p.handleSummaryAction).Methods(http.MethodPost)
+	apiRouter.HandleFunc("/summary/{id}", p.handleGetSummary).Methods(http.MethodGet)
 
+	adminRouter := apiRouter.NewRoute().Subrouter()
+	adminRouter.Use(p.SystemAdminRequired)
+	adminRouter.HandleFunc("/models", p.handleListModels).Methods(http.MethodGet)
 
 	router.ServeHTTP(w, r)
 }
@@ -33,4 +53,261 @@ func (p *Plugin) MattermostAuthorizationRequired(next http.Handler) http.Handler
 	})
 }
 
+func (p *Plugin) SystemAdminRequired(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Header.Get("Mattermost-User-ID")
+		if !p.API.HasPermissionTo(userID, model.PermissionManageSystem) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// modelsResponse is the body of GET /api/v1/models.
+type modelsResponse struct {
+	Provider string             `json:"provider"`
+	Current  string             `json:"current"`
+	Models   []ollama.ModelInfo `json:"models"`
+}
+
+// handleListModels lists the models installed on the configured Ollama
+// server so that administrators can pick one in the System Console.
+func (p *Plugin) handleListModels(w http.ResponseWriter, r *http.Request) {
+	c := p.getConfiguration()
+	if c.LLMProvider != providerOllama {
+		http.Error(w, "Model discovery is only supported for the Ollama provider", http.StatusBadRequest)
+		return
+	}
+
+	provider, err := ollama.New(ollama.WithHost(c.OllamaURL), ollama.WithModel(c.OllamaModel))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(c.RequestTimeout)*time.Second)
+	defer cancel()
+	models, err := provider.Models(ctx)
+	if err != nil {
+		p.API.LogError("Failed to list ollama models", "error", err.Error())
+		http.Error(w, "Failed to list models", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(modelsResponse{
+		Provider: c.LLMProvider,
+		Current:  c.OllamaModel,
+		Models:   models,
+	}); err != nil {
+		p.API.LogError("Failed to write models response", "error", err.Error())
+	}
+}
+
+// summaryJobResponse is the body of the summary endpoints. Result holds the
+// summary once the job is done.
+type summaryJobResponse struct {
+	ID        string          `json:"id"`
+	Status    jobs.Status     `json:"status"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt int64           `json:"created_at"`
+	UpdatedAt int64           `json:"updated_at"`
+}
+
+func newSummaryJobResponse(job *jobs.Job) summaryJobResponse {
+	response := summaryJobResponse{
+		ID:        job.ID,
+		Status:    job.Status,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+	if json.Valid([]byte(job.Result)) {
+		response.Result = json.RawMessage(job.Result)
+	}
+	return response
+}
+
+// handleSummarizeThread enqueues a summary of the thread. The root ID may be
+// the ID of any post in the thread.
+func (p *Plugin) handleSummarizeThread(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	postID := mux.Vars(r)["rootId"]
+	if !model.IsValidId(postID) {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	post, err := p.authorizer.AuthorizeThread(userID, postID)
+	if errors.Is(err, summary.ErrForbidden) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Thread not found", http.StatusNotFound)
+		return
+	}
+
+	rootID := post.Id
+	if post.RootId != "" {
+		rootID = post.RootId
+	}
+	p.requestSummary(w, r, summaryCommand.SummaryRequest{
+		UserID:    userID,
+		ChannelID: post.ChannelId,
+		RootID:    rootID,
+	})
+}
+
+// handleSummarizeChannel enqueues a summary of the channel. The optional
+// since (unix millis) and limit query parameters select the posts.
+func (p *Plugin) handleSummarizeChannel(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	channelID := mux.Vars(r)["channelId"]
+	if !model.IsValidId(channelID) {
+		http.Error(w, "Invalid channel ID", http.StatusBadRequest)
+		return
+	}
+
+	request := summaryCommand.SummaryRequest{UserID: userID, ChannelID: channelID}
+	query := r.URL.Query()
+	if value := query.Get("since"); value != "" {
+		since, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || since < 0 {
+			http.Error(w, "since must be a unix timestamp in milliseconds", http.StatusBadRequest)
+			return
+		}
+		request.Since = since
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		request.Limit = limit
+	}
+
+	if !p.authorizeChannel(w, userID, channelID) {
+		return
+	}
+	p.requestSummary(w, r, request)
+}
+
+// requestSummary enqueues the summary job and answers with its ID. The
+// optional lang query parameter overrides the summary language.
+func (p *Plugin) requestSummary(w http.ResponseWriter, r *http.Request, request summaryCommand.SummaryRequest) {
+	if language := r.URL.Query().Get("lang"); language != "" {
+		if !summary.IsSupportedLanguage(language) {
+			http.Error(w, "Unsupported language", http.StatusBadRequest)
+			return
+		}
+		request.Language = language
+	}
+
+	handler := p.getCommand()
+	if handler == nil {
+		http.Error(w, "Summaries are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	job, err := handler.RequestSummary(request)
+	if err != nil {
+		p.API.LogError("Failed to request summary", "error", err.Error())
+		http.Error(w, "Failed to schedule summary generation", http.StatusInternalServerError)
+		return
+	}
+	p.writeJSON(w, http.StatusAccepted, newSummaryJobResponse(job))
+}
+
+// handleSummaryAction runs the message action of a summary post the server
+// forwards on behalf of the user who clicked it. Any user can call it with a
+// body of their own, so the handler checks the post the action refers to.
+func (p *Plugin) handleSummaryAction(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid action request", http.StatusBadRequest)
+		return
+	}
+
+	handler := p.getCommand()
+	if handler == nil {
+		http.Error(w, "Summaries are not available", http.StatusServiceUnavailable)
+		return
+	}
+	p.writeJSON(w, http.StatusOK, handler.HandleAction(userID, &request))
+}
+
+// handleGetSummary returns the status of a summary job and the summary once
+// it is done. Only the user who requested the summary can get it, and only
+// while they can read the channel.
+func (p *Plugin) handleGetSummary(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if p.jobQueue == nil {
+		http.Error(w, "Summaries are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	if !model.IsValidId(id) {
+		http.Error(w, "Summary not found", http.StatusNotFound)
+		return
+	}
 
+	job, err := p.jobQueue.Get(id)
+	if err != nil {
+		p.API.LogError("Failed to get summary job", "error", err.Error())
+		http.Error(w, "Failed to get summary", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.Error(w, "Summary not found", http.StatusNotFound)
+		return
+	}
+	if job.UserID != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	channelID, ok := summaryCommand.JobChannelID(job)
+	if !ok {
+		http.Error(w, "Summary not found", http.StatusNotFound)
+		return
+	}
+	if !p.authorizeChannel(w, userID, channelID) {
+		return
+	}
+
+	p.writeJSON(w, http.StatusOK, newSummaryJobResponse(job))
+}
+
+// authorizeChannel checks that the user can read the channel and writes the
+// error response if not.
+func (p *Plugin) authorizeChannel(w http.ResponseWriter, userID, channelID string) bool {
+	err := p.authorizer.AuthorizeChannel(userID, channelID)
+	switch {
+	case errors.Is(err, summary.ErrForbidden):
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	case errors.Is(err, pluginapi.ErrNotFound):
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return false
+	case err != nil:
+		p.API.LogError("Failed to check channel access", "channel_id", channelID, "error", err.Error())
+		http.Error(w, "Failed to check channel access", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func (p *Plugin) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		p.API.LogError("Failed to write response", "error", err.Error())
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	summaryCommand "github.com/EgorTarasov/summary/server/internal/commands/summary"
+	"github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+func TestPlugin_handleListModels(t *testing.T) {
+	ollamaServer := newOllamaServer(http.StatusOK, http.StatusOK)
+	defer ollamaServer.Close()
+
+	tests := []struct {
+		name           string
+		userID         string
+		isAdmin        bool
+		provider       string
+		expectedStatus int
+	}{
+		{
+			name:           "should_list_models_for_system_admin",
+			userID:         "admin",
+			isAdmin:        true,
+			provider:       providerOllama,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "should_reject_anonymous_request",
+			provider:       providerOllama,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "should_reject_regular_user",
+			userID:         "user",
+			provider:       providerOllama,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "should_reject_other_providers",
+			userID:         "admin",
+			isAdmin:        true,
+			provider:       providerOpenAI,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("HasPermissionTo", tt.userID, model.PermissionManageSystem).Return(tt.isAdmin).Maybe()
+			api.On("LogError", mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+			c := newOllamaConfiguration(ollamaServer.URL)
+			c.LLMProvider = tt.provider
+			p := &Plugin{}
+			p.SetAPI(api)
+			p.setConfiguration(c)
+
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/models", nil)
+			if tt.userID != "" {
+				r.Header.Set("Mattermost-User-ID", tt.userID)
+			}
+			w := httptest.NewRecorder()
+
+			p.ServeHTTP(nil, w, r)
+
+			require.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response modelsResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			assert.Equal(t, "gemma3:12b", response.Current)
+			require.Len(t, response.Models, 1)
+			assert.Equal(t, "gemma3:12b", response.Models[0].Name)
+		})
+	}
+}
+
+// fakeCommand records summary requests and answers them with pending jobs.
+type fakeCommand struct {
+	requests []summaryCommand.SummaryRequest
+	actions  []*model.PostActionIntegrationRequest
+}
+
+func (f *fakeCommand) Handle(*model.CommandArgs) (*model.CommandResponse, error) {
+	return &model.CommandResponse{}, nil
+}
+
+func (f *fakeCommand) RequestSummary(request summaryCommand.SummaryRequest) (*jobs.Job, error) {
+	f.requests = append(f.requests, request)
+	return &jobs.Job{ID: model.NewId(), Status: jobs.StatusPending}, nil
+}
+
+func (f *fakeCommand) HandleAction(userID string, request *model.PostActionIntegrationRequest) *model.PostActionIntegrationResponse {
+	f.actions = append(f.actions, request)
+	return &model.PostActionIntegrationResponse{EphemeralText: "handled for " + userID}
+}
+
+func (f *fakeCommand) EnqueueDueDigests(time.Time) {}
+
+type memJobStore map[string]*jobs.Job
+
+func (m memJobStore) SaveJob(job *jobs.Job) error          { m[job.ID] = job; return nil }
+func (m memJobStore) GetJob(id string) (*jobs.Job, error)  { return m[id], nil }
+func (m memJobStore) PushJobID(string) error               { return nil }
+func (m memJobStore) PopJobID() (string, error)            { return "", nil }
+func (m memJobStore) AddRunningJobID(string) error         { return nil }
+func (m memJobStore) RemoveRunningJobID(string) error      { return nil }
+func (m memJobStore) ListRunningJobIDs() ([]string, error) { return nil, nil }
+
+func TestPlugin_summaryAPI(t *testing.T) {
+	channelID := model.NewId()
+	rootID := model.NewId()
+	replyID := model.NewId()
+
+	setup := func(t *testing.T) (*Plugin, *fakeCommand, memJobStore) {
+		api := &plugintest.API{}
+		api.On("GetChannelMember", channelID, "reader").Return(&model.ChannelMember{ChannelId: channelID, UserId: "reader"}, nil).Maybe()
+		api.On("GetChannelMember", channelID, "outsider").Return(nil, model.NewAppError("test", "not_found", nil, "", http.StatusNotFound)).Maybe()
+		api.On("GetChannel", channelID).Return(&model.Channel{Id: channelID, Type: model.ChannelTypePrivate}, nil).Maybe()
+		api.On("HasPermissionToChannel", "outsider", channelID, model.PermissionReadChannel).Return(false).Maybe()
+		api.On("GetPost", replyID).Return(&model.Post{Id: replyID, RootId: rootID, ChannelId: channelID}, nil).Maybe()
+		api.On("LogError", mock.Anything, mock.Anything, mock.Anything).Maybe()
+
+		store := memJobStore{}
+		queue, err := jobs.New(store, nil, nil)
+		require.NoError(t, err)
+
+		command := &fakeCommand{}
+		client := pluginapi.NewClient(api, &plugintest.Driver{})
+		p := &Plugin{
+			jobQueue:   queue,
+			authorizer: summary.NewAuthorizer(&client.User, &client.Channel, &client.Post),
+		}
+		p.SetAPI(api)
+		p.setCommand(command)
+		return p, command, store
+	}
+	serve := func(p *Plugin, method, url, userID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, url, nil)
+		r.Header.Set("Mattermost-User-ID", userID)
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, r)
+		return w
+	}
+
+	t.Run("should_request_summary_of_the_whole_thread", func(t *testing.T) {
+		p, command, _ := setup(t)
+
+		w := serve(p, http.MethodPost, "/api/v1/summary/thread/"+replyID+"?lang=en", "reader")
+
+		require.Equal(t, http.StatusAccepted, w.Code)
+		require.Len(t, command.requests, 1)
+		assert.Equal(t, summaryCommand.SummaryRequest{UserID: "reader", ChannelID: channelID, RootID: rootID, Language: "en"}, command.requests[0])
+		var response summaryJobResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, jobs.StatusPending, response.Status)
+		assert.NotEmpty(t, response.ID)
+	})
+
+	t.Run("should_request_channel_summary_with_filters", func(t *testing.T) {
+		p, command, _ := setup(t)
+
+		w := serve(p, http.MethodPost, "/api/v1/summary/channel/"+channelID+"?since=1741600000000&limit=50", "reader")
+
+		require.Equal(t, http.StatusAccepted, w.Code)
+		require.Len(t, command.requests, 1)
+		assert.Equal(t, summaryCommand.SummaryRequest{UserID: "reader", ChannelID: channelID, Since: 1741600000000, Limit: 50}, command.requests[0])
+	})
+
+	t.Run("should_reject_invalid_channel_filters", func(t *testing.T) {
+		p, command, _ := setup(t)
+
+		for _, query := range []string{"since=yesterday", "limit=0", "lang=xx"} {
+			w := serve(p, http.MethodPost, "/api/v1/summary/channel/"+channelID+"?"+query, "reader")
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+		assert.Empty(t, command.requests)
+	})
+
+	t.Run("should_forbid_summaries_of_unreadable_channels", func(t *testing.T) {
+		p, command, _ := setup(t)
+
+		thread := serve(p, http.MethodPost, "/api/v1/summary/thread/"+replyID, "outsider")
+		channel := serve(p, http.MethodPost, "/api/v1/summary/channel/"+channelID, "outsider")
+
+		assert.Equal(t, http.StatusForbidden, thread.Code)
+		assert.Equal(t, http.StatusForbidden, channel.Code)
+		assert.Empty(t, command.requests)
+	})
+
+	t.Run("should_return_job_result", func(t *testing.T) {
+		p, _, store := setup(t)
+		job := &jobs.Job{
+			ID:      model.NewId(),
+			Type:    "summary",
+			UserID:  "reader",
+			Payload: map[string]string{"channel_id": channelID},
+			Status:  jobs.StatusDone,
+			Result:  `{"text":"Release planning."}`,
+		}
+		store[job.ID] = job
+
+		w := serve(p, http.MethodGet, "/api/v1/summary/"+job.ID, "reader")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var response summaryJobResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, jobs.StatusDone, response.Status)
+		assert.JSONEq(t, job.Result, string(response.Result))
+
+		forbidden := serve(p, http.MethodGet, "/api/v1/summary/"+job.ID, "outsider")
+		assert.Equal(t, http.StatusForbidden, forbidden.Code)
+	})
+
+	t.Run("should_not_return_summary_of_another_user", func(t *testing.T) {
+		p, _, store := setup(t)
+		job := &jobs.Job{
+			ID:      model.NewId(),
+			Type:    "summary",
+			UserID:  "owner",
+			Payload: map[string]string{"mode": "unread", "channel_id": channelID},
+			Status:  jobs.StatusDone,
+			Result:  `{"text":"Unread messages of the owner."}`,
+		}
+		store[job.ID] = job
+
+		w := serve(p, http.MethodGet, "/api/v1/summary/"+job.ID, "reader")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NotContains(t, w.Body.String(), "Unread messages of the owner.")
+	})
+
+	t.Run("should_not_find_unknown_jobs", func(t *testing.T) {
+		p, _, _ := setup(t)
+
+		w := serve(p, http.MethodGet, "/api/v1/summary/"+model.NewId(), "reader")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("should_forward_summary_actions", func(t *testing.T) {
+		p, command, _ := setup(t)
+
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/summary/actions", strings.NewReader(`{"post_id":"post1","context":{"action":"shorter"}}`))
+		r.Header.Set("Mattermost-User-ID", "reader")
+		w := httptest.NewRecorder()
+		p.ServeHTTP(nil, w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, command.actions, 1)
+		assert.Equal(t, "post1", command.actions[0].PostId)
+		assert.Equal(t, "shorter", command.actions[0].Context["action"])
+		var response model.PostActionIntegrationResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, "handled for reader", response.EphemeralText)
+	})
+}
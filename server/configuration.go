@@ -1,9 +1,14 @@
 package main
 
 import (
+	"encoding/json"
 	"reflect"
+	"strings"
 
 	"github.com/pkg/errors"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/ptr"
+	"github.com/EgorTarasov/summary/server/internal/domain/summary"
 )
 
 // configuration captures the plugin's external configuration as exposed in the Mattermost server
@@ -20,31 +25,35 @@ import (
 type configuration struct {
 	// TODO: create seperate structs and functions for different model providers
 	// LLM Provider Configuration
-	LLMProvider   string `json:"llm_provider"`   // "ollama", "openai"
+	LLMProvider string `json:"llm_provider"` // "ollama", "openai"
 
 	// Ollama Configuration
-	OllamaURL     string `json:"ollama_url"`     // e.g., "http://localhost:11434"
-	OllamaModel   string `json:"ollama_model"`   // e.g., "llama2", "mistral", "codellama"
+	OllamaURL       string `json:"ollama_url"`        // e.g., "http://localhost:11434"
+	OllamaModel     string `json:"ollama_model"`      // e.g., "llama2", "mistral", "codellama"
+	OllamaPullModel bool   `json:"ollama_pull_model"` // Download the model if the server does not have it
 
 	// OpenAI Configuration
 	OpenAIAPIKey  string `json:"openai_api_key"`
-	OpenAIModel   string `json:"openai_model"`   // e.g., "gpt-3.5-turbo", "gpt-4"
+	OpenAIModel   string `json:"openai_model"`    // e.g., "gpt-3.5-turbo", "gpt-4"
 	OpenAIBaseURL string `json:"openai_base_url"` // For OpenAI-compatible APIs
 
 	// Summary Configuration
-	MaxTokens        int     `json:"max_tokens"`         // Maximum tokens for summary
-	Temperature      float32 `json:"temperature"`        // LLM temperature (0.0-1.0)
-	SummaryLanguage  string  `json:"summary_language"`   // "en", "ru", "auto"
-	MaxMessages      int     `json:"max_messages"`       // Max messages to process per request
+	MaxTokens       int     `json:"max_tokens"`       // Maximum tokens for summary
+	Temperature     float32 `json:"temperature"`      // LLM temperature (0.0-1.0)
+	SummaryLanguage string  `json:"summary_language"` // "en", "ru", "auto"
+	MaxMessages     int     `json:"max_messages"`     // Max messages to process per request
+	MaxImages       *int    `json:"max_images"`       // Max attached images passed to multimodal models, 0 disables
 
 	// Feature Flags
-	EnableChannelSummary bool `json:"enable_channel_summary"`
-	EnableThreadSummary  bool `json:"enable_thread_summary"`
-	EnableCaching        bool `json:"enable_caching"`
+	EnableChannelSummary    bool `json:"enable_channel_summary"`
+	EnableThreadSummary     bool `json:"enable_thread_summary"`
+	EnableCaching           bool `json:"enable_caching"`
+	EnableStructuredSummary bool `json:"enable_structured_summary"`
 
 	// Advanced Settings
-	RequestTimeout   int    `json:"request_timeout"`    // Timeout in seconds
-	SystemPrompt     string `json:"system_prompt"`      // Custom system prompt
+	RequestTimeout  int    `json:"request_timeout"`  // Timeout in seconds
+	SystemPrompt    string `json:"system_prompt"`    // Custom system prompt
+	PromptTemplates string `json:"prompt_templates"` // JSON object of named prompt templates
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -91,13 +100,38 @@ func (c *configuration) IsValid() error {
 		return errors.New("max_messages must be greater than 0")
 	}
 
+	if ptr.Get(c.MaxImages) < 0 {
+		return errors.New("max_images must not be negative")
+	}
+
 	if c.RequestTimeout <= 0 {
 		return errors.New("request_timeout must be greater than 0")
 	}
 
+	if !summary.IsSupportedLanguage(c.SummaryLanguage) {
+		return errors.Errorf("unsupported summary_language: %s", c.SummaryLanguage)
+	}
+
+	if _, err := c.promptTemplates(); err != nil {
+		return errors.Wrap(err, "invalid prompt_templates")
+	}
+
 	return nil
 }
 
+// promptTemplates parses the admin-defined prompt templates, a JSON object
+// mapping template names to text/template sources.
+func (c *configuration) promptTemplates() (summary.PromptTemplates, error) {
+	definitions := map[string]string{}
+	if strings.TrimSpace(c.PromptTemplates) != "" {
+		if err := json.Unmarshal([]byte(c.PromptTemplates), &definitions); err != nil {
+			return nil, errors.Wrap(err, "failed to decode prompt templates")
+		}
+	}
+
+	return summary.ParseTemplates(definitions)
+}
+
 // SetDefaults sets default values for configuration
 func (c *configuration) SetDefaults() {
 	if c.LLMProvider == "" {
@@ -136,6 +170,12 @@ func (c *configuration) SetDefaults() {
 		c.MaxMessages = 50
 	}
 
+	// Unlike the other limits, 0 is a valid setting that disables images, so
+	// only a missing value is defaulted.
+	if c.MaxImages == nil {
+		c.MaxImages = ptr.To(4)
+	}
+
 	if c.RequestTimeout == 0 {
 		c.RequestTimeout = 30
 	}
@@ -147,7 +187,6 @@ func (c *configuration) SetDefaults() {
 	// Enable features by default
 	c.EnableChannelSummary = true
 	c.EnableThreadSummary = true
-	c.EnableCaching = false
 }
 
 // getConfiguration retrieves the active configuration under lock, making it safe to use
@@ -208,6 +247,13 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "invalid plugin configuration")
 	}
 
+	// Apply the configuration to the running plugin, keeping the previous
+	// configuration active if the new provider is not usable.
+	if err := p.reload(configuration); err != nil {
+		p.API.LogError("Failed to apply configuration, keeping the previous one", "error", err.Error())
+		return errors.Wrap(err, "failed to apply plugin configuration")
+	}
+
 	p.setConfiguration(configuration)
 
 	return nil
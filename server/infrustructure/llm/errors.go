@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrUnavailable is returned without contacting the provider while it is
+// considered down after repeated failures.
+var ErrUnavailable = errors.New("llm provider is temporarily unavailable")
+
+// ErrImagesNotSupported is returned when images are passed to a provider or
+// model that does not accept them.
+var ErrImagesNotSupported = errors.New("llm provider does not support images")
+
+// StatusError is returned by providers when the server responds with a non-2xx
+// status code.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request may succeed when repeated: the
+// server timed out, is rate limiting or is temporarily failing.
+func (e StatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
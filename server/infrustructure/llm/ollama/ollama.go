@@ -3,20 +3,21 @@ package ollama
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/EgorTarasov/summary/server/infrustructure/llm"
 	"github.com/EgorTarasov/summary/server/infrustructure/ptr"
 	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/types/model"
 )
 
 // TODO: add system metrics such as tokens / second, len of prompts, time of response and any valueble info
-var avaliableModels map[string]struct{} = map[string]struct{}{
-	"gemma3:12b": {},
-}
 
 const (
 	defaultHost           = "http://localhost:11434"
@@ -53,16 +54,26 @@ func WithHost(host string) Option {
 	}
 }
 
+// WithModel sets the model used for generation. Whether the server actually
+// has the model is checked by EnsureModel.
 func WithModel(model string) func(c *config) (*config, error) {
 	return func(c *config) (*config, error) {
-		if _, ok := avaliableModels[model]; !ok {
-			return c, fmt.Errorf("unsupported model: %v", model)
+		if strings.TrimSpace(model) == "" {
+			return c, fmt.Errorf("model cannot be empty")
 		}
 		c.model = model
 		return c, nil
 	}
 }
 
+// WithPull makes EnsureModel download the model when the server does not have it.
+func WithPull(pull bool) Option {
+	return func(c *config) (*config, error) {
+		c.pull = pull
+		return c, nil
+	}
+}
+
 func WithSystemPrompt(propmt string) func(c *config) (*config, error) {
 	return func(c *config) (*config, error) {
 		if len(propmt) > systemPromptMaxLength {
@@ -91,6 +102,7 @@ type config struct {
 	model        string
 	systemPrompt string
 	contextSize  int
+	pull         bool
 }
 
 func (c *config) validate() error {
@@ -169,31 +181,162 @@ func (p OllamaProvider) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
+// ModelInfo describes a model installed on the Ollama server.
+type ModelInfo struct {
+	Name              string    `json:"name"`
+	Family            string    `json:"family,omitempty"`
+	ParameterSize     string    `json:"parameter_size,omitempty"`
+	QuantizationLevel string    `json:"quantization_level,omitempty"`
+	Size              int64     `json:"size"`
+	ModifiedAt        time.Time `json:"modified_at"`
+}
+
+// Models returns the models installed on the server (/api/tags).
+func (p OllamaProvider) Models(ctx context.Context) ([]ModelInfo, error) {
+	resp, err := p.api.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", wrapError(err))
+	}
+
+	models := make([]ModelInfo, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		models = append(models, ModelInfo{
+			Name:              m.Name,
+			Family:            m.Details.Family,
+			ParameterSize:     m.Details.ParameterSize,
+			QuantizationLevel: m.Details.QuantizationLevel,
+			Size:              m.Size,
+			ModifiedAt:        m.ModifiedAt,
+		})
+	}
+	return models, nil
+}
+
+// EnsureModel checks that the configured model exists on the server
+// (/api/show). If it does not and pulling is enabled, the model is downloaded;
+// this may take long, so ctx should allow for it.
+func (p OllamaProvider) EnsureModel(ctx context.Context) error {
+	_, err := p.api.Show(ctx, &api.ShowRequest{Model: p.cfg.model})
+	if err == nil {
+		return nil
+	}
+
+	var statusErr llm.StatusError
+	if !errors.As(wrapError(err), &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to get model %s: %w", p.cfg.model, wrapError(err))
+	}
+	if !p.cfg.pull {
+		return fmt.Errorf("model %s is not available on the ollama server, pull it with `ollama pull %s` or enable model pulling", p.cfg.model, p.cfg.model)
+	}
+
+	err = p.api.Pull(ctx, &api.PullRequest{Model: p.cfg.model}, func(api.ProgressResponse) error { return nil })
+	if err != nil {
+		return fmt.Errorf("failed to pull model %s: %w", p.cfg.model, wrapError(err))
+	}
+	return nil
+}
+
+func (p OllamaProvider) Model() string {
+	return p.cfg.model
+}
+
+func (p OllamaProvider) ContextSize() int {
+	return p.cfg.contextSize
+}
+
 func (p OllamaProvider) Generate(ctx context.Context, prompt string) (string, error) {
-	in := &api.GenerateRequest{
+	return p.generate(ctx, p.newGenerateRequest(prompt, false, json.RawMessage{}))
+}
+
+// GenerateJSON passes schema as the request format, which makes Ollama
+// constrain the output to the schema.
+func (p OllamaProvider) GenerateJSON(ctx context.Context, prompt string, schema json.RawMessage) (string, error) {
+	return p.generate(ctx, p.newGenerateRequest(prompt, false, schema))
+}
+
+func (p OllamaProvider) generate(ctx context.Context, in *api.GenerateRequest) (string, error) {
+	resp := strings.Builder{}
+	err := p.api.Generate(ctx, in, func(gr api.GenerateResponse) error {
+		resp.WriteString(gr.Response)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", wrapError(err))
+	}
+	return resp.String(), nil
+}
+
+func (p OllamaProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string) error) (string, error) {
+	return p.stream(ctx, p.newGenerateRequest(prompt, true, json.RawMessage{}), onChunk)
+}
+
+// SupportsImages reports whether the model has the vision capability
+// (/api/show).
+func (p OllamaProvider) SupportsImages(ctx context.Context) (bool, error) {
+	resp, err := p.api.Show(ctx, &api.ShowRequest{Model: p.cfg.model})
+	if err != nil {
+		return false, fmt.Errorf("failed to get model %s: %w", p.cfg.model, wrapError(err))
+	}
+	return slices.Contains(resp.Capabilities, model.CapabilityVision), nil
+}
+
+// GenerateWithImages streams the response to a prompt with images. Models
+// without the vision capability reject the request.
+func (p OllamaProvider) GenerateWithImages(ctx context.Context, prompt string, images [][]byte, onChunk func(chunk string) error) (string, error) {
+	in := p.newGenerateRequest(prompt, true, json.RawMessage{})
+	for _, image := range images {
+		in.Images = append(in.Images, api.ImageData(image))
+	}
+	return p.stream(ctx, in, onChunk)
+}
+
+func (p OllamaProvider) stream(ctx context.Context, in *api.GenerateRequest, onChunk func(chunk string) error) (string, error) {
+	resp := strings.Builder{}
+	err := p.api.Generate(ctx, in, func(gr api.GenerateResponse) error {
+		if gr.Response == "" {
+			return nil
+		}
+		resp.WriteString(gr.Response)
+		return onChunk(gr.Response)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to stream response: %w", wrapError(err))
+	}
+	return resp.String(), nil
+}
+
+func (p OllamaProvider) newGenerateRequest(prompt string, stream bool, format json.RawMessage) *api.GenerateRequest {
+	return &api.GenerateRequest{
 		Model:    p.cfg.model,
 		Prompt:   prompt,
 		Suffix:   "",
-		System:   "",
+		System:   p.cfg.systemPrompt,
 		Template: "",
 		Context:  []int{},
-		Stream:   ptr.To(false),
+		Stream:   ptr.To(stream),
 		Raw:      false,
-		Format:   json.RawMessage{},
+		Format:   format,
 		KeepAlive: &api.Duration{
 			Duration: time.Hour * 1,
 		},
-		Images:  []api.ImageData{},
-		Options: map[string]any{},
-		Think:   ptr.To(false),
+		Images: []api.ImageData{},
+		Options: map[string]any{
+			"num_ctx": p.cfg.contextSize,
+		},
+		Think: ptr.To(false),
 	}
-	resp := strings.Builder{}
-	err := p.api.Generate(ctx, in, func(gr api.GenerateResponse) error {
-		resp.WriteString(gr.Response)
-		return nil
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to send request")
+}
+
+// wrapError converts ollama status errors into llm.StatusError so callers can
+// tell temporary server failures apart without depending on the ollama client.
+func wrapError(err error) error {
+	var statusErr api.StatusError
+	if errors.As(err, &statusErr) {
+		message := statusErr.ErrorMessage
+		if message == "" {
+			message = statusErr.Status
+		}
+		return llm.StatusError{StatusCode: statusErr.StatusCode, Message: message}
 	}
-	return resp.String(), nil
+	return err
 }
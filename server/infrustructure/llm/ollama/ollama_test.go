@@ -65,12 +65,19 @@ func TestNew(t *testing.T) {
 			expectError: true,
 		},
 		{
-			name: "should_fail_with_unsupported_model",
+			name: "should_create_provider_with_any_model_name",
 			options: []Option{
-				WithModel("invalid-model"),
+				WithModel("llama2"),
+			},
+			expectError: false,
+		},
+		{
+			name: "should_fail_with_empty_model",
+			options: []Option{
+				WithModel(" "),
 			},
 			expectError: true,
-			errorMsg:    "unsupported model",
+			errorMsg:    "model cannot be empty",
 		},
 		{
 			name: "should_fail_with_system_prompt_too_long",
@@ -177,6 +184,32 @@ func TestOllamaProvider_Generate(t *testing.T) {
 				}))
 			},
 		},
+		{
+			name:        "should_return_server_error_message",
+			prompt:      "Hello",
+			expectError: true,
+			errorMsg:    "failed to send request: model is loading",
+			setupMockFunc: func() *httptest.Server {
+				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					json.NewEncoder(w).Encode(map[string]string{"error": "model is loading"})
+				}))
+			},
+		},
+		{
+			name:        "should_return_status_code",
+			prompt:      "Hello",
+			expectError: true,
+			errorMsg:    "unexpected status code 503",
+			setupMockFunc: func() *httptest.Server {
+				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					json.NewEncoder(w).Encode(map[string]string{})
+				}))
+			},
+		},
 		{
 			name:        "should_handle_streaming_response",
 			prompt:      "Tell me a story",
@@ -472,3 +505,283 @@ func TestOllamaProvider_Generate_RequestStructure(t *testing.T) {
 	assert.False(t, *capturedRequest.Stream)
 	assert.Equal(t, time.Hour, capturedRequest.KeepAlive.Duration)
 }
+
+func TestOllamaProvider_GenerateJSON(t *testing.T) {
+	var capturedRequest api.GenerateRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/generate" {
+			json.NewDecoder(r.Body).Decode(&capturedRequest)
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"response": `{"overview": "ok"}`, "done": true})
+		}
+	}))
+	defer server.Close()
+
+	provider, err := New(WithHost(server.URL))
+	require.NoError(t, err)
+
+	schema := json.RawMessage(`{"type":"object","properties":{"overview":{"type":"string"}}}`)
+	result, err := provider.GenerateJSON(context.Background(), "Test prompt", schema)
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"overview": "ok"}`, result)
+	assert.JSONEq(t, string(schema), string(capturedRequest.Format))
+	require.NotNil(t, capturedRequest.Stream)
+	assert.False(t, *capturedRequest.Stream)
+}
+
+func TestOllamaProvider_GenerateStream(t *testing.T) {
+	var capturedRequest api.GenerateRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/generate" {
+			json.NewDecoder(r.Body).Decode(&capturedRequest)
+
+			responses := []map[string]interface{}{
+				{"response": "Once ", "done": false},
+				{"response": "upon ", "done": false},
+				{"response": "a time...", "done": true},
+			}
+
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for _, resp := range responses {
+				json.NewEncoder(w).Encode(resp)
+			}
+		}
+	}))
+	defer server.Close()
+
+	provider, err := New(
+		WithHost(server.URL),
+		WithModel("gemma3:12b"),
+	)
+	require.NoError(t, err)
+
+	var chunks []string
+	result, err := provider.GenerateStream(context.Background(), "Tell me a story", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Once upon a time...", result)
+	assert.Equal(t, []string{"Once ", "upon ", "a time..."}, chunks)
+	require.NotNil(t, capturedRequest.Stream)
+	assert.True(t, *capturedRequest.Stream)
+}
+
+func TestOllamaProvider_Models(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/tags" {
+			response := map[string]interface{}{
+				"models": []interface{}{
+					map[string]interface{}{
+						"name": "llama2:latest",
+						"size": 3825819519,
+						"details": map[string]interface{}{
+							"family":             "llama",
+							"parameter_size":     "7B",
+							"quantization_level": "Q4_0",
+						},
+					},
+					map[string]interface{}{
+						"name": "mistral:latest",
+						"size": 4109829387,
+					},
+				},
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(response)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := New(WithHost(server.URL))
+	require.NoError(t, err)
+
+	models, err := provider.Models(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, ModelInfo{
+		Name:              "llama2:latest",
+		Family:            "llama",
+		ParameterSize:     "7B",
+		QuantizationLevel: "Q4_0",
+		Size:              3825819519,
+	}, models[0])
+	assert.Equal(t, "mistral:latest", models[1].Name)
+}
+
+func TestOllamaProvider_EnsureModel(t *testing.T) {
+	tests := []struct {
+		name        string
+		pull        bool
+		showStatus  int
+		pullStatus  int
+		expectPull  bool
+		expectError string
+	}{
+		{
+			name:       "should_accept_installed_model",
+			showStatus: http.StatusOK,
+		},
+		{
+			name:        "should_fail_for_missing_model",
+			showStatus:  http.StatusNotFound,
+			expectError: "model llama2 is not available on the ollama server",
+		},
+		{
+			name:       "should_pull_missing_model",
+			pull:       true,
+			showStatus: http.StatusNotFound,
+			pullStatus: http.StatusOK,
+			expectPull: true,
+		},
+		{
+			name:        "should_fail_when_pull_fails",
+			pull:        true,
+			showStatus:  http.StatusNotFound,
+			pullStatus:  http.StatusInternalServerError,
+			expectPull:  true,
+			expectError: "failed to pull model llama2",
+		},
+		{
+			name:        "should_not_pull_on_server_error",
+			pull:        true,
+			showStatus:  http.StatusInternalServerError,
+			expectError: "failed to get model llama2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pulled bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/api/show":
+					var req api.ShowRequest
+					json.NewDecoder(r.Body).Decode(&req)
+					assert.Equal(t, "llama2", req.Model)
+					w.WriteHeader(tt.showStatus)
+					if tt.showStatus == http.StatusNotFound {
+						json.NewEncoder(w).Encode(map[string]string{"error": "model 'llama2' not found"})
+						return
+					}
+					json.NewEncoder(w).Encode(map[string]interface{}{"details": map[string]string{"family": "llama"}})
+				case "/api/pull":
+					pulled = true
+					w.WriteHeader(tt.pullStatus)
+					if tt.pullStatus != http.StatusOK {
+						json.NewEncoder(w).Encode(map[string]string{"error": "registry unreachable"})
+						return
+					}
+					json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+				}
+			}))
+			defer server.Close()
+
+			provider, err := New(WithHost(server.URL), WithModel("llama2"), WithPull(tt.pull))
+			require.NoError(t, err)
+
+			err = provider.EnsureModel(context.Background())
+
+			assert.Equal(t, tt.expectPull, pulled)
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOllamaProvider_SupportsImages(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		capabilities []string
+		expected     bool
+		expectError  string
+	}{
+		{
+			name:         "should_support_images_with_vision_capability",
+			status:       http.StatusOK,
+			capabilities: []string{"completion", "vision"},
+			expected:     true,
+		},
+		{
+			name:         "should_not_support_images_without_vision_capability",
+			status:       http.StatusOK,
+			capabilities: []string{"completion"},
+			expected:     false,
+		},
+		{
+			name:        "should_fail_on_server_error",
+			status:      http.StatusInternalServerError,
+			expectError: "failed to get model llava",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/show" {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(tt.status)
+					if tt.status != http.StatusOK {
+						json.NewEncoder(w).Encode(map[string]string{"error": "boom"})
+						return
+					}
+					json.NewEncoder(w).Encode(map[string]interface{}{"capabilities": tt.capabilities})
+				}
+			}))
+			defer server.Close()
+
+			provider, err := New(WithHost(server.URL), WithModel("llava"))
+			require.NoError(t, err)
+
+			supported, err := provider.SupportsImages(context.Background())
+
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, supported)
+		})
+	}
+}
+
+func TestOllamaProvider_GenerateWithImages(t *testing.T) {
+	var capturedRequest api.GenerateRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/generate" {
+			json.NewDecoder(r.Body).Decode(&capturedRequest)
+
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			json.NewEncoder(w).Encode(map[string]interface{}{"response": "A stack trace.", "done": true})
+		}
+	}))
+	defer server.Close()
+
+	provider, err := New(WithHost(server.URL), WithModel("llava"))
+	require.NoError(t, err)
+
+	var chunks []string
+	result, err := provider.GenerateWithImages(context.Background(), "Describe", [][]byte{[]byte("png1"), []byte("png2")}, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "A stack trace.", result)
+	assert.Equal(t, []string{"A stack trace."}, chunks)
+	assert.Equal(t, []api.ImageData{api.ImageData("png1"), api.ImageData("png2")}, capturedRequest.Images)
+}
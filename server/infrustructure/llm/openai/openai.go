@@ -0,0 +1,435 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm"
+)
+
+const (
+	defaultBaseURL     = "https://api.openai.com/v1"
+	defaultModel       = "gpt-3.5-turbo"
+	defaultContextSize = 16000
+	minContextSize     = 1024
+	maxContextSize     = 1000000
+
+	chatCompletionsPath = "/chat/completions"
+	modelsPath          = "/models"
+
+	responseFormatJSONSchema = "json_schema"
+	responseSchemaName       = "response"
+
+	sseDataPrefix = "data:"
+	sseDone       = "[DONE]"
+
+	// maxErrorBodySize limits how much of an error response is included in returned errors.
+	maxErrorBodySize = 512
+)
+
+type Option func(c *config) (*config, error)
+
+func WithBaseURL(baseURL string) Option {
+	return func(c *config) (*config, error) {
+		if strings.TrimSpace(baseURL) == "" {
+			return c, fmt.Errorf("base URL cannot be empty")
+		}
+
+		u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+		if err != nil {
+			return c, fmt.Errorf("invalid baseURL for openai: %w", err)
+		}
+
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return c, fmt.Errorf("invalid URL scheme '%s': must be http or https", u.Scheme)
+		}
+
+		if u.Host == "" {
+			return c, fmt.Errorf("invalid URL: host cannot be empty")
+		}
+
+		c.baseURL = u
+		return c, nil
+	}
+}
+
+func WithAPIKey(apiKey string) Option {
+	return func(c *config) (*config, error) {
+		if strings.TrimSpace(apiKey) == "" {
+			return c, fmt.Errorf("api key cannot be empty")
+		}
+		c.apiKey = apiKey
+		return c, nil
+	}
+}
+
+func WithModel(model string) Option {
+	return func(c *config) (*config, error) {
+		if strings.TrimSpace(model) == "" {
+			return c, fmt.Errorf("model cannot be empty")
+		}
+		c.model = model
+		return c, nil
+	}
+}
+
+func WithSystemPrompt(prompt string) Option {
+	return func(c *config) (*config, error) {
+		c.systemPrompt = prompt
+		return c, nil
+	}
+}
+
+func WithTemperature(temperature float32) Option {
+	return func(c *config) (*config, error) {
+		if temperature < 0 || temperature > 2 {
+			return c, fmt.Errorf("temperature must be between 0 and 2, got %v", temperature)
+		}
+		c.temperature = &temperature
+		return c, nil
+	}
+}
+
+func WithMaxTokens(maxTokens int) Option {
+	return func(c *config) (*config, error) {
+		if maxTokens <= 0 {
+			return c, fmt.Errorf("max tokens must be greater than 0, got %d", maxTokens)
+		}
+		c.maxTokens = maxTokens
+		return c, nil
+	}
+}
+
+func WithContextSize(size int) Option {
+	return func(c *config) (*config, error) {
+		if size < minContextSize {
+			return c, fmt.Errorf("context size must be at least %d, got %d", minContextSize, size)
+		}
+		if size > maxContextSize {
+			return c, fmt.Errorf("context size must not exceed %d, got %d", maxContextSize, size)
+		}
+		c.contextSize = size
+		return c, nil
+	}
+}
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *config) (*config, error) {
+		if client == nil {
+			return c, fmt.Errorf("http client cannot be nil")
+		}
+		c.httpClient = client
+		return c, nil
+	}
+}
+
+type config struct {
+	baseURL      *url.URL
+	apiKey       string
+	model        string
+	systemPrompt string
+	temperature  *float32
+	maxTokens    int
+	contextSize  int
+	httpClient   *http.Client
+}
+
+func (c *config) validate() error {
+	if c.baseURL == nil {
+		return fmt.Errorf("baseURL is required")
+	}
+
+	if c.model == "" {
+		return fmt.Errorf("model is required")
+	}
+
+	if c.contextSize < minContextSize || c.contextSize > maxContextSize {
+		return fmt.Errorf("context size %d is out of valid range [%d, %d]",
+			c.contextSize, minContextSize, maxContextSize)
+	}
+
+	return nil
+}
+
+func (c *config) setDefaults() error {
+	if c.baseURL == nil {
+		defaultURL, err := url.Parse(defaultBaseURL)
+		if err != nil {
+			return fmt.Errorf("failed to parse default base URL: %w", err)
+		}
+		c.baseURL = defaultURL
+	}
+
+	if c.model == "" {
+		c.model = defaultModel
+	}
+
+	if c.contextSize == 0 {
+		c.contextSize = defaultContextSize
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	return nil
+}
+
+// OpenAIProvider talks to the OpenAI chat completions API or any server
+// exposing a compatible /v1 interface (vLLM, LM Studio, Ollama's /v1, ...).
+type OpenAIProvider struct {
+	cfg *config
+}
+
+func New(options ...Option) (*OpenAIProvider, error) {
+	cfg := &config{}
+
+	for _, opt := range options {
+		var err error
+		cfg, err = opt(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	if err := cfg.setDefaults(); err != nil {
+		return nil, fmt.Errorf("failed to set defaults: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &OpenAIProvider{
+		cfg: cfg,
+	}, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature *float32      `json:"temperature,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stream      bool          `json:"stream"`
+
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// responseFormat requests structured output validated against a JSON schema.
+type responseFormat struct {
+	Type       string     `json:"type"`
+	JSONSchema jsonSchema `json:"json_schema"`
+}
+
+type jsonSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message      chatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta        chatMessage `json:"delta"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+type modelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+type errorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+func (p OpenAIProvider) HealthCheck(ctx context.Context) error {
+	var models modelsResponse
+	if err := p.do(ctx, http.MethodGet, modelsPath, nil, &models); err != nil {
+		return fmt.Errorf("openai server is unavailable: %w", err)
+	}
+	return nil
+}
+
+func (p OpenAIProvider) Model() string {
+	return p.cfg.model
+}
+
+func (p OpenAIProvider) ContextSize() int {
+	return p.cfg.contextSize
+}
+
+func (p OpenAIProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	return p.complete(ctx, p.newChatRequest(prompt, false))
+}
+
+// GenerateJSON sets response_format to the json_schema type. Compatible
+// servers that do not support it may ignore the schema.
+func (p OpenAIProvider) GenerateJSON(ctx context.Context, prompt string, schema json.RawMessage) (string, error) {
+	in := p.newChatRequest(prompt, false)
+	in.ResponseFormat = &responseFormat{
+		Type:       responseFormatJSONSchema,
+		JSONSchema: jsonSchema{Name: responseSchemaName, Schema: schema},
+	}
+	return p.complete(ctx, in)
+}
+
+func (p OpenAIProvider) complete(ctx context.Context, in chatCompletionRequest) (string, error) {
+	var out chatCompletionResponse
+	if err := p.do(ctx, http.MethodPost, chatCompletionsPath, in, &out); err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("empty response: no choices returned")
+	}
+
+	return out.Choices[0].Message.Content, nil
+}
+
+// GenerateStream requests a server-sent events stream and forwards every
+// content delta to onChunk.
+func (p OpenAIProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string) error) (string, error) {
+	resp, err := p.send(ctx, http.MethodPost, chatCompletionsPath, p.newChatRequest(prompt, true))
+	if err != nil {
+		return "", fmt.Errorf("failed to stream response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	out := strings.Builder{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, sseDataPrefix) {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, sseDataPrefix))
+		if data == sseDone {
+			break
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		content := chunk.Choices[0].Delta.Content
+		out.WriteString(content)
+		if err := onChunk(content); err != nil {
+			return "", err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return out.String(), nil
+}
+
+func (p OpenAIProvider) newChatRequest(prompt string, stream bool) chatCompletionRequest {
+	messages := make([]chatMessage, 0, 2)
+	if p.cfg.systemPrompt != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: p.cfg.systemPrompt})
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: prompt})
+
+	return chatCompletionRequest{
+		Model:       p.cfg.model,
+		Messages:    messages,
+		Temperature: p.cfg.temperature,
+		MaxTokens:   p.cfg.maxTokens,
+		Stream:      stream,
+	}
+}
+
+func (p OpenAIProvider) do(ctx context.Context, method, path string, in, out any) error {
+	resp, err := p.send(ctx, method, path, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// send performs the request and returns the response if the server answered
+// with a 2xx status code. The caller must close the response body.
+func (p OpenAIProvider) send(ctx context.Context, method, path string, in any) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.cfg.baseURL.String()+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if p.cfg.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.cfg.apiKey)
+	}
+
+	resp, err := p.cfg.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		return nil, newStatusError(resp)
+	}
+
+	return resp, nil
+}
+
+// StatusError is returned when the server responds with a non-2xx status code.
+type StatusError = llm.StatusError
+
+func newStatusError(resp *http.Response) StatusError {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+
+	var errResp errorResponse
+	if err := json.Unmarshal(data, &errResp); err == nil && errResp.Error.Message != "" {
+		return StatusError{StatusCode: resp.StatusCode, Message: errResp.Error.Message}
+	}
+
+	return StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+}
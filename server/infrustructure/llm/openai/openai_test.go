@@ -0,0 +1,419 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []Option
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "should_create_provider_with_default_config",
+			options:     []Option{},
+			expectError: false,
+		},
+		{
+			name: "should_create_provider_with_all_valid_options",
+			options: []Option{
+				WithBaseURL("https://api.openai.com/v1"),
+				WithAPIKey("sk-test"),
+				WithModel("gpt-4"),
+				WithSystemPrompt("You are a helpful assistant"),
+				WithTemperature(0.3),
+				WithMaxTokens(1000),
+			},
+			expectError: false,
+		},
+		{
+			name: "should_fail_with_invalid_base_url",
+			options: []Option{
+				WithBaseURL("invalid-url"),
+			},
+			expectError: true,
+		},
+		{
+			name: "should_fail_with_empty_api_key",
+			options: []Option{
+				WithAPIKey(" "),
+			},
+			expectError: true,
+			errorMsg:    "api key cannot be empty",
+		},
+		{
+			name: "should_fail_with_empty_model",
+			options: []Option{
+				WithModel(""),
+			},
+			expectError: true,
+			errorMsg:    "model cannot be empty",
+		},
+		{
+			name: "should_fail_with_invalid_temperature",
+			options: []Option{
+				WithTemperature(3),
+			},
+			expectError: true,
+			errorMsg:    "temperature must be between",
+		},
+		{
+			name: "should_fail_with_invalid_max_tokens",
+			options: []Option{
+				WithMaxTokens(0),
+			},
+			expectError: true,
+			errorMsg:    "max tokens must be greater than 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := New(tt.options...)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, provider)
+				if tt.errorMsg != "" {
+					assert.Contains(t, err.Error(), tt.errorMsg)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, provider)
+				assert.NotNil(t, provider.cfg)
+			}
+		})
+	}
+}
+
+func TestNew_ConfigValidation(t *testing.T) {
+	t.Run("should_trim_trailing_slash_from_base_url", func(t *testing.T) {
+		provider, err := New(WithBaseURL("http://localhost:11434/v1/"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "http://localhost:11434/v1", provider.cfg.baseURL.String())
+	})
+
+	t.Run("should_set_default_model", func(t *testing.T) {
+		provider, err := New()
+
+		require.NoError(t, err)
+		assert.Equal(t, defaultModel, provider.cfg.model)
+	})
+}
+
+func TestOpenAIProvider_Generate(t *testing.T) {
+	tests := []struct {
+		name           string
+		prompt         string
+		expectedResult string
+		expectError    bool
+		errorMsg       string
+		handler        http.HandlerFunc
+	}{
+		{
+			name:           "should_generate_response_successfully",
+			prompt:         "Hello, how are you?",
+			expectedResult: "I'm doing well, thank you!",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/chat/completions" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				response := map[string]interface{}{
+					"id": "chatcmpl-1",
+					"choices": []interface{}{
+						map[string]interface{}{
+							"index":         0,
+							"finish_reason": "stop",
+							"message": map[string]interface{}{
+								"role":    "assistant",
+								"content": "I'm doing well, thank you!",
+							},
+						},
+					},
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(response)
+			},
+		},
+		{
+			name:        "should_fail_when_no_choices_returned",
+			prompt:      "Hello",
+			expectError: true,
+			errorMsg:    "no choices returned",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{"choices": []interface{}{}})
+			},
+		},
+		{
+			name:        "should_surface_api_error_message",
+			prompt:      "Hello",
+			expectError: true,
+			errorMsg:    "Incorrect API key provided",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error"}}`))
+			},
+		},
+		{
+			name:        "should_fail_on_invalid_json",
+			prompt:      "Hello",
+			expectError: true,
+			errorMsg:    "failed to decode response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"choices": invalid}`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			provider, err := New(
+				WithBaseURL(server.URL+"/v1"),
+				WithAPIKey("sk-test"),
+				WithModel("gpt-4"),
+			)
+			require.NoError(t, err)
+
+			result, err := provider.Generate(context.Background(), tt.prompt)
+
+			if tt.expectError {
+				assert.Error(t, err)
+				if tt.errorMsg != "" {
+					assert.Contains(t, err.Error(), tt.errorMsg)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedResult, result)
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_Generate_RequestStructure(t *testing.T) {
+	var (
+		capturedRequest chatCompletionRequest
+		capturedAuth    string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedAuth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&capturedRequest)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Test response"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(
+		WithBaseURL(server.URL+"/v1"),
+		WithAPIKey("sk-test"),
+		WithModel("gpt-4"),
+		WithSystemPrompt("You are a test assistant"),
+		WithTemperature(0.3),
+		WithMaxTokens(500),
+	)
+	require.NoError(t, err)
+
+	_, err = provider.Generate(context.Background(), "Test prompt")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bearer sk-test", capturedAuth)
+	assert.Equal(t, "gpt-4", capturedRequest.Model)
+	assert.False(t, capturedRequest.Stream)
+	assert.Equal(t, 500, capturedRequest.MaxTokens)
+	require.NotNil(t, capturedRequest.Temperature)
+	assert.InDelta(t, 0.3, *capturedRequest.Temperature, 0.0001)
+	require.Len(t, capturedRequest.Messages, 2)
+	assert.Equal(t, chatMessage{Role: "system", Content: "You are a test assistant"}, capturedRequest.Messages[0])
+	assert.Equal(t, chatMessage{Role: "user", Content: "Test prompt"}, capturedRequest.Messages[1])
+}
+
+func TestOpenAIProvider_GenerateJSON(t *testing.T) {
+	var capturedRequest chatCompletionRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&capturedRequest)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "{\"overview\": \"ok\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(WithBaseURL(server.URL+"/v1"), WithAPIKey("sk-test"))
+	require.NoError(t, err)
+
+	schema := json.RawMessage(`{"type":"object","properties":{"overview":{"type":"string"}}}`)
+	result, err := provider.GenerateJSON(context.Background(), "Test prompt", schema)
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"overview": "ok"}`, result)
+	require.NotNil(t, capturedRequest.ResponseFormat)
+	assert.Equal(t, "json_schema", capturedRequest.ResponseFormat.Type)
+	assert.JSONEq(t, string(schema), string(capturedRequest.ResponseFormat.JSONSchema.Schema))
+}
+
+func TestOpenAIProvider_Generate_ContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "late"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := New(WithBaseURL(server.URL + "/v1"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	result, err := provider.Generate(ctx, "Hello")
+
+	assert.Error(t, err)
+	assert.Empty(t, result)
+}
+
+func TestOpenAIProvider_HealthCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		expectError bool
+		handler     http.HandlerFunc
+	}{
+		{
+			name:        "should_pass_health_check_when_server_is_healthy",
+			expectError: false,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/models" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"object": "list", "data": [{"id": "gpt-4", "object": "model"}]}`))
+			},
+		},
+		{
+			name:        "should_fail_health_check_when_server_returns_500",
+			expectError: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("Internal Server Error"))
+			},
+		},
+		{
+			name:        "should_fail_health_check_when_server_returns_unauthorized",
+			expectError: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte("Unauthorized"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			provider, err := New(
+				WithBaseURL(server.URL+"/v1"),
+				WithAPIKey("sk-test"),
+			)
+			require.NoError(t, err)
+
+			err = provider.HealthCheck(context.Background())
+
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "openai server is unavailable")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOpenAIProvider_GenerateStream(t *testing.T) {
+	t.Run("should_forward_deltas_until_done", func(t *testing.T) {
+		var capturedRequest chatCompletionRequest
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&capturedRequest)
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"choices\": [{\"delta\": {\"role\": \"assistant\"}}]}\n\n"))
+			w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"Once \"}}]}\n\n"))
+			w.Write([]byte(": keep-alive\n\n"))
+			w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"upon a time\"}}]}\n\n"))
+			w.Write([]byte("data: [DONE]\n\n"))
+		}))
+		defer server.Close()
+
+		provider, err := New(WithBaseURL(server.URL + "/v1"))
+		require.NoError(t, err)
+
+		var chunks []string
+		result, err := provider.GenerateStream(context.Background(), "Tell me a story", func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.True(t, capturedRequest.Stream)
+		assert.Equal(t, "Once upon a time", result)
+		assert.Equal(t, []string{"Once ", "upon a time"}, chunks)
+	})
+
+	t.Run("should_stop_when_callback_fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"a\"}}]}\n\n"))
+			w.Write([]byte("data: {\"choices\": [{\"delta\": {\"content\": \"b\"}}]}\n\n"))
+		}))
+		defer server.Close()
+
+		provider, err := New(WithBaseURL(server.URL + "/v1"))
+		require.NoError(t, err)
+
+		calls := 0
+		_, err = provider.GenerateStream(context.Background(), "Hello", func(chunk string) error {
+			calls++
+			return assert.AnError
+		})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should_fail_on_error_status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": {"message": "Rate limit reached"}}`))
+		}))
+		defer server.Close()
+
+		provider, err := New(WithBaseURL(server.URL + "/v1"))
+		require.NoError(t, err)
+
+		_, err = provider.GenerateStream(context.Background(), "Hello", func(string) error { return nil })
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Rate limit reached")
+	})
+}
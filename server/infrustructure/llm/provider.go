@@ -2,12 +2,38 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 )
 
 type Provider interface {
-	// Generate creates a response from the given prompt.
-	// Note: This method should be used with exponential backoff retry logic
-	// to handle temporary failures, rate limits, and network issues from LLM providers.
+	// Generate creates a response from the given prompt. Providers do not retry
+	// on their own; wrap them with resilient.New to handle temporary failures,
+	// rate limits and network issues.
 	Generate(ctx context.Context, prompt string) (string, error)
+	// GenerateStream works like Generate but invokes onChunk for every piece of
+	// the response as soon as it is produced. It returns the complete response.
+	// Returning an error from onChunk aborts generation.
+	GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string) error) (string, error)
+	// GenerateJSON works like Generate but asks the model to answer with a
+	// JSON document matching schema. Models do not always comply, so callers
+	// must validate the response.
+	GenerateJSON(ctx context.Context, prompt string, schema json.RawMessage) (string, error)
 	HealthCheck(ctx context.Context) error
+	// Model returns the name of the model used for generation.
+	Model() string
+	// ContextSize returns the configured context window of the model in tokens.
+	ContextSize() int
+}
+
+// ImageProvider is implemented by providers that can pass images to
+// multimodal models. It is optional: callers check for it with a type
+// assertion and must still ask SupportsImages, since wrappers implement it
+// for any provider.
+type ImageProvider interface {
+	// SupportsImages reports whether the configured model accepts images.
+	SupportsImages(ctx context.Context) (bool, error)
+	// GenerateWithImages works like GenerateStream and passes the images,
+	// encoded as PNG, JPEG or another format the model reads, along with the
+	// prompt.
+	GenerateWithImages(ctx context.Context, prompt string, images [][]byte, onChunk func(chunk string) error) (string, error)
 }
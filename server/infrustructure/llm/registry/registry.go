@@ -0,0 +1,151 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm"
+)
+
+// entry is a registered provider together with the requests it is serving.
+type entry struct {
+	provider llm.Provider
+	inflight sync.WaitGroup
+}
+
+// Registry is an llm.Provider that forwards calls to the current provider and
+// allows replacing it at runtime. Requests that already started keep using
+// the provider they started with.
+type Registry struct {
+	mu      sync.RWMutex
+	current *entry
+
+	// drains tracks retired providers that still serve requests.
+	drains sync.WaitGroup
+}
+
+func New(provider llm.Provider) (*Registry, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("provider cannot be nil")
+	}
+	return &Registry{current: &entry{provider: provider}}, nil
+}
+
+// Swap health-checks provider and makes it the current one. If the check
+// fails, the previous provider stays in place and the error is returned. The
+// previous provider is closed, if it implements io.Closer, once the requests
+// it is serving have finished.
+func (r *Registry) Swap(ctx context.Context, provider llm.Provider) error {
+	if provider == nil {
+		return fmt.Errorf("provider cannot be nil")
+	}
+	if err := provider.HealthCheck(ctx); err != nil {
+		return fmt.Errorf("new provider failed health check: %w", err)
+	}
+
+	r.mu.Lock()
+	previous := r.current
+	r.current = &entry{provider: provider}
+	r.mu.Unlock()
+
+	r.drains.Add(1)
+	go func() {
+		defer r.drains.Done()
+		previous.inflight.Wait()
+		if closer, ok := previous.provider.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}()
+
+	return nil
+}
+
+// Wait blocks until all retired providers have finished their requests or ctx
+// is done.
+func (r *Registry) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.drains.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// acquire returns the current provider and registers a request on it. The
+// caller must call release when the request is finished.
+func (r *Registry) acquire() (*entry, func()) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e := r.current
+	e.inflight.Add(1)
+	return e, e.inflight.Done
+}
+
+func (r *Registry) load() llm.Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current.provider
+}
+
+func (r *Registry) Generate(ctx context.Context, prompt string) (string, error) {
+	e, release := r.acquire()
+	defer release()
+	return e.provider.Generate(ctx, prompt)
+}
+
+func (r *Registry) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string) error) (string, error) {
+	e, release := r.acquire()
+	defer release()
+	return e.provider.GenerateStream(ctx, prompt, onChunk)
+}
+
+func (r *Registry) GenerateJSON(ctx context.Context, prompt string, schema json.RawMessage) (string, error) {
+	e, release := r.acquire()
+	defer release()
+	return e.provider.GenerateJSON(ctx, prompt, schema)
+}
+
+// SupportsImages reports whether the current provider accepts images.
+func (r *Registry) SupportsImages(ctx context.Context) (bool, error) {
+	e, release := r.acquire()
+	defer release()
+	provider, ok := e.provider.(llm.ImageProvider)
+	if !ok {
+		return false, nil
+	}
+	return provider.SupportsImages(ctx)
+}
+
+func (r *Registry) GenerateWithImages(ctx context.Context, prompt string, images [][]byte, onChunk func(chunk string) error) (string, error) {
+	e, release := r.acquire()
+	defer release()
+	provider, ok := e.provider.(llm.ImageProvider)
+	if !ok {
+		return "", llm.ErrImagesNotSupported
+	}
+	return provider.GenerateWithImages(ctx, prompt, images, onChunk)
+}
+
+func (r *Registry) HealthCheck(ctx context.Context) error {
+	e, release := r.acquire()
+	defer release()
+	return e.provider.HealthCheck(ctx)
+}
+
+func (r *Registry) Model() string {
+	return r.load().Model()
+}
+
+func (r *Registry) ContextSize() int {
+	return r.load().ContextSize()
+}
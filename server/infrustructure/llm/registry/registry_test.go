@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	model     string
+	healthErr error
+	started   chan struct{}
+	release   chan struct{}
+	closed    atomic.Bool
+}
+
+func (f *fakeProvider) Generate(ctx context.Context, _ string) (string, error) {
+	if f.started != nil {
+		close(f.started)
+	}
+	if f.release != nil {
+		<-f.release
+	}
+	return f.model, nil
+}
+
+func (f *fakeProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string) error) (string, error) {
+	out, err := f.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return out, onChunk(out)
+}
+
+func (f *fakeProvider) GenerateJSON(ctx context.Context, prompt string, _ json.RawMessage) (string, error) {
+	return f.Generate(ctx, prompt)
+}
+
+func (f *fakeProvider) HealthCheck(context.Context) error { return f.healthErr }
+func (f *fakeProvider) Model() string                     { return f.model }
+func (f *fakeProvider) ContextSize() int                  { return 1024 }
+
+func (f *fakeProvider) Close() error {
+	f.closed.Store(true)
+	return nil
+}
+
+func TestRegistry_Swap(t *testing.T) {
+	t.Run("should_use_new_provider_after_swap", func(t *testing.T) {
+		old := &fakeProvider{model: "old"}
+		r, err := New(old)
+		require.NoError(t, err)
+
+		require.NoError(t, r.Swap(context.Background(), &fakeProvider{model: "new"}))
+
+		result, err := r.Generate(context.Background(), "prompt")
+		require.NoError(t, err)
+		assert.Equal(t, "new", result)
+		assert.Equal(t, "new", r.Model())
+
+		require.NoError(t, r.Wait(context.Background()))
+		assert.True(t, old.closed.Load())
+	})
+
+	t.Run("should_keep_previous_provider_when_health_check_fails", func(t *testing.T) {
+		old := &fakeProvider{model: "old"}
+		r, err := New(old)
+		require.NoError(t, err)
+
+		err = r.Swap(context.Background(), &fakeProvider{model: "new", healthErr: errors.New("connection refused")})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed health check")
+		assert.Equal(t, "old", r.Model())
+		assert.False(t, old.closed.Load())
+	})
+
+	t.Run("should_drain_in_flight_requests_before_closing", func(t *testing.T) {
+		old := &fakeProvider{model: "old", started: make(chan struct{}), release: make(chan struct{})}
+		r, err := New(old)
+		require.NoError(t, err)
+
+		results := make(chan string, 1)
+		go func() {
+			result, _ := r.Generate(context.Background(), "prompt")
+			results <- result
+		}()
+		<-old.started
+
+		require.NoError(t, r.Swap(context.Background(), &fakeProvider{model: "new"}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, r.Wait(ctx), context.DeadlineExceeded)
+		assert.False(t, old.closed.Load(), "provider must not be closed while serving a request")
+
+		close(old.release)
+		assert.Equal(t, "old", <-results)
+		require.NoError(t, r.Wait(context.Background()))
+		assert.True(t, old.closed.Load())
+	})
+}
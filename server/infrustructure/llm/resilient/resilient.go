@@ -0,0 +1,337 @@
+package resilient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm"
+)
+
+const (
+	defaultTimeout          = 30 * time.Second
+	defaultMaxRetries       = 3
+	defaultBaseBackoff      = 500 * time.Millisecond
+	defaultMaxBackoff       = 10 * time.Second
+	defaultFailureThreshold = 5
+	defaultCooldown         = 30 * time.Second
+)
+
+// errAttemptTimeout is the cancellation cause of an attempt that exceeded the
+// per-request timeout, as opposed to the caller giving up.
+var errAttemptTimeout = errors.New("llm request timed out")
+
+type Option func(c *config) (*config, error)
+
+// WithTimeout limits a single Generate attempt. For GenerateStream it limits
+// the wait for each next chunk, so long answers are not cut off while the
+// model keeps producing output.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) (*config, error) {
+		if timeout <= 0 {
+			return c, fmt.Errorf("timeout must be greater than 0, got %v", timeout)
+		}
+		c.timeout = timeout
+		return c, nil
+	}
+}
+
+// WithMaxRetries sets how many times a failed request is repeated.
+func WithMaxRetries(retries int) Option {
+	return func(c *config) (*config, error) {
+		if retries < 0 {
+			return c, fmt.Errorf("max retries must not be negative, got %d", retries)
+		}
+		c.maxRetries = retries
+		return c, nil
+	}
+}
+
+// WithBackoff sets the base and the maximum delay between retries. The delay
+// doubles with every attempt and is randomized (full jitter).
+func WithBackoff(base, maxDelay time.Duration) Option {
+	return func(c *config) (*config, error) {
+		if base <= 0 || maxDelay < base {
+			return c, fmt.Errorf("invalid backoff: base %v, max %v", base, maxDelay)
+		}
+		c.baseBackoff = base
+		c.maxBackoff = maxDelay
+		return c, nil
+	}
+}
+
+// WithCircuitBreaker sets the number of consecutive failed requests after
+// which requests fail fast with llm.ErrUnavailable, and for how long.
+func WithCircuitBreaker(failureThreshold int, cooldown time.Duration) Option {
+	return func(c *config) (*config, error) {
+		if failureThreshold <= 0 {
+			return c, fmt.Errorf("failure threshold must be greater than 0, got %d", failureThreshold)
+		}
+		if cooldown <= 0 {
+			return c, fmt.Errorf("cooldown must be greater than 0, got %v", cooldown)
+		}
+		c.failureThreshold = failureThreshold
+		c.cooldown = cooldown
+		return c, nil
+	}
+}
+
+type config struct {
+	timeout          time.Duration
+	maxRetries       int
+	baseBackoff      time.Duration
+	maxBackoff       time.Duration
+	failureThreshold int
+	cooldown         time.Duration
+}
+
+// ResilientProvider decorates an llm.Provider with per-request timeouts,
+// retries of temporary failures and a circuit breaker.
+type ResilientProvider struct {
+	llm.Provider
+
+	cfg     *config
+	breaker *breaker
+}
+
+func New(provider llm.Provider, options ...Option) (*ResilientProvider, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("provider cannot be nil")
+	}
+
+	cfg := &config{
+		timeout:          defaultTimeout,
+		maxRetries:       defaultMaxRetries,
+		baseBackoff:      defaultBaseBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		failureThreshold: defaultFailureThreshold,
+		cooldown:         defaultCooldown,
+	}
+	for _, opt := range options {
+		var err error
+		cfg, err = opt(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	return &ResilientProvider{
+		Provider: provider,
+		cfg:      cfg,
+		breaker:  &breaker{threshold: cfg.failureThreshold, cooldown: cfg.cooldown},
+	}, nil
+}
+
+func (p *ResilientProvider) HealthCheck(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.timeout)
+	defer cancel()
+	return p.Provider.HealthCheck(ctx)
+}
+
+func (p *ResilientProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	return p.call(ctx, func(ctx context.Context, _ func()) (string, error) {
+		return p.Provider.Generate(ctx, prompt)
+	}, func() bool { return true })
+}
+
+func (p *ResilientProvider) GenerateJSON(ctx context.Context, prompt string, schema json.RawMessage) (string, error) {
+	return p.call(ctx, func(ctx context.Context, _ func()) (string, error) {
+		return p.Provider.GenerateJSON(ctx, prompt, schema)
+	}, func() bool { return true })
+}
+
+// GenerateStream retries only until the first chunk was delivered; repeating
+// a request after that would show the beginning of the answer twice.
+func (p *ResilientProvider) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string) error) (string, error) {
+	var streamed bool
+	return p.call(ctx, func(ctx context.Context, progress func()) (string, error) {
+		return p.Provider.GenerateStream(ctx, prompt, func(chunk string) error {
+			progress()
+			streamed = true
+			return onChunk(chunk)
+		})
+	}, func() bool { return !streamed })
+}
+
+// SupportsImages reports whether the wrapped provider accepts images. The
+// check is not retried.
+func (p *ResilientProvider) SupportsImages(ctx context.Context) (bool, error) {
+	provider, ok := p.Provider.(llm.ImageProvider)
+	if !ok {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.timeout)
+	defer cancel()
+	return provider.SupportsImages(ctx)
+}
+
+// GenerateWithImages is retried like GenerateStream.
+func (p *ResilientProvider) GenerateWithImages(ctx context.Context, prompt string, images [][]byte, onChunk func(chunk string) error) (string, error) {
+	provider, ok := p.Provider.(llm.ImageProvider)
+	if !ok {
+		return "", llm.ErrImagesNotSupported
+	}
+
+	var streamed bool
+	return p.call(ctx, func(ctx context.Context, progress func()) (string, error) {
+		return provider.GenerateWithImages(ctx, prompt, images, func(chunk string) error {
+			progress()
+			streamed = true
+			return onChunk(chunk)
+		})
+	}, func() bool { return !streamed })
+}
+
+// call runs attempt until it succeeds, fails permanently or runs out of
+// retries. Every attempt is cancelled after the timeout unless it reports
+// progress, which restarts the timeout. canRetry reports whether the attempt
+// may be repeated at all.
+func (p *ResilientProvider) call(ctx context.Context, attempt func(ctx context.Context, progress func()) (string, error), canRetry func() bool) (string, error) {
+	if err := p.breaker.allow(); err != nil {
+		return "", err
+	}
+
+	var err error
+	for i := 0; ; i++ {
+		var out string
+		out, err = p.attempt(ctx, attempt)
+		if err == nil {
+			p.breaker.success()
+			return out, nil
+		}
+
+		timedOut := errors.Is(err, errAttemptTimeout)
+		if ctx.Err() != nil {
+			// The caller gave up; this says nothing about the provider.
+			p.breaker.release()
+			return "", err
+		}
+		if !timedOut && !isTemporary(err) {
+			// The request itself is wrong and would fail again.
+			p.breaker.success()
+			return "", err
+		}
+		if i >= p.cfg.maxRetries || !canRetry() {
+			break
+		}
+
+		if err := sleep(ctx, p.backoff(i)); err != nil {
+			p.breaker.release()
+			return "", err
+		}
+	}
+
+	p.breaker.failure()
+	return "", err
+}
+
+func (p *ResilientProvider) attempt(ctx context.Context, attempt func(ctx context.Context, progress func()) (string, error)) (string, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	timer := time.AfterFunc(p.cfg.timeout, func() { cancel(errAttemptTimeout) })
+	defer timer.Stop()
+
+	out, err := attempt(ctx, func() { timer.Reset(p.cfg.timeout) })
+	if err != nil && errors.Is(context.Cause(ctx), errAttemptTimeout) {
+		return "", fmt.Errorf("%w after %v: %w", errAttemptTimeout, p.cfg.timeout, err)
+	}
+	return out, err
+}
+
+// backoff returns a random delay up to base * 2^attempt capped by the maximum.
+func (p *ResilientProvider) backoff(attempt int) time.Duration {
+	delay := p.cfg.baseBackoff
+	for i := 0; i < attempt && delay < p.cfg.maxBackoff; i++ {
+		delay *= 2
+	}
+	return rand.N(min(delay, p.cfg.maxBackoff)) + 1
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isTemporary reports whether err is worth retrying: network failures,
+// timeouts and temporary server errors.
+func isTemporary(err error) bool {
+	var statusErr llm.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// breaker is a circuit breaker. After threshold consecutive failures it opens
+// and rejects requests for cooldown; then it lets a single trial request
+// through and closes again if that succeeds.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return nil
+	}
+
+	if wait := time.Until(b.openUntil); wait > 0 || b.trial {
+		return fmt.Errorf("%w, try again in %v", llm.ErrUnavailable, max(wait, time.Second).Round(time.Second))
+	}
+	b.trial = true
+	return nil
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+// release ends a trial request that was abandoned by the caller.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,289 @@
+package resilient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm"
+)
+
+type fakeProvider struct {
+	mu     sync.Mutex
+	calls  int
+	errs   []error
+	delay  time.Duration
+	chunks []string
+}
+
+func (f *fakeProvider) next() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *fakeProvider) Generate(ctx context.Context, _ string) (string, error) {
+	if err := f.next(); err != nil {
+		return "", err
+	}
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-time.After(f.delay):
+		return "ok", nil
+	}
+}
+
+func (f *fakeProvider) GenerateStream(ctx context.Context, _ string, onChunk func(chunk string) error) (string, error) {
+	err := f.next()
+	out := strings.Builder{}
+	for _, chunk := range f.chunks {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(f.delay):
+		}
+		out.WriteString(chunk)
+		if err := onChunk(chunk); err != nil {
+			return "", err
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func (f *fakeProvider) GenerateJSON(ctx context.Context, prompt string, _ json.RawMessage) (string, error) {
+	return f.Generate(ctx, prompt)
+}
+
+func (f *fakeProvider) HealthCheck(context.Context) error { return nil }
+func (f *fakeProvider) Model() string                     { return "test-model" }
+func (f *fakeProvider) ContextSize() int                  { return 1024 }
+
+func newTestProvider(t *testing.T, fake *fakeProvider, options ...Option) *ResilientProvider {
+	t.Helper()
+	options = append([]Option{WithBackoff(time.Millisecond, 2*time.Millisecond)}, options...)
+	p, err := New(fake, options...)
+	require.NoError(t, err)
+	return p
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []Option
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name: "should_create_provider_with_defaults",
+		},
+		{
+			name:    "should_allow_disabling_retries",
+			options: []Option{WithMaxRetries(0)},
+		},
+		{
+			name:        "should_fail_with_invalid_timeout",
+			options:     []Option{WithTimeout(0)},
+			expectError: true,
+			errorMsg:    "timeout must be greater than 0",
+		},
+		{
+			name:        "should_fail_with_invalid_backoff",
+			options:     []Option{WithBackoff(time.Second, time.Millisecond)},
+			expectError: true,
+			errorMsg:    "invalid backoff",
+		},
+		{
+			name:        "should_fail_with_invalid_threshold",
+			options:     []Option{WithCircuitBreaker(0, time.Second)},
+			expectError: true,
+			errorMsg:    "failure threshold must be greater than 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&fakeProvider{}, tt.options...)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestResilientProvider_Generate(t *testing.T) {
+	unavailable := llm.StatusError{StatusCode: http.StatusServiceUnavailable}
+
+	t.Run("should_retry_temporary_errors", func(t *testing.T) {
+		fake := &fakeProvider{errs: []error{unavailable, unavailable}}
+		p := newTestProvider(t, fake)
+
+		result, err := p.Generate(context.Background(), "prompt")
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", result)
+		assert.Equal(t, 3, fake.calls)
+	})
+
+	t.Run("should_not_retry_permanent_errors", func(t *testing.T) {
+		fake := &fakeProvider{errs: []error{llm.StatusError{StatusCode: http.StatusBadRequest}}}
+		p := newTestProvider(t, fake)
+
+		_, err := p.Generate(context.Background(), "prompt")
+
+		require.Error(t, err)
+		assert.Equal(t, 1, fake.calls)
+	})
+
+	t.Run("should_give_up_after_max_retries", func(t *testing.T) {
+		fake := &fakeProvider{errs: []error{unavailable, unavailable, unavailable}}
+		p := newTestProvider(t, fake, WithMaxRetries(2))
+
+		_, err := p.Generate(context.Background(), "prompt")
+
+		require.Error(t, err)
+		assert.ErrorIs(t, err, unavailable)
+		assert.Equal(t, 3, fake.calls)
+	})
+
+	t.Run("should_time_out_and_retry_slow_requests", func(t *testing.T) {
+		fake := &fakeProvider{delay: time.Second}
+		p := newTestProvider(t, fake, WithTimeout(10*time.Millisecond), WithMaxRetries(1))
+
+		_, err := p.Generate(context.Background(), "prompt")
+
+		require.Error(t, err)
+		assert.ErrorIs(t, err, errAttemptTimeout)
+		assert.Equal(t, 2, fake.calls)
+	})
+
+	t.Run("should_stop_when_caller_cancels", func(t *testing.T) {
+		fake := &fakeProvider{delay: time.Second}
+		p := newTestProvider(t, fake)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := p.Generate(ctx, "prompt")
+
+		require.Error(t, err)
+		assert.Equal(t, 1, fake.calls)
+	})
+}
+
+func TestResilientProvider_GenerateStream(t *testing.T) {
+	t.Run("should_not_time_out_while_chunks_arrive", func(t *testing.T) {
+		fake := &fakeProvider{delay: 10 * time.Millisecond, chunks: []string{"a", "b", "c", "d", "e"}}
+		p := newTestProvider(t, fake, WithTimeout(30*time.Millisecond))
+
+		result, err := p.GenerateStream(context.Background(), "prompt", func(string) error { return nil })
+
+		require.NoError(t, err)
+		assert.Equal(t, "abcde", result)
+	})
+
+	t.Run("should_not_retry_after_first_chunk", func(t *testing.T) {
+		fake := &fakeProvider{chunks: []string{"a"}, errs: []error{llm.StatusError{StatusCode: http.StatusBadGateway}}}
+		p := newTestProvider(t, fake)
+
+		var chunks []string
+		_, err := p.GenerateStream(context.Background(), "prompt", func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+
+		require.Error(t, err)
+		assert.Equal(t, 1, fake.calls)
+		assert.Equal(t, []string{"a"}, chunks)
+	})
+}
+
+// fakeImageProvider accepts images and records them.
+type fakeImageProvider struct {
+	*fakeProvider
+	images [][]byte
+}
+
+func (f *fakeImageProvider) SupportsImages(context.Context) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeImageProvider) GenerateWithImages(ctx context.Context, prompt string, images [][]byte, onChunk func(chunk string) error) (string, error) {
+	f.images = images
+	return f.GenerateStream(ctx, prompt, onChunk)
+}
+
+func TestResilientProvider_GenerateWithImages(t *testing.T) {
+	t.Run("should_retry_failed_request_with_images", func(t *testing.T) {
+		fake := &fakeImageProvider{fakeProvider: &fakeProvider{errs: []error{llm.StatusError{StatusCode: http.StatusBadGateway}}}}
+		p, err := New(fake, WithBackoff(time.Millisecond, 2*time.Millisecond))
+		require.NoError(t, err)
+
+		supported, err := p.SupportsImages(context.Background())
+		require.NoError(t, err)
+		assert.True(t, supported)
+
+		_, err = p.GenerateWithImages(context.Background(), "prompt", [][]byte{[]byte("png")}, func(string) error { return nil })
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, fake.calls, "the failed attempt did not stream anything and was repeated")
+		assert.Equal(t, [][]byte{[]byte("png")}, fake.images)
+	})
+
+	t.Run("should_reject_images_for_text_only_provider", func(t *testing.T) {
+		p := newTestProvider(t, &fakeProvider{})
+
+		supported, err := p.SupportsImages(context.Background())
+		require.NoError(t, err)
+		assert.False(t, supported)
+
+		_, err = p.GenerateWithImages(context.Background(), "prompt", [][]byte{[]byte("png")}, func(string) error { return nil })
+
+		assert.ErrorIs(t, err, llm.ErrImagesNotSupported)
+	})
+}
+
+func TestResilientProvider_CircuitBreaker(t *testing.T) {
+	unavailable := llm.StatusError{StatusCode: http.StatusServiceUnavailable}
+
+	fake := &fakeProvider{errs: []error{unavailable, unavailable}}
+	p := newTestProvider(t, fake, WithMaxRetries(0), WithCircuitBreaker(2, 50*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		_, err := p.Generate(context.Background(), "prompt")
+		require.Error(t, err)
+	}
+
+	_, err := p.Generate(context.Background(), "prompt")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, llm.ErrUnavailable))
+	assert.Equal(t, 2, fake.calls, "open circuit must not reach the provider")
+
+	time.Sleep(60 * time.Millisecond)
+
+	result, err := p.Generate(context.Background(), "prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, 3, fake.calls)
+}
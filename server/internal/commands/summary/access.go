@@ -0,0 +1,32 @@
+package summary
+
+import (
+	"errors"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+)
+
+// authorize checks that the user can read the thread, if rootID is set, or
+// the channel. A thread must belong to the channel.
+func (h Handler) authorize(userID, channelID, rootID string) error {
+	if rootID == "" {
+		return h.access.AuthorizeChannel(userID, channelID)
+	}
+
+	post, err := h.access.AuthorizeThread(userID, rootID)
+	if err != nil {
+		return err
+	}
+	if post.ChannelId != channelID {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+func (h Handler) describeAccessError(err error, channelID string) string {
+	if errors.Is(err, domain.ErrForbidden) {
+		return "You do not have permission to read this channel."
+	}
+	h.client.Log.Error("failed to check channel access", "channel_id", channelID, "error", err.Error())
+	return "Failed to check your access to this channel."
+}
@@ -0,0 +1,71 @@
+package summary
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+)
+
+// fakeAccess lets users read the channels listed for them.
+type fakeAccess struct {
+	readable map[string]string
+	posts    map[string]*model.Post
+}
+
+func (f fakeAccess) AuthorizeChannel(userID, channelID string) error {
+	if f.readable[userID] != channelID {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+func (f fakeAccess) AuthorizeThread(userID, postID string) (*model.Post, error) {
+	post := f.posts[postID]
+	if err := f.AuthorizeChannel(userID, post.ChannelId); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+func TestHandler_Handle_authorization(t *testing.T) {
+	access := fakeAccess{
+		readable: map[string]string{"user1": "channel1"},
+		posts: map[string]*model.Post{
+			"root1": {Id: "root1", ChannelId: "channel1"},
+			"root2": {Id: "root2", ChannelId: "channel2"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		args *model.CommandArgs
+	}{
+		{
+			name: "should_reject_unreadable_channel",
+			args: &model.CommandArgs{Command: "/summary channel", UserId: "user2", ChannelId: "channel1"},
+		},
+		{
+			name: "should_reject_thread_of_another_channel",
+			args: &model.CommandArgs{Command: "/summary thread", UserId: "user1", ChannelId: "channel1", RootId: "root2"},
+		},
+		{
+			name: "should_reject_listing_action_items_of_unreadable_channel",
+			args: &model.CommandArgs{Command: "/summary actions list", UserId: "user2", ChannelId: "channel1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupTest()
+			h := Handler{client: env.client, access: access, actions: memActionStore{}}
+
+			resp, err := h.Handle(tt.args)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "You do not have permission to read this channel.", resp.Text)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm"
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+const (
+	modeActions = "actions"
+
+	actionsList = "list"
+
+	actionsUsage = "Usage: /summary actions [list] [--since 2h|2026-10-01] [--last 200] [--from @user] [--exclude-bots|--only-bots] [--lang auto|en|ru|es|fr|de]"
+)
+
+func newActionsAutocompleteData() *model.AutocompleteData {
+	data := model.NewAutocompleteData(modeActions, "[list] [--since 2h] [--last 200] [--from @user] [--exclude-bots|--only-bots] [--lang en]", "Extract action items from the current thread or channel")
+	data.AddCommand(model.NewAutocompleteData(actionsList, "", "Show action items extracted earlier in this channel or thread"))
+	data.AddNamedTextArgument("since", "Only messages since a duration (2h, 3d) or date (2026-10-01)", "2h", "", false)
+	data.AddNamedTextArgument("last", "Number of most recent messages", "200", "[0-9]+", false)
+	data.AddNamedTextArgument("from", "Only messages from this user", "@user", "", false)
+	addLanguageArgument(data)
+	return data
+}
+
+// listActions shows the action items stored for the channel. In a thread only
+// the items of that thread are shown.
+func (h Handler) listActions(args *model.CommandArgs) *model.CommandResponse {
+	items, err := h.actions.GetActionItems(args.ChannelId)
+	if err != nil {
+		h.client.Log.Error("failed to get action items", "channel_id", args.ChannelId, "error", err.Error())
+		return ephemeral("Failed to load action items.")
+	}
+
+	scope := "channel"
+	if args.RootId != "" {
+		scope = "thread"
+		inThread := items[:0:0]
+		for _, item := range items {
+			if item.RootID == args.RootId || item.PostID == args.RootId {
+				inThread = append(inThread, item)
+			}
+		}
+		items = inThread
+	}
+
+	if len(items) == 0 {
+		return ephemeral(fmt.Sprintf("No action items have been extracted in this %s yet. Use `/summary actions` to extract them.", scope))
+	}
+	return ephemeral(fmt.Sprintf("**Action Items:**\n%s", renderActions(items, h.siteURL())))
+}
+
+// processActions extracts action items from the posts of a queued job, stores
+// them for `/summary actions list` and delivers them as a checklist.
+func (h Handler) processActions(ctx context.Context, job *jobs.Job, stream *streamingPost, posts []*model.Post, params domain.Params) (string, error) {
+	items, err := h.service.ExtractActions(ctx, posts, params)
+	if err != nil {
+		if errors.Is(err, llm.ErrUnavailable) {
+			stream.Fail("The language model is temporarily unavailable. Please try again in a minute.")
+		} else {
+			stream.Fail("Failed to extract action items.")
+		}
+		return "", fmt.Errorf("failed to extract action items: %w", err)
+	}
+
+	if len(items) == 0 {
+		stream.Finish("No action items found.")
+		return "[]", nil
+	}
+
+	channelID := job.Payload[payloadChannelID]
+	if err := h.actions.SaveActionItems(channelID, items); err != nil {
+		h.client.Log.Error("failed to save action items", "channel_id", channelID, "error", err.Error())
+	}
+	stream.Finish(renderActions(items, h.siteURL()))
+
+	result, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode action items: %w", err)
+	}
+	return string(result), nil
+}
+
+// renderActions formats action items as a markdown checklist. Owners matched
+// to a user are mentioned, and every item links to the post it comes from.
+func renderActions(items []domain.ExtractedAction, siteURL string) string {
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		line := "- [ ] " + item.Text
+		switch {
+		case item.OwnerID != "":
+			line += " — @" + item.Owner
+		case item.Owner != "":
+			line += " — " + item.Owner
+		}
+		if item.DueDate != "" {
+			line += fmt.Sprintf(" (due: %s)", item.DueDate)
+		}
+		if item.PostID != "" {
+			line += fmt.Sprintf(" · [source](%s/_redirect/pl/%s)", siteURL, item.PostID)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (h Handler) siteURL() string {
+	cfg := h.client.Configuration.GetConfig()
+	if cfg == nil || cfg.ServiceSettings.SiteURL == nil {
+		return ""
+	}
+	return strings.TrimSuffix(*cfg.ServiceSettings.SiteURL, "/")
+}
@@ -0,0 +1,71 @@
+package summary
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+)
+
+type memActionStore map[string][]domain.ExtractedAction
+
+func (m memActionStore) GetActionItems(channelID string) ([]domain.ExtractedAction, error) {
+	return m[channelID], nil
+}
+
+func (m memActionStore) SaveActionItems(channelID string, items []domain.ExtractedAction) error {
+	m[channelID] = append(items, m[channelID]...)
+	return nil
+}
+
+func TestRenderActions(t *testing.T) {
+	items := []domain.ExtractedAction{
+		{Text: "Update the API", OwnerID: "u2", Owner: "anna", DueDate: "2025-03-14", PostID: "p2"},
+		{Text: "Prepare demo", Owner: "Maria"},
+	}
+
+	expected := "- [ ] Update the API — @anna (due: 2025-03-14) · [source](https://chat.example.com/_redirect/pl/p2)\n" +
+		"- [ ] Prepare demo — Maria"
+	assert.Equal(t, expected, renderActions(items, "https://chat.example.com"))
+}
+
+func TestHandler_listActions(t *testing.T) {
+	store := memActionStore{"channel1": {
+		{Text: "Update the API", OwnerID: "u2", Owner: "anna", PostID: "p2", RootID: "p1"},
+		{Text: "Book a room", PostID: "p5"},
+	}}
+	siteURL := "https://chat.example.com/"
+
+	t.Run("should_list_channel_action_items", func(t *testing.T) {
+		env := setupTest()
+		env.api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		h := Handler{client: env.client, actions: store}
+
+		resp := h.listActions(&model.CommandArgs{ChannelId: "channel1"})
+
+		assert.Contains(t, resp.Text, "- [ ] Update the API — @anna · [source](https://chat.example.com/_redirect/pl/p2)")
+		assert.Contains(t, resp.Text, "- [ ] Book a room")
+	})
+
+	t.Run("should_list_only_thread_action_items_in_thread", func(t *testing.T) {
+		env := setupTest()
+		env.api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		h := Handler{client: env.client, actions: store}
+
+		resp := h.listActions(&model.CommandArgs{ChannelId: "channel1", RootId: "p1"})
+
+		assert.Contains(t, resp.Text, "Update the API")
+		assert.NotContains(t, resp.Text, "Book a room")
+	})
+
+	t.Run("should_explain_how_to_extract_when_empty", func(t *testing.T) {
+		env := setupTest()
+		h := Handler{client: env.client, actions: memActionStore{}}
+
+		resp := h.listActions(&model.CommandArgs{ChannelId: "channel2"})
+
+		assert.Contains(t, resp.Text, "Use `/summary actions`")
+	})
+}
@@ -0,0 +1,195 @@
+package summary
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+)
+
+const (
+	flagSince = "--since"
+	flagLast  = "--last"
+	flagFrom  = "--from"
+	flagLang  = "--lang"
+
+	flagExcludeBots = "--exclude-bots"
+	flagOnlyBots    = "--only-bots"
+
+	dateLayout = "2006-01-02"
+)
+
+// channelArgs are the optional filters of `/summary channel`.
+type channelArgs struct {
+	// since limits the summary to posts created after this time. Zero means no limit.
+	since time.Time
+	// sinceLabel is the --since value as entered by the user.
+	sinceLabel string
+	// last is the requested number of most recent posts. Zero means the default.
+	last int
+	// fromUsername limits the summary to posts of a single user.
+	fromUsername string
+	// bots selects posts of integrations, see --exclude-bots and --only-bots.
+	bots botFilter
+}
+
+// parseChannelArgs parses flags such as `--since 2h`, `--since 2026-10-01`,
+// `--last 200`, `--from @user`, `--exclude-bots` and `--only-bots`. Dates are
+// interpreted in loc.
+func parseChannelArgs(fields []string, now time.Time, loc *time.Location) (channelArgs, error) {
+	var args channelArgs
+
+	for i := 0; i < len(fields); i++ {
+		flag := fields[i]
+		if flag == flagExcludeBots || flag == flagOnlyBots {
+			bots := botsExcluded
+			if flag == flagOnlyBots {
+				bots = botsOnly
+			}
+			if args.bots != botsIncluded && args.bots != bots {
+				return args, fmt.Errorf("%s and %s cannot be used together", flagExcludeBots, flagOnlyBots)
+			}
+			args.bots = bots
+			continue
+		}
+		if i+1 >= len(fields) {
+			return args, fmt.Errorf("missing value for %s", flag)
+		}
+		i++
+		value := fields[i]
+
+		switch flag {
+		case flagSince:
+			since, err := parseSince(value, now, loc)
+			if err != nil {
+				return args, err
+			}
+			args.since = since
+			args.sinceLabel = value
+		case flagLast:
+			last, err := strconv.Atoi(value)
+			if err != nil || last <= 0 {
+				return args, fmt.Errorf("invalid value for %s: %q must be a positive number", flagLast, value)
+			}
+			args.last = last
+		case flagFrom:
+			username := strings.TrimPrefix(value, "@")
+			if username == "" {
+				return args, fmt.Errorf("invalid value for %s: username is empty", flagFrom)
+			}
+			args.fromUsername = username
+		default:
+			return args, fmt.Errorf("unknown argument: %s", flag)
+		}
+	}
+
+	return args, nil
+}
+
+// extractLanguage removes `--lang <code>` from fields and returns the
+// remaining fields together with the requested summary language.
+func extractLanguage(fields []string) ([]string, string, error) {
+	rest := make([]string, 0, len(fields))
+	var language string
+	for i := 0; i < len(fields); i++ {
+		if fields[i] != flagLang {
+			rest = append(rest, fields[i])
+			continue
+		}
+		if i+1 >= len(fields) {
+			return nil, "", fmt.Errorf("missing value for %s", flagLang)
+		}
+		i++
+		language = strings.ToLower(fields[i])
+		if !domain.IsSupportedLanguage(language) {
+			return nil, "", fmt.Errorf("unsupported language for %s: %q", flagLang, fields[i])
+		}
+	}
+	return rest, language, nil
+}
+
+// extractTimezone removes `--tz Europe/Berlin` from fields and returns the
+// remaining fields and the time zone, or fallback if the flag is not set.
+func extractTimezone(fields []string, fallback string) ([]string, string, error) {
+	rest := make([]string, 0, len(fields))
+	timezone := fallback
+	for i := 0; i < len(fields); i++ {
+		if fields[i] != flagTimezone {
+			rest = append(rest, fields[i])
+			continue
+		}
+		if i+1 >= len(fields) {
+			return nil, "", fmt.Errorf("missing value for %s", flagTimezone)
+		}
+		i++
+		if _, err := time.LoadLocation(fields[i]); err != nil {
+			return nil, "", fmt.Errorf("unknown time zone: %s", fields[i])
+		}
+		timezone = fields[i]
+	}
+	return rest, timezone, nil
+}
+
+// parseSince accepts a relative duration (30m, 2h, 3d, 1w), a date
+// (2026-10-01) or an RFC 3339 timestamp.
+func parseSince(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	if d, err := parseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if t, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid value for %s: %q must be a duration like 2h or 3d, or a date like 2026-10-01", flagSince, value)
+}
+
+// parseDuration extends time.ParseDuration with day (d) and week (w) units.
+func parseDuration(value string) (time.Duration, error) {
+	var unit time.Duration
+	switch {
+	case strings.HasSuffix(value, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(value, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+		if d <= 0 {
+			return 0, fmt.Errorf("duration must be positive")
+		}
+		return d, nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(value, "d"), "w"))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid duration: %q", value)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// describe returns a human readable description of the summarized window.
+func (a channelArgs) describe(limit int) string {
+	window := fmt.Sprintf("last %d messages", limit)
+	if a.sinceLabel != "" {
+		window += " since " + a.sinceLabel
+	}
+	if a.fromUsername != "" {
+		window += " from @" + a.fromUsername
+	}
+	switch a.bots {
+	case botsExcluded:
+		window += " without bots"
+	case botsOnly:
+		window += " from bots only"
+	}
+	return window
+}
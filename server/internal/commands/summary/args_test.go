@@ -0,0 +1,143 @@
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseChannelArgs(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	tests := []struct {
+		name        string
+		fields      []string
+		expected    channelArgs
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:     "should_accept_no_arguments",
+			fields:   nil,
+			expected: channelArgs{},
+		},
+		{
+			name:     "should_parse_relative_since",
+			fields:   []string{"--since", "2h"},
+			expected: channelArgs{since: now.Add(-2 * time.Hour), sinceLabel: "2h"},
+		},
+		{
+			name:     "should_parse_days",
+			fields:   []string{"--since", "3d"},
+			expected: channelArgs{since: now.Add(-72 * time.Hour), sinceLabel: "3d"},
+		},
+		{
+			name:     "should_parse_date_in_user_timezone",
+			fields:   []string{"--since", "2026-10-01"},
+			expected: channelArgs{since: time.Date(2026, 10, 1, 0, 0, 0, 0, moscow), sinceLabel: "2026-10-01"},
+		},
+		{
+			name:     "should_parse_all_flags",
+			fields:   []string{"--last", "200", "--from", "@ivan", "--since", "1w"},
+			expected: channelArgs{since: now.Add(-7 * 24 * time.Hour), sinceLabel: "1w", last: 200, fromUsername: "ivan"},
+		},
+		{
+			name:     "should_parse_bot_flags_without_value",
+			fields:   []string{"--exclude-bots", "--last", "20"},
+			expected: channelArgs{last: 20, bots: botsExcluded},
+		},
+		{
+			name:     "should_parse_only_bots",
+			fields:   []string{"--since", "2h", "--only-bots"},
+			expected: channelArgs{since: now.Add(-2 * time.Hour), sinceLabel: "2h", bots: botsOnly},
+		},
+		{
+			name:        "should_fail_on_conflicting_bot_flags",
+			fields:      []string{"--only-bots", "--exclude-bots"},
+			expectError: true,
+			errorMsg:    "cannot be used together",
+		},
+		{
+			name:        "should_fail_on_invalid_since",
+			fields:      []string{"--since", "yesterday"},
+			expectError: true,
+			errorMsg:    "invalid value for --since",
+		},
+		{
+			name:        "should_fail_on_negative_last",
+			fields:      []string{"--last", "-5"},
+			expectError: true,
+			errorMsg:    "must be a positive number",
+		},
+		{
+			name:        "should_fail_on_missing_value",
+			fields:      []string{"--last"},
+			expectError: true,
+			errorMsg:    "missing value for --last",
+		},
+		{
+			name:        "should_fail_on_unknown_flag",
+			fields:      []string{"--until", "2h"},
+			expectError: true,
+			errorMsg:    "unknown argument",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := parseChannelArgs(tt.fields, now, moscow)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.expected.since.Equal(args.since), "since: expected %v, got %v", tt.expected.since, args.since)
+			assert.Equal(t, tt.expected.sinceLabel, args.sinceLabel)
+			assert.Equal(t, tt.expected.last, args.last)
+			assert.Equal(t, tt.expected.fromUsername, args.fromUsername)
+		})
+	}
+}
+
+func TestChannelArgs_Describe(t *testing.T) {
+	assert.Equal(t, "last 50 messages", channelArgs{}.describe(50))
+	assert.Equal(t, "last 200 messages since 2h from @ivan", channelArgs{sinceLabel: "2h", fromUsername: "ivan"}.describe(200))
+	assert.Equal(t, "last 50 messages from bots only", channelArgs{bots: botsOnly}.describe(50))
+}
+
+func TestExtractLanguage(t *testing.T) {
+	t.Run("should_remove_language_flag", func(t *testing.T) {
+		rest, language, err := extractLanguage([]string{"--last", "20", "--lang", "DE"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--last", "20"}, rest)
+		assert.Equal(t, "de", language)
+	})
+
+	t.Run("should_keep_fields_without_language", func(t *testing.T) {
+		rest, language, err := extractLanguage([]string{"--since", "2h"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"--since", "2h"}, rest)
+		assert.Empty(t, language)
+	})
+
+	t.Run("should_fail_on_unsupported_language", func(t *testing.T) {
+		_, _, err := extractLanguage([]string{"--lang", "it"})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported language")
+	})
+
+	t.Run("should_fail_on_missing_value", func(t *testing.T) {
+		_, _, err := extractLanguage([]string{"--lang"})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing value for --lang")
+	})
+}
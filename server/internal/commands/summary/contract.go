@@ -4,8 +4,50 @@ import (
 	"context"
 
 	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/EgorTarasov/summary/server/internal/domain/digest"
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
 )
 
-type summarizer interface {
-	GenerateSummary(ctx context.Context, posts []*model.Post) (string, error)
-}
+type (
+	summarizer interface {
+		GenerateSummary(ctx context.Context, posts []*model.Post) (domain.Summary, error)
+		GenerateSummaryStream(ctx context.Context, posts []*model.Post, params domain.Params, onChunk func(chunk string) error) (domain.Summary, error)
+		GenerateUnreadSummaryStream(ctx context.Context, userID, channelID string, posts []*model.Post, params domain.Params, onChunk func(chunk string) error) (domain.Summary, error)
+		ExtractActions(ctx context.Context, posts []*model.Post, params domain.Params) ([]domain.ExtractedAction, error)
+		GenerateBrief(ctx context.Context, posts []*model.Post, params domain.Params) (string, error)
+		GenerateGroupedSummaryStream(ctx context.Context, groups []domain.PostGroup, params domain.Params, onChunk func(chunk string) error) (domain.Summary, error)
+	}
+	authorizer interface {
+		AuthorizeChannel(userID, channelID string) error
+		AuthorizeThread(userID, postID string) (*model.Post, error)
+	}
+	jobQueue interface {
+		Register(jobType string, processor jobs.Processor)
+		Enqueue(job *jobs.Job) error
+		Get(id string) (*jobs.Job, error)
+	}
+	templateStore interface {
+		GetPromptTemplate(scopeID string) (string, error)
+		SetPromptTemplate(scopeID, name string) error
+	}
+	scheduleStore interface {
+		GetDigestSchedule(channelID string) (*digest.Schedule, error)
+		SaveDigestSchedule(schedule *digest.Schedule) error
+		UpdateDigestSchedule(channelID string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error)
+		DeleteDigestSchedule(channelID string) error
+		ListDigestChannels() ([]string, error)
+	}
+	personalDigestStore interface {
+		GetPersonalDigest(userID string) (*digest.Schedule, error)
+		SavePersonalDigest(schedule *digest.Schedule) error
+		UpdatePersonalDigest(userID string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error)
+		DeletePersonalDigest(userID string) error
+		ListPersonalDigestUsers() ([]string, error)
+	}
+	actionStore interface {
+		GetActionItems(channelID string) ([]domain.ExtractedAction, error)
+		SaveActionItems(channelID string, items []domain.ExtractedAction) error
+	}
+)
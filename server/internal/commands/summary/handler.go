@@ -2,40 +2,147 @@ package summary
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm"
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
 )
 
 // TODO: move summary logic into service
 type Handler struct {
-	client  *pluginapi.Client
-	service summarizer
+	client    *pluginapi.Client
+	service   summarizer
+	access    authorizer
+	queue     jobQueue
+	templates templateStore
+	actions   actionStore
+	schedules scheduleStore
+	personal  personalDigestStore
+	cfg       Config
+}
+
+// Config holds the settings the command depends on.
+type Config struct {
+	// BotID is the user ID of the bot that authors summary posts.
+	BotID string
+	// MaxMessages is the maximum number of posts summarized per request.
+	MaxMessages int
+	// PromptTemplates are the names of the prompt templates channels and
+	// teams can select.
+	PromptTemplates []string
+	// ActionURL is the URL the server sends the actions of summary posts
+	// to, see HandleAction.
+	ActionURL string
 }
 
-const summaryTrigger = "summary"
+const (
+	summaryTrigger = "summary"
+
+	summaryJobType = "summary"
+
+	payloadMode      = "mode"
+	payloadChannelID = "channel_id"
+	payloadRootID    = "root_id"
+	payloadPostID    = "post_id"
+	payloadTitle     = "title"
+	payloadSince     = "since"
+	payloadLimit     = "limit"
+	payloadUserID    = "from_user_id"
+	payloadBots      = "bots"
+	payloadLanguage  = "language"
+
+	modeThread  = "thread"
+	modeChannel = "channel"
+	modeUnread  = "unread"
+)
+
+const (
+	usage        = "Usage: /summary [thread|channel|unread|search|tag|actions|digest|schedule|template] [--lang auto|en|ru|es|fr|de]"
+	channelUsage = "Usage: /summary channel [--since 2h|2026-10-01] [--last 200] [--from @user] [--exclude-bots|--only-bots] [--lang auto|en|ru|es|fr|de]"
+)
+
+var languages = []string{
+	domain.LanguageAuto,
+	domain.LanguageEnglish,
+	domain.LanguageRussian,
+	domain.LanguageSpanish,
+	domain.LanguageFrench,
+	domain.LanguageGerman,
+}
+
+func newAutocompleteData(cfg Config) *model.AutocompleteData {
+	data := model.NewAutocompleteData(summaryTrigger, "[thread|channel|unread|search|tag|actions|digest|schedule|template]", "Generate summary of current thread, channel or unread messages")
+
+	thread := model.NewAutocompleteData(modeThread, "[--lang en]", "Summarize the current thread")
+	addLanguageArgument(thread)
+	data.AddCommand(thread)
 
-func New(client *pluginapi.Client, service summarizer) *Handler {
+	channel := model.NewAutocompleteData(modeChannel, "[--since 2h|2026-10-01] [--last 200] [--from @user] [--exclude-bots|--only-bots] [--lang en]", "Summarize recent messages in the current channel")
+	channel.AddNamedTextArgument("since", "Only messages since a duration (2h, 3d) or date (2026-10-01)", "2h", "", false)
+	channel.AddNamedTextArgument("last", "Number of most recent messages", "200", "[0-9]+", false)
+	channel.AddNamedTextArgument("from", "Only messages from this user", "@user", "", false)
+	addLanguageArgument(channel)
+	data.AddCommand(channel)
+
+	unread := model.NewAutocompleteData(modeUnread, "[--lang en]", "Summarize messages since your last visit")
+	addLanguageArgument(unread)
+	data.AddCommand(unread)
+
+	data.AddCommand(newSearchAutocompleteData())
+	data.AddCommand(newTagAutocompleteData())
+	data.AddCommand(newActionsAutocompleteData())
+	data.AddCommand(newDigestAutocompleteData())
+	data.AddCommand(newScheduleAutocompleteData())
+	data.AddCommand(newTemplateAutocompleteData(cfg.PromptTemplates))
+	return data
+}
+
+func addLanguageArgument(data *model.AutocompleteData) {
+	items := make([]model.AutocompleteListItem, 0, len(languages))
+	for _, language := range languages {
+		items = append(items, model.AutocompleteListItem{Item: language})
+	}
+	data.AddNamedStaticListArgument("lang", "Summary language, auto detects it from the conversation", false, items)
+}
+
+func New(client *pluginapi.Client, service summarizer, access authorizer, queue jobQueue, templates templateStore, actions actionStore, schedules scheduleStore, personal personalDigestStore, cfg Config) *Handler {
 	err := client.SlashCommand.Register(&model.Command{
 		Trigger:          summaryTrigger,
 		AutoComplete:     true,
 		AutoCompleteDesc: "Generate a summary of current channel or thread",
-		AutoCompleteHint: "[thread|channel]",
-		AutocompleteData: model.NewAutocompleteData(summaryTrigger, "[thread|channel]", "Generate summary of current thread or channel"),
+		AutoCompleteHint: "[thread|channel|unread|search|tag|actions|digest|schedule|template]",
+		AutocompleteData: newAutocompleteData(cfg),
 	})
 	if err != nil {
 		client.Log.Error("Failed to register summary command", "error", err)
 	}
-	return &Handler{
-		client:  client,
-		service: service,
+	h := &Handler{
+		client:    client,
+		service:   service,
+		access:    access,
+		queue:     queue,
+		templates: templates,
+		actions:   actions,
+		schedules: schedules,
+		personal:  personal,
+		cfg:       cfg,
 	}
+	queue.Register(summaryJobType, h.process)
+	queue.Register(digestJobType, h.processDigest)
+	queue.Register(personalDigestJobType, h.processPersonalDigest)
+	return h
 }
 
 func (h Handler) Handle(args *model.CommandArgs) (*model.CommandResponse, error) {
-	ctx := context.Background()
 	trigger := strings.TrimPrefix(strings.Fields(args.Command)[0], "/")
 	if trigger != summaryTrigger {
 		return &model.CommandResponse{
@@ -45,65 +152,300 @@ func (h Handler) Handle(args *model.CommandArgs) (*model.CommandResponse, error)
 	}
 
 	fields := strings.Fields(args.Command)
-	summaryType := "thread" // default to thread
+	summaryType := modeThread // default to thread
 	if len(fields) > 1 {
 		summaryType = fields[1]
 	}
+	var flags []string
+	if len(fields) > 2 {
+		flags = fields[2:]
+	}
+
+	if summaryType == modeTemplate {
+		return h.handleTemplate(args, flags), nil
+	}
+	if summaryType == modeDigest {
+		return h.handleDigest(args, flags), nil
+	}
+	if err := h.authorize(args.UserId, args.ChannelId, args.RootId); err != nil {
+		return ephemeral(h.describeAccessError(err, args.ChannelId)), nil
+	}
+	if summaryType == modeSchedule {
+		return h.handleSchedule(args, flags), nil
+	}
+	if summaryType == modeActions && len(flags) > 0 && flags[0] == actionsList {
+		return h.listActions(args), nil
+	}
+
+	flags, language, err := extractLanguage(flags)
+	if err != nil {
+		return ephemeral(fmt.Sprintf("%v\n%s", err, usage)), nil
+	}
 
-	var postList *model.PostList
-	var err error
 	var summaryTitle string
+	payload := map[string]string{
+		payloadMode:      summaryType,
+		payloadChannelID: args.ChannelId,
+		payloadRootID:    args.RootId,
+		payloadLanguage:  language,
+	}
 
 	switch summaryType {
-	case "thread":
+	case modeThread:
 		if args.RootId == "" {
 			return &model.CommandResponse{
 				ResponseType: model.CommandResponseTypeEphemeral,
 				Text:         "This command must be used in a thread. Reply to a message first, or use `/summary channel` to summarize the entire channel.",
 			}, nil
 		}
-		postList, err = h.client.Post.GetPostThread(args.RootId)
 		summaryTitle = "Thread Summary:"
-	case "channel":
-		postList, err = h.client.Post.GetPostsForChannel(args.ChannelId, 0, 50)
-		summaryTitle = "Channel Summary (last 50 messages):"
+	case modeChannel:
+		filter, err := h.parseChannelFilter(args, flags)
+		if err != nil {
+			return &model.CommandResponse{
+				ResponseType: model.CommandResponseTypeEphemeral,
+				Text:         fmt.Sprintf("%v\n%s", err, channelUsage),
+			}, nil
+		}
+		payload[payloadSince] = strconv.FormatInt(filter.since, 10)
+		payload[payloadLimit] = strconv.Itoa(filter.limit)
+		payload[payloadUserID] = filter.userID
+		payload[payloadBots] = string(filter.bots)
+		summaryTitle = fmt.Sprintf("Channel Summary (%s):", filter.description)
+	case modeUnread:
+		// The last visit is read now: by the time the job runs, the client has
+		// usually marked the channel as viewed.
+		member, err := h.client.Channel.GetMember(args.ChannelId, args.UserId)
+		if err != nil {
+			h.client.Log.Error("failed to get channel membership", "channel_id", args.ChannelId, "error", err.Error())
+			return ephemeral("Failed to find your last visit to this channel."), nil
+		}
+		payload[payloadSince] = strconv.FormatInt(member.LastViewedAt, 10)
+		summaryTitle = "Unread Messages Summary:"
+	case modeSearch, modeTag:
+		query, err := parseSearchQuery(summaryType, flags)
+		if err != nil {
+			usage := searchUsage
+			if summaryType == modeTag {
+				usage = tagUsage
+			}
+			return ephemeral(fmt.Sprintf("%v\n%s", err, usage)), nil
+		}
+		payload[payloadQuery] = query
+		summaryTitle = fmt.Sprintf("Search Summary (%s):", query)
+	case modeActions:
+		if args.RootId != "" {
+			summaryTitle = "Thread Action Items:"
+			break
+		}
+		filter, err := h.parseChannelFilter(args, flags)
+		if err != nil {
+			return ephemeral(fmt.Sprintf("%v\n%s", err, actionsUsage)), nil
+		}
+		payload[payloadSince] = strconv.FormatInt(filter.since, 10)
+		payload[payloadLimit] = strconv.Itoa(filter.limit)
+		payload[payloadUserID] = filter.userID
+		payload[payloadBots] = string(filter.bots)
+		summaryTitle = fmt.Sprintf("Action Items (%s):", filter.description)
 	default:
 		return &model.CommandResponse{
 			ResponseType: model.CommandResponseTypeEphemeral,
-			Text:         "Usage: /summary [thread|channel]",
+			Text:         usage,
 		}, nil
 	}
 
+	// Generation takes longer than the slash command timeout on local models, so
+	// the summary is produced by a background job and streamed into an
+	// ephemeral bot post instead of the command response.
+	stream := newStreamingPost(h.client, h.cfg.BotID, args, summaryTitle)
+	payload[payloadPostID] = stream.post.Id
+	payload[payloadTitle] = summaryTitle
+
+	err = h.queue.Enqueue(&jobs.Job{
+		Type:    summaryJobType,
+		UserID:  args.UserId,
+		Payload: payload,
+	})
 	if err != nil {
-		return &model.CommandResponse{
-			ResponseType: model.CommandResponseTypeEphemeral,
-			Text:         fmt.Sprintf("Failed to get posts: %v", err),
-		}, nil
+		h.client.Log.Error("failed to enqueue summary job", "error", err.Error())
+		stream.Fail("Failed to schedule summary generation.")
 	}
 
-	summary := h.generateSummary(ctx, postList)
-	if summary == "" {
-		return &model.CommandResponse{
-			ResponseType: model.CommandResponseTypeEphemeral,
-			Text:         "Failed to generate summary.",
-		}, nil
+	return &model.CommandResponse{}, nil
+}
+
+// process generates the summary requested by a queued job and delivers it to
+// the requesting user.
+func (h Handler) process(ctx context.Context, job *jobs.Job) (string, error) {
+	stream := h.resumeStream(job)
+
+	mode := job.Payload[payloadMode]
+	channelID := job.Payload[payloadChannelID]
+
+	// Access is checked again when the job runs: the job may have been
+	// requested through the API, or the user may have left the channel since.
+	rootID := job.Payload[payloadRootID]
+	if mode != modeThread && mode != modeActions {
+		rootID = ""
+	}
+	if err := h.authorize(job.UserID, channelID, rootID); err != nil {
+		stream.Fail(h.describeAccessError(err, channelID))
+		return "", fmt.Errorf("failed to authorize summary: %w", err)
 	}
 
-	return &model.CommandResponse{
-		ResponseType: model.CommandResponseTypeEphemeral,
-		Text:         fmt.Sprintf("**%s**\n%s", summaryTitle, summary),
-	}, nil
-}
+	params := domain.Params{
+		Language: job.Payload[payloadLanguage],
+		Timezone: h.userTimezone(job.UserID),
+		Length:   job.Payload[payloadLength],
+		Fresh:    job.Payload[payloadFresh] == "true",
+	}
+	if isSearchMode(mode) {
+		return h.processSearch(ctx, job, stream, params)
+	}
+
+	postList, err := h.getPosts(job)
+	if err != nil {
+		stream.Fail(fmt.Sprintf("Failed to get posts: %v", err))
+		return "", err
+	}
 
-func (c *Handler) generateSummary(ctx context.Context, postList *model.PostList) string {
 	if postList == nil || len(postList.Posts) == 0 {
-		return "No messages found to summarize."
+		switch mode {
+		case modeUnread:
+			stream.Finish("You're all caught up: there are no unread messages in this channel.")
+		case modeActions:
+			stream.Finish("No messages found to extract action items from.")
+		default:
+			stream.Finish("No messages found to summarize.")
+		}
+		return "", nil
+	}
+
+	if mode == modeActions {
+		return h.processActions(ctx, job, stream, domain.OrderedPosts(postList), params)
 	}
 
-	summary, err := c.service.GenerateSummary(ctx, postList.ToSlice())
+	posts := domain.OrderedPosts(postList)
+	var summary domain.Summary
+	if mode == modeUnread {
+		summary, err = h.service.GenerateUnreadSummaryStream(ctx, job.UserID, channelID, posts, params, stream.Write)
+	} else {
+		summary, err = h.service.GenerateSummaryStream(ctx, posts, params, stream.Write)
+	}
 	if err != nil {
-		c.client.Log.Error("failed to generate summary: %w", err)
+		stream.Fail(describeGenerationError(err))
+		return "", fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	if summary.Structured != nil {
+		stream.SetProp(propStructuredSummary, summary.Structured)
+	}
+	h.deliverSummary(job, stream, posts, summary.Text+describeSkippedAttachments(summary.SkippedAttachments))
+
+	result, err := json.Marshal(summary)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode summary: %w", err)
+	}
+	return string(result), nil
+}
+
+// describeGenerationError returns the message shown to the user when a
+// summary could not be generated.
+func describeGenerationError(err error) string {
+	if errors.Is(err, llm.ErrUnavailable) {
+		return "The language model is temporarily unavailable. Please try again in a minute."
+	}
+	return "Failed to generate summary."
+}
+
+// describeSkippedAttachments returns a note listing the attached files the
+// summary does not cover, empty if there are none.
+func describeSkippedAttachments(skipped []domain.SkippedAttachment) string {
+	if len(skipped) == 0 {
 		return ""
 	}
-	return summary
+	files := make([]string, 0, len(skipped))
+	for _, file := range skipped {
+		files = append(files, fmt.Sprintf("%s (%s)", file.Name, file.Reason))
+	}
+	return "\n\n_Attachments not included in the summary: " + strings.Join(files, ", ") + "_"
+}
+
+func (h Handler) getPosts(job *jobs.Job) (*model.PostList, error) {
+	switch mode := job.Payload[payloadMode]; mode {
+	case modeThread:
+		return h.client.Post.GetPostThread(job.Payload[payloadRootID])
+	case modeChannel:
+		return h.getChannelPosts(job)
+	case modeUnread:
+		return h.getUnreadPosts(job)
+	case modeActions:
+		if rootID := job.Payload[payloadRootID]; rootID != "" {
+			return h.client.Post.GetPostThread(rootID)
+		}
+		return h.getChannelPosts(job)
+	default:
+		return nil, fmt.Errorf("unknown summary mode: %s", mode)
+	}
+}
+
+// getChannelPosts returns the channel posts selected by the filter in the job
+// payload.
+func (h Handler) getChannelPosts(job *jobs.Job) (*model.PostList, error) {
+	since, _ := strconv.ParseInt(job.Payload[payloadSince], 10, 64)
+	limit, err := strconv.Atoi(job.Payload[payloadLimit])
+	if err != nil || limit <= 0 {
+		limit = h.cfg.MaxMessages
+	}
+	return h.collectPosts(job.Payload[payloadChannelID], postFilter{
+		since:  since,
+		limit:  limit,
+		userID: job.Payload[payloadUserID],
+		bots:   botFilter(job.Payload[payloadBots]),
+	})
+}
+
+// channelFilter is a parsed `/summary channel` request.
+type channelFilter struct {
+	postFilter
+	description string
+}
+
+// parseChannelFilter turns `/summary channel` arguments into a post filter,
+// capping the number of posts at MaxMessages.
+func (h Handler) parseChannelFilter(args *model.CommandArgs, fields []string) (channelFilter, error) {
+	loc := time.UTC
+	if user, err := h.client.User.Get(args.UserId); err == nil {
+		if l, err := time.LoadLocation(user.GetPreferredTimezone()); err == nil {
+			loc = l
+		}
+	}
+
+	parsed, err := parseChannelArgs(fields, time.Now(), loc)
+	if err != nil {
+		return channelFilter{}, err
+	}
+
+	limit := h.cfg.MaxMessages
+	if parsed.last > 0 {
+		limit = min(parsed.last, h.cfg.MaxMessages)
+	}
+
+	filter := channelFilter{
+		postFilter:  postFilter{limit: limit},
+		description: parsed.describe(limit),
+	}
+	if !parsed.since.IsZero() {
+		filter.since = parsed.since.UnixMilli()
+	}
+	if parsed.fromUsername != "" {
+		user, err := h.client.User.GetByUsername(parsed.fromUsername)
+		if err != nil {
+			return channelFilter{}, fmt.Errorf("unknown user: @%s", parsed.fromUsername)
+		}
+		filter.userID = user.Id
+	}
+	filter.bots = parsed.bots
+
+	return filter, nil
 }
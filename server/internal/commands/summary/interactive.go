@@ -0,0 +1,409 @@
+package summary
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+// Summaries of threads, channels, unread messages and search results are
+// saved as a bot post in the direct messages of the requesting user. The post
+// carries message actions that generate the summary again, change its length
+// or language, or share it to the summarized channel.
+const (
+	actionRegenerate = "regenerate"
+	actionShorter    = "shorter"
+	actionLonger     = "longer"
+	actionLanguage   = "language"
+	actionShare      = "share"
+
+	// contextAction is the action in the context of a summary post action.
+	// The context is sent back by the client, so it holds nothing else.
+	contextAction = "action"
+	// contextSelectedOption is added by the server for select actions.
+	contextSelectedOption = "selected_option"
+
+	// The props of a summary post hold the user the summary belongs to and
+	// the request the summary was generated for, so that actions do not
+	// depend on the job, which may have expired, and the last completed
+	// summary, which is what gets shared while the post shows progress or an
+	// error. Only the bot can change them.
+	propSummaryUserID  = "summary_user_id"
+	propSummaryRequest = "summary_request"
+	propSummaryText    = "summary_text"
+	// propSummaryJobID is the job generating the summary again, so that only
+	// one job at a time streams into the post.
+	propSummaryJobID = "summary_job_id"
+
+	// payloadLength is the requested length of the summary, see
+	// domain.Params.Length.
+	payloadLength = "length"
+	// payloadFresh bypasses the summary cache.
+	payloadFresh = "fresh"
+	// payloadPersistent marks jobs that update the summary post in
+	// payloadPostID rather than an ephemeral post.
+	payloadPersistent = "persistent"
+)
+
+// requestKeys are the payload entries that describe a summary request and are
+// kept in the context of summary actions.
+var requestKeys = []string{
+	payloadMode,
+	payloadChannelID,
+	payloadRootID,
+	payloadTitle,
+	payloadSince,
+	payloadLimit,
+	payloadUserID,
+	payloadBots,
+	payloadQuery,
+	payloadLanguage,
+	payloadLength,
+}
+
+// resumeStream returns the post a summary job is delivered to: the summary
+// post for regenerated summaries, the ephemeral post of the slash command
+// otherwise.
+func (h Handler) resumeStream(job *jobs.Job) *streamingPost {
+	if job.Payload[payloadPersistent] == "true" {
+		post, err := h.client.Post.GetPost(job.Payload[payloadPostID])
+		if err == nil {
+			stream := resumePersistentPost(h.client, job.UserID, post, job.Payload[payloadTitle])
+			// The post may have been read before the action marked it.
+			stream.SetProp(propSummaryJobID, job.ID)
+			return stream
+		}
+		h.client.Log.Error("failed to get summary post", "post_id", job.Payload[payloadPostID], "error", err.Error())
+		// The summary is still stored in the job.
+		return resumeStreamingPost(h.client, h.cfg.BotID, job.UserID, "", "", "", job.Payload[payloadTitle])
+	}
+	return resumeStreamingPost(
+		h.client,
+		h.cfg.BotID,
+		job.UserID,
+		job.Payload[payloadChannelID],
+		job.Payload[payloadRootID],
+		job.Payload[payloadPostID],
+		job.Payload[payloadTitle],
+	)
+}
+
+// summaryRequest returns the request a summary job was run for. Unread
+// summaries are repeated as summaries of the channel since the oldest of the
+// posts, since the channel is read once the summary is shown.
+func (h Handler) summaryRequest(job *jobs.Job, posts []*model.Post) map[string]string {
+	request := make(map[string]string, len(requestKeys))
+	for _, key := range requestKeys {
+		if value := job.Payload[key]; value != "" {
+			request[key] = value
+		}
+	}
+	if request[payloadMode] == modeUnread && len(posts) > 0 {
+		request[payloadMode] = modeChannel
+		request[payloadSince] = strconv.FormatInt(posts[0].CreateAt-1, 10)
+		request[payloadLimit] = strconv.Itoa(h.cfg.MaxMessages)
+	}
+	return request
+}
+
+// deliverSummary finishes the summary job: an ephemeral post of a slash
+// command gets the summary together with a link to the summary post saved in
+// the direct messages, and a summary post being regenerated is updated in
+// place. Summaries requested through the API are only stored in the job.
+func (h Handler) deliverSummary(job *jobs.Job, stream *streamingPost, posts []*model.Post, text string) {
+	request := h.summaryRequest(job, posts)
+	if stream.persistent {
+		stream.SetProp(propSummaryUserID, job.UserID)
+		stream.SetProp(propSummaryRequest, request)
+		stream.SetProp(propSummaryText, stream.render(text))
+		stream.DelProp(propSummaryJobID)
+		stream.SetAttachments(h.summaryActions(request))
+		stream.Finish(text)
+		return
+	}
+	if stream.post.Id == "" {
+		return
+	}
+
+	post := &model.Post{Message: stream.render(text)}
+	post.AddProp(propSummaryUserID, job.UserID)
+	post.AddProp(propSummaryRequest, request)
+	post.AddProp(propSummaryText, post.Message)
+	model.ParseSlackAttachment(post, h.summaryActions(request))
+	if err := h.client.Post.DM(h.cfg.BotID, job.UserID, post); err != nil {
+		h.client.Log.Error("failed to save summary post", "user_id", job.UserID, "error", err.Error())
+		stream.Finish(text)
+		return
+	}
+	actions := "regenerate, shorten, translate or share it"
+	if isSearchMode(request[payloadMode]) {
+		actions = "regenerate, shorten or translate it"
+	}
+	stream.Finish(fmt.Sprintf("%s\n\n_[Saved to your direct messages](/_redirect/pl/%s), where you can %s._", text, post.Id, actions))
+}
+
+// summaryActions returns the attachment with the actions of a summary post,
+// which also tells what was summarized. Summaries of search results span
+// many channels and cannot be shared to one of them.
+func (h Handler) summaryActions(request map[string]string) []*model.SlackAttachment {
+	actionContext := func(action string) map[string]any {
+		return map[string]any{contextAction: action}
+	}
+	button := func(action, name string) *model.PostAction {
+		return &model.PostAction{
+			Id:          action,
+			Type:        model.PostActionTypeButton,
+			Name:        name,
+			Integration: &model.PostActionIntegration{URL: h.cfg.ActionURL, Context: actionContext(action)},
+		}
+	}
+
+	options := make([]*model.PostActionOptions, 0, len(languages))
+	for _, language := range languages {
+		options = append(options, &model.PostActionOptions{Text: language, Value: language})
+	}
+
+	actions := []*model.PostAction{
+		button(actionRegenerate, "Regenerate"),
+		button(actionShorter, "Shorter"),
+		button(actionLonger, "Longer"),
+		{
+			Id:            actionLanguage,
+			Type:          model.PostActionTypeSelect,
+			Name:          "Language",
+			DefaultOption: request[payloadLanguage],
+			Options:       options,
+			Integration:   &model.PostActionIntegration{URL: h.cfg.ActionURL, Context: actionContext(actionLanguage)},
+		},
+	}
+	if !isSearchMode(request[payloadMode]) {
+		actions = append(actions, button(actionShare, "Share to channel"))
+	}
+
+	return []*model.SlackAttachment{{
+		Text:    h.describeSource(request),
+		Actions: actions,
+	}}
+}
+
+// describeSource tells where the summarized posts are, as a reference to the
+// channel and a link to the thread, or the search query. Direct and group
+// messages are not named.
+func (h Handler) describeSource(request map[string]string) string {
+	switch request[payloadMode] {
+	case modeSearch:
+		return fmt.Sprintf("Summary of search results for `%s`", request[payloadQuery])
+	case modeTag:
+		return "Summary of messages tagged " + request[payloadQuery]
+	}
+
+	var source string
+	if rootID := request[payloadRootID]; rootID != "" && request[payloadMode] == modeThread {
+		source = fmt.Sprintf("[thread](/_redirect/pl/%s)", rootID)
+	}
+	channel, err := h.client.Channel.Get(request[payloadChannelID])
+	if err == nil && (channel.Type == model.ChannelTypeOpen || channel.Type == model.ChannelTypePrivate) {
+		if source != "" {
+			source += " in "
+		}
+		source += "~" + channel.Name
+	}
+	if source == "" {
+		return ""
+	}
+	return "Summary of " + source
+}
+
+// HandleAction runs an action of a summary post on behalf of userID. Summaries
+// are generated again in the background and streamed into the post.
+//
+// The request comes from the client, so only the action is taken from it: the
+// post must be a summary post of the bot in its direct messages with the user,
+// and the summary request is read from the post.
+func (h Handler) HandleAction(userID string, request *model.PostActionIntegrationRequest) *model.PostActionIntegrationResponse {
+	action, _ := request.Context[contextAction].(string)
+
+	post, err := h.client.Post.GetPost(request.PostId)
+	if err != nil {
+		h.client.Log.Error("failed to get summary post", "post_id", request.PostId, "error", err.Error())
+		return actionResponse("Failed to find the summary.")
+	}
+	summary, err := h.summaryOf(userID, post)
+	if err != nil {
+		h.client.Log.Warn("rejected summary action", "user_id", userID, "post_id", post.Id, "error", err.Error())
+		return actionResponse("This summary belongs to another user.")
+	}
+
+	switch action {
+	case actionShare:
+		return h.shareSummary(userID, post, summary)
+	case actionRegenerate:
+		summary[payloadFresh] = "true"
+	case actionShorter:
+		summary[payloadLength] = adjustLength(summary[payloadLength], -1)
+	case actionLonger:
+		summary[payloadLength] = adjustLength(summary[payloadLength], 1)
+	case actionLanguage:
+		language, _ := request.Context[contextSelectedOption].(string)
+		if !domain.IsSupportedLanguage(language) {
+			return actionResponse(fmt.Sprintf("Unsupported language: %q", language))
+		}
+		summary[payloadLanguage] = language
+	default:
+		return actionResponse(fmt.Sprintf("Unknown action: %q", action))
+	}
+	return h.regenerateSummary(userID, post, summary)
+}
+
+// regenerateSummary enqueues a summary job that streams into the summary post,
+// unless one is already pending for it. The actions stay on the post so that
+// a failed attempt can be repeated.
+func (h Handler) regenerateSummary(userID string, post *model.Post, payload map[string]string) *model.PostActionIntegrationResponse {
+	if h.isGenerating(post) {
+		return actionResponse("The summary is already being generated. Please wait until it is finished.")
+	}
+
+	payload[payloadPostID] = post.Id
+	payload[payloadPersistent] = "true"
+
+	stream := resumePersistentPost(h.client, userID, post, payload[payloadTitle])
+	job := &jobs.Job{
+		Type:    summaryJobType,
+		UserID:  userID,
+		Payload: payload,
+	}
+	if err := h.queue.Enqueue(job); err != nil {
+		h.client.Log.Error("failed to enqueue summary job", "error", err.Error())
+		stream.Fail("Failed to schedule summary generation.")
+		return &model.PostActionIntegrationResponse{}
+	}
+
+	stream.SetProp(propSummaryJobID, job.ID)
+	stream.Finish(pendingMessage)
+	return &model.PostActionIntegrationResponse{}
+}
+
+// isGenerating reports whether the job marked on the summary post is still
+// pending or running. Jobs that failed, expired or cannot be looked up do not
+// block new actions.
+func (h Handler) isGenerating(post *model.Post) bool {
+	jobID, _ := post.GetProp(propSummaryJobID).(string)
+	if jobID == "" {
+		return false
+	}
+	job, err := h.queue.Get(jobID)
+	if err != nil {
+		h.client.Log.Error("failed to get summary job", "job_id", jobID, "error", err.Error())
+		return false
+	}
+	return job != nil && (job.Status == jobs.StatusPending || job.Status == jobs.StatusRunning)
+}
+
+// shareSummary posts a copy of the last completed summary to the summarized
+// channel, or to the thread for thread summaries, as a post of the user. The
+// user must still be able to read the conversation and to post in the
+// channel.
+func (h Handler) shareSummary(userID string, post *model.Post, request map[string]string) *model.PostActionIntegrationResponse {
+	if isSearchMode(request[payloadMode]) {
+		return actionResponse("Summaries of search results cannot be shared to a channel.")
+	}
+	text, _ := post.GetProp(propSummaryText).(string)
+	if text == "" {
+		return actionResponse("There is no completed summary to share yet.")
+	}
+
+	channelID := request[payloadChannelID]
+	rootID := ""
+	if request[payloadMode] == modeThread {
+		rootID = request[payloadRootID]
+	}
+	if err := h.authorize(userID, channelID, rootID); err != nil {
+		return actionResponse(h.describeAccessError(err, channelID))
+	}
+	if !h.client.User.HasPermissionToChannel(userID, channelID, model.PermissionCreatePost) {
+		return actionResponse("You do not have permission to post in this channel.")
+	}
+
+	shared := &model.Post{
+		UserId:    userID,
+		ChannelId: channelID,
+		RootId:    rootID,
+		Message:   text,
+	}
+	if err := h.client.Post.CreatePost(shared); err != nil {
+		h.client.Log.Error("failed to share summary", "channel_id", channelID, "error", err.Error())
+		return actionResponse("Failed to share the summary.")
+	}
+	return actionResponse("The summary was shared to the channel.")
+}
+
+// summaryOf returns the request of a summary post that belongs to userID: a
+// post of the bot in its direct messages with the user, made for that user.
+func (h Handler) summaryOf(userID string, post *model.Post) (map[string]string, error) {
+	if post.UserId != h.cfg.BotID {
+		return nil, errors.New("not a post of the bot")
+	}
+	if owner, _ := post.GetProp(propSummaryUserID).(string); owner != userID {
+		return nil, errors.New("summary of another user")
+	}
+	channel, err := h.client.Channel.Get(post.ChannelId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+	if channel.Type != model.ChannelTypeDirect || channel.Name != model.GetDMNameFromIds(h.cfg.BotID, userID) {
+		return nil, errors.New("not a direct message with the user")
+	}
+	request := requestFromProp(post.GetProp(propSummaryRequest))
+	if request == nil {
+		return nil, errors.New("not a summary post")
+	}
+	return request, nil
+}
+
+// isSearchMode reports whether the summary mode summarizes search results,
+// which come from many channels.
+func isSearchMode(mode string) bool {
+	return mode == modeSearch || mode == modeTag
+}
+
+// requestFromProp reads the summary request back from a post prop, where it
+// arrives decoded from JSON.
+func requestFromProp(value any) map[string]string {
+	switch entries := value.(type) {
+	case map[string]string:
+		return maps.Clone(entries)
+	case map[string]any:
+		request := make(map[string]string, len(entries))
+		for key, entry := range entries {
+			if s, ok := entry.(string); ok {
+				request[key] = s
+			}
+		}
+		return request
+	default:
+		return nil
+	}
+}
+
+// adjustLength moves the summary length one step from the current one:
+// short, usual, long.
+func adjustLength(length string, step int) string {
+	lengths := []string{domain.LengthShort, "", domain.LengthLong}
+	current := 1
+	for i, l := range lengths {
+		if l == length {
+			current = i
+		}
+	}
+	return lengths[max(0, min(len(lengths)-1, current+step))]
+}
+
+func actionResponse(text string) *model.PostActionIntegrationResponse {
+	return &model.PostActionIntegrationResponse{EphemeralText: text}
+}
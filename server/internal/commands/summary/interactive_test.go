@@ -0,0 +1,448 @@
+package summary
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+// actionRequest builds the request the server sends when userID clicks an
+// action of the summary post post1.
+func actionRequest(action, userID string) *model.PostActionIntegrationRequest {
+	return &model.PostActionIntegrationRequest{
+		UserId:  userID,
+		PostId:  "post1",
+		Context: map[string]any{contextAction: action},
+	}
+}
+
+// summaryPost returns the summary post of user1 saved in the direct messages
+// with bot1 for the request.
+func summaryPost(request map[string]any) *model.Post {
+	post := &model.Post{Id: "post1", UserId: "bot1", ChannelId: "dm1", Message: "**Channel Summary (last 50 messages):**\nRelease moved to Friday."}
+	post.AddProp(propSummaryUserID, "user1")
+	post.AddProp(propSummaryRequest, request)
+	post.AddProp(propSummaryText, post.Message)
+	return post
+}
+
+// setupActionTest returns an environment where dm1 is the direct channel of
+// bot1 and user1.
+func setupActionTest() *env {
+	env := setupTest()
+	env.api.On("GetChannel", "dm1").Return(&model.Channel{Id: "dm1", Type: model.ChannelTypeDirect, Name: model.GetDMNameFromIds("bot1", "user1")}, nil).Maybe()
+	env.api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	return env
+}
+
+func TestHandler_HandleAction(t *testing.T) {
+	request := map[string]any{
+		payloadMode:      modeChannel,
+		payloadChannelID: "channel1",
+		payloadTitle:     "Channel Summary (last 50 messages):",
+		payloadLimit:     "50",
+		payloadLength:    domain.LengthShort,
+	}
+
+	tests := []struct {
+		name            string
+		action          string
+		selected        string
+		expectedPayload map[string]string
+	}{
+		{
+			name:   "should_regenerate_without_cache",
+			action: actionRegenerate,
+			expectedPayload: map[string]string{
+				payloadFresh:  "true",
+				payloadLength: domain.LengthShort,
+			},
+		},
+		{
+			name:            "should_keep_shortest_length",
+			action:          actionShorter,
+			expectedPayload: map[string]string{payloadLength: domain.LengthShort},
+		},
+		{
+			name:            "should_step_to_usual_length",
+			action:          actionLonger,
+			expectedPayload: map[string]string{payloadLength: ""},
+		},
+		{
+			name:     "should_switch_language",
+			action:   actionLanguage,
+			selected: domain.LanguageGerman,
+			expectedPayload: map[string]string{
+				payloadLanguage: domain.LanguageGerman,
+				payloadLength:   domain.LengthShort,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupActionTest()
+			env.api.On("GetPost", "post1").Return(summaryPost(request), nil)
+			env.api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+				return post.Id == "post1" && post.Message == "**Channel Summary (last 50 messages):**\n"+pendingMessage &&
+					post.GetProp(propSummaryJobID) == "job1"
+			})).Return(summaryPost(request), nil).Once()
+			queue := &memQueue{}
+			h := Handler{client: env.client, queue: queue, cfg: Config{BotID: "bot1"}}
+
+			action := actionRequest(tt.action, "user1")
+			if tt.selected != "" {
+				action.Context[contextSelectedOption] = tt.selected
+			}
+			response := h.HandleAction("user1", action)
+
+			assert.Empty(t, response.EphemeralText)
+			require.Len(t, queue.jobs, 1)
+			job := queue.jobs[0]
+			assert.Equal(t, summaryJobType, job.Type)
+			assert.Equal(t, "user1", job.UserID)
+			assert.Equal(t, "post1", job.Payload[payloadPostID])
+			assert.Equal(t, "true", job.Payload[payloadPersistent])
+			assert.Equal(t, "channel1", job.Payload[payloadChannelID])
+			for key, value := range tt.expectedPayload {
+				assert.Equal(t, value, job.Payload[key], key)
+			}
+			env.api.AssertExpectations(t)
+		})
+	}
+
+	forged := []struct {
+		name   string
+		userID string
+		post   func() *model.Post
+	}{
+		{
+			name:   "should_reject_actions_of_other_users",
+			userID: "user2",
+			post:   func() *model.Post { return summaryPost(request) },
+		},
+		{
+			name:   "should_reject_posts_of_other_users",
+			userID: "user1",
+			post: func() *model.Post {
+				post := summaryPost(request)
+				post.UserId = "user2"
+				return post
+			},
+		},
+		{
+			name:   "should_reject_bot_posts_outside_of_direct_messages_with_the_user",
+			userID: "user1",
+			post: func() *model.Post {
+				post := summaryPost(request)
+				post.ChannelId = "private1"
+				return post
+			},
+		},
+		{
+			name:   "should_reject_bot_posts_that_are_not_summaries",
+			userID: "user1",
+			post: func() *model.Post {
+				return &model.Post{Id: "post1", UserId: "bot1", ChannelId: "dm1", Message: "Your digest."}
+			},
+		},
+	}
+
+	for _, tt := range forged {
+		t.Run(tt.name, func(t *testing.T) {
+			env := setupActionTest()
+			env.api.On("GetPost", "post1").Return(tt.post(), nil)
+			env.api.On("GetChannel", "private1").Return(&model.Channel{Id: "private1", Type: model.ChannelTypePrivate, Name: "secret"}, nil).Maybe()
+			queue := &memQueue{}
+			h := Handler{client: env.client, queue: queue, cfg: Config{BotID: "bot1"}}
+
+			regenerate := h.HandleAction(tt.userID, actionRequest(actionRegenerate, tt.userID))
+			share := h.HandleAction(tt.userID, actionRequest(actionShare, tt.userID))
+
+			assert.Equal(t, "This summary belongs to another user.", regenerate.EphemeralText)
+			assert.Equal(t, "This summary belongs to another user.", share.EphemeralText)
+			assert.Empty(t, queue.jobs)
+			env.api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+			env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		})
+	}
+
+	t.Run("should_ignore_request_in_the_action_context", func(t *testing.T) {
+		env := setupActionTest()
+		env.api.On("GetPost", "post1").Return(summaryPost(request), nil)
+		env.api.On("UpdatePost", mock.Anything).Return(summaryPost(request), nil)
+		queue := &memQueue{}
+		h := Handler{client: env.client, queue: queue, cfg: Config{BotID: "bot1"}}
+
+		action := actionRequest(actionRegenerate, "user1")
+		action.Context["user_id"] = "user1"
+		action.Context["request"] = map[string]any{payloadMode: modeThread, payloadChannelID: "private1", payloadRootID: "secret1", payloadPostID: "other1"}
+		h.HandleAction("user1", action)
+
+		require.Len(t, queue.jobs, 1)
+		assert.Equal(t, modeChannel, queue.jobs[0].Payload[payloadMode])
+		assert.Equal(t, "channel1", queue.jobs[0].Payload[payloadChannelID])
+		assert.Equal(t, "post1", queue.jobs[0].Payload[payloadPostID])
+		assert.Empty(t, queue.jobs[0].Payload[payloadRootID])
+	})
+
+	t.Run("should_not_start_a_second_job_for_a_summary_post", func(t *testing.T) {
+		for _, status := range []jobs.Status{jobs.StatusPending, jobs.StatusRunning} {
+			env := setupActionTest()
+			post := summaryPost(request)
+			post.AddProp(propSummaryJobID, "job1")
+			env.api.On("GetPost", "post1").Return(post, nil)
+			queue := &memQueue{jobs: []*jobs.Job{{ID: "job1", Status: status}}}
+			h := Handler{client: env.client, queue: queue, cfg: Config{BotID: "bot1"}}
+
+			response := h.HandleAction("user1", actionRequest(actionShorter, "user1"))
+
+			assert.Equal(t, "The summary is already being generated. Please wait until it is finished.", response.EphemeralText)
+			assert.Len(t, queue.jobs, 1)
+			env.api.AssertNotCalled(t, "UpdatePost", mock.Anything)
+		}
+	})
+
+	t.Run("should_regenerate_after_previous_job_failed", func(t *testing.T) {
+		env := setupActionTest()
+		post := summaryPost(request)
+		post.AddProp(propSummaryJobID, "job1")
+		env.api.On("GetPost", "post1").Return(post, nil)
+		env.api.On("UpdatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.GetProp(propSummaryJobID) == "job2"
+		})).Return(summaryPost(request), nil).Once()
+		queue := &memQueue{jobs: []*jobs.Job{{ID: "job1", Status: jobs.StatusFailed}}}
+		h := Handler{client: env.client, queue: queue, cfg: Config{BotID: "bot1"}}
+
+		response := h.HandleAction("user1", actionRequest(actionRegenerate, "user1"))
+
+		assert.Empty(t, response.EphemeralText)
+		assert.Len(t, queue.jobs, 2)
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("should_reject_unsupported_language", func(t *testing.T) {
+		env := setupActionTest()
+		env.api.On("GetPost", "post1").Return(summaryPost(request), nil)
+		queue := &memQueue{}
+		h := Handler{client: env.client, queue: queue, cfg: Config{BotID: "bot1"}}
+
+		action := actionRequest(actionLanguage, "user1")
+		action.Context[contextSelectedOption] = "xx"
+		response := h.HandleAction("user1", action)
+
+		assert.Contains(t, response.EphemeralText, "Unsupported language")
+		assert.Empty(t, queue.jobs)
+	})
+
+	t.Run("should_share_thread_summary_as_post_of_the_user", func(t *testing.T) {
+		env := setupActionTest()
+		thread := summaryPost(map[string]any{
+			payloadMode:      modeThread,
+			payloadChannelID: "channel1",
+			payloadRootID:    "root1",
+		})
+		// The post shows the progress of a regeneration; the last completed
+		// summary is shared.
+		thread.Message = "**Thread Summary:**\n" + pendingMessage
+		thread.AddProp(propSummaryText, "**Thread Summary:**\nRelease moved to Friday.")
+		env.api.On("GetPost", "post1").Return(thread, nil)
+		env.api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionCreatePost).Return(true)
+		env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.UserId == "user1" && post.ChannelId == "channel1" && post.RootId == "root1" &&
+				post.Message == "**Thread Summary:**\nRelease moved to Friday."
+		})).Return(&model.Post{Id: "shared1"}, nil).Once()
+		h := Handler{
+			client: env.client,
+			access: fakeAccess{
+				readable: map[string]string{"user1": "channel1"},
+				posts:    map[string]*model.Post{"root1": {Id: "root1", ChannelId: "channel1"}},
+			},
+			cfg: Config{BotID: "bot1"},
+		}
+
+		response := h.HandleAction("user1", actionRequest(actionShare, "user1"))
+
+		assert.Equal(t, "The summary was shared to the channel.", response.EphemeralText)
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("should_not_share_before_a_summary_is_completed", func(t *testing.T) {
+		env := setupActionTest()
+		post := summaryPost(request)
+		post.Message = "**Channel Summary (last 50 messages):**\nFailed to generate summary."
+		post.DelProp(propSummaryText)
+		env.api.On("GetPost", "post1").Return(post, nil)
+		h := Handler{client: env.client, access: fakeAccess{readable: map[string]string{"user1": "channel1"}}, cfg: Config{BotID: "bot1"}}
+
+		response := h.HandleAction("user1", actionRequest(actionShare, "user1"))
+
+		assert.Equal(t, "There is no completed summary to share yet.", response.EphemeralText)
+		env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("should_not_share_search_summaries", func(t *testing.T) {
+		env := setupActionTest()
+		env.api.On("GetPost", "post1").Return(summaryPost(map[string]any{
+			payloadMode:      modeSearch,
+			payloadChannelID: "channel1",
+			payloadQuery:     "outage in:private",
+		}), nil)
+		h := Handler{client: env.client, access: fakeAccess{readable: map[string]string{"user1": "channel1"}}, cfg: Config{BotID: "bot1"}}
+
+		response := h.HandleAction("user1", actionRequest(actionShare, "user1"))
+
+		assert.Equal(t, "Summaries of search results cannot be shared to a channel.", response.EphemeralText)
+		env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("should_not_share_without_permission_to_post", func(t *testing.T) {
+		env := setupActionTest()
+		env.api.On("GetPost", "post1").Return(summaryPost(request), nil)
+		env.api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionCreatePost).Return(false)
+		h := Handler{client: env.client, access: fakeAccess{readable: map[string]string{"user1": "channel1"}}, cfg: Config{BotID: "bot1"}}
+
+		response := h.HandleAction("user1", actionRequest(actionShare, "user1"))
+
+		assert.Equal(t, "You do not have permission to post in this channel.", response.EphemeralText)
+		env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("should_not_share_after_losing_access", func(t *testing.T) {
+		env := setupActionTest()
+		env.api.On("GetPost", "post1").Return(summaryPost(request), nil)
+		h := Handler{client: env.client, access: fakeAccess{}, cfg: Config{BotID: "bot1"}}
+
+		response := h.HandleAction("user1", actionRequest(actionShare, "user1"))
+
+		assert.Equal(t, "You do not have permission to read this channel.", response.EphemeralText)
+		env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
+
+func TestHandler_deliverSummary(t *testing.T) {
+	job := func(payload map[string]string) *jobs.Job {
+		return &jobs.Job{Type: summaryJobType, UserID: "user1", Payload: payload}
+	}
+	actionsOf := func(post *model.Post) []string {
+		var ids []string
+		for _, attachment := range post.Attachments() {
+			for _, action := range attachment.Actions {
+				ids = append(ids, action.Id)
+				if action.Integration == nil || action.Integration.URL != "/plugins/summary/api/v1/summary/actions" {
+					return nil
+				}
+			}
+		}
+		return ids
+	}
+	expectedActions := []string{actionRegenerate, actionShorter, actionLonger, actionLanguage, actionShare}
+
+	t.Run("should_save_summary_post_in_direct_messages", func(t *testing.T) {
+		env := setupTest()
+		env.api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", Name: "release", Type: model.ChannelTypeOpen}, nil)
+		env.api.On("GetDirectChannel", "bot1", "user1").Return(&model.Channel{Id: "dm1"}, nil)
+		var saved *model.Post
+		env.api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(a mock.Arguments) {
+			saved = a.Get(0).(*model.Post).Clone()
+		}).Return(&model.Post{Id: "summary1"}, nil)
+		var finished string
+		env.api.On("UpdateEphemeralPost", "user1", mock.AnythingOfType("*model.Post")).Run(func(a mock.Arguments) {
+			finished = a.Get(1).(*model.Post).Message
+		}).Return(&model.Post{})
+		h := Handler{client: env.client, cfg: Config{BotID: "bot1", MaxMessages: 100, ActionURL: "/plugins/summary/api/v1/summary/actions"}}
+
+		j := job(map[string]string{
+			payloadMode:      modeUnread,
+			payloadChannelID: "channel1",
+			payloadPostID:    "ephemeral1",
+			payloadTitle:     "Unread Messages Summary:",
+		})
+		stream := h.resumeStream(j)
+		h.deliverSummary(j, stream, []*model.Post{{Id: "p1", CreateAt: 1000}, {Id: "p2", CreateAt: 2000}}, "Release moved to Friday.")
+
+		require.NotNil(t, saved)
+		assert.Equal(t, "dm1", saved.ChannelId)
+		assert.Equal(t, "bot1", saved.UserId)
+		assert.Equal(t, "**Unread Messages Summary:**\nRelease moved to Friday.", saved.Message)
+		assert.Equal(t, expectedActions, actionsOf(saved))
+		attachment := saved.Attachments()[0]
+		assert.Equal(t, "Summary of ~release", attachment.Text)
+		assert.Equal(t, map[string]any{
+			payloadMode:      modeChannel,
+			payloadChannelID: "channel1",
+			payloadTitle:     "Unread Messages Summary:",
+			payloadSince:     "999",
+			payloadLimit:     "100",
+		}, toAnyMap(saved.GetProp(propSummaryRequest)), "unread summaries are repeated for the same posts")
+		assert.Equal(t, "user1", saved.GetProp(propSummaryUserID))
+		assert.Equal(t, saved.Message, saved.GetProp(propSummaryText))
+		assert.Equal(t, map[string]any{contextAction: actionRegenerate}, attachment.Actions[0].Integration.Context)
+		assert.True(t, strings.HasPrefix(finished, "**Unread Messages Summary:**\nRelease moved to Friday.\n\n_[Saved to your direct messages](/_redirect/pl/summary1)"))
+	})
+
+	t.Run("should_update_regenerated_summary_post", func(t *testing.T) {
+		env := setupTest()
+		summary := &model.Post{Id: "summary1", UserId: "bot1", ChannelId: "dm1"}
+		summary.AddProp(propSummaryJobID, "job1")
+		env.api.On("GetPost", "summary1").Return(summary, nil)
+		env.api.On("GetChannel", "channel1").Return(nil, model.NewAppError("test", "not_found", nil, "", 404))
+		var updated *model.Post
+		env.api.On("UpdatePost", mock.AnythingOfType("*model.Post")).Run(func(a mock.Arguments) {
+			updated = a.Get(0).(*model.Post).Clone()
+		}).Return(func(post *model.Post) *model.Post { return post.Clone() }, nil)
+		h := Handler{client: env.client, cfg: Config{BotID: "bot1", ActionURL: "/plugins/summary/api/v1/summary/actions"}}
+
+		j := job(map[string]string{
+			payloadMode:       modeChannel,
+			payloadChannelID:  "channel1",
+			payloadPostID:     "summary1",
+			payloadPersistent: "true",
+			payloadFresh:      "true",
+			payloadLength:     domain.LengthLong,
+			payloadTitle:      "Channel Summary:",
+		})
+		h.deliverSummary(j, h.resumeStream(j), nil, "Longer summary.")
+
+		require.NotNil(t, updated)
+		assert.Equal(t, "dm1", updated.ChannelId)
+		assert.Equal(t, "**Channel Summary:**\nLonger summary.", updated.Message)
+		assert.Equal(t, updated.Message, updated.GetProp(propSummaryText))
+		assert.Nil(t, updated.GetProp(propSummaryJobID), "actions are accepted again")
+		assert.Equal(t, expectedActions, actionsOf(updated))
+		request := toAnyMap(updated.GetProp(propSummaryRequest))
+		assert.Equal(t, domain.LengthLong, request[payloadLength])
+		assert.NotContains(t, request, payloadFresh, "regenerating is not repeated by other actions")
+		env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
+
+func TestAdjustLength(t *testing.T) {
+	assert.Equal(t, domain.LengthShort, adjustLength("", -1))
+	assert.Equal(t, domain.LengthShort, adjustLength(domain.LengthShort, -1))
+	assert.Equal(t, "", adjustLength(domain.LengthShort, 1))
+	assert.Equal(t, domain.LengthLong, adjustLength("", 1))
+	assert.Equal(t, domain.LengthLong, adjustLength(domain.LengthLong, 1))
+}
+
+// toAnyMap converts a request kept in a post prop to map[string]any the way
+// it arrives from the server.
+func toAnyMap(value any) map[string]any {
+	result := map[string]any{}
+	switch m := value.(type) {
+	case map[string]string:
+		for k, v := range m {
+			result[k] = v
+		}
+	case map[string]any:
+		return m
+	}
+	return result
+}
@@ -0,0 +1,382 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/EgorTarasov/summary/server/internal/domain/digest"
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+const (
+	modeDigest = "digest"
+
+	digestNow = "now"
+
+	personalDigestJobType = "personal_digest"
+
+	// payloadScheduled marks personal digests enqueued by the schedule rather
+	// than requested by the user.
+	payloadScheduled = "scheduled"
+
+	digestUsage = "Usage: /summary digest [now|show|daily 08:00|weekly mon 08:00|off] [--tz Europe/Berlin] [--lang auto|en|ru|es|fr|de]"
+
+	// maxDigestChannels is the number of most active channels a personal
+	// digest covers.
+	maxDigestChannels = 10
+
+	// maxDigestChannelPosts bounds the unread posts summarized per channel.
+	maxDigestChannelPosts = 50
+
+	// membersPageSize is the number of channel memberships requested per page.
+	membersPageSize = 200
+)
+
+func newDigestAutocompleteData() *model.AutocompleteData {
+	data := model.NewAutocompleteData(modeDigest, "[now|show|daily|weekly|off]", "Get a direct message summarizing your unread channels")
+
+	now := model.NewAutocompleteData(digestNow, "[--lang en]", "Send your digest now")
+	addLanguageArgument(now)
+	data.AddCommand(now)
+
+	data.AddCommand(model.NewAutocompleteData(scheduleShow, "", "Show the schedule of your digest"))
+
+	daily := model.NewAutocompleteData(digest.FrequencyDaily, "08:00 [--tz Europe/Berlin] [--lang en]", "Send your digest every day")
+	daily.AddTextArgument("Time of day", "08:00", "[0-9]{1,2}:[0-9]{2}")
+	addScheduleArguments(daily)
+	data.AddCommand(daily)
+
+	weekly := model.NewAutocompleteData(digest.FrequencyWeekly, "mon 08:00 [--tz Europe/Berlin] [--lang en]", "Send your digest every week")
+	weekly.AddStaticListArgument("Day of the week", true, weekdayItems())
+	weekly.AddTextArgument("Time of day", "08:00", "[0-9]{1,2}:[0-9]{2}")
+	addScheduleArguments(weekly)
+	data.AddCommand(weekly)
+
+	data.AddCommand(model.NewAutocompleteData(scheduleOff, "", "Stop sending your digest"))
+	return data
+}
+
+// handleDigest sends the personal digest of the user now or manages its
+// schedule. The digest covers all channels of the user, so unlike other
+// modes it does not depend on the current channel.
+func (h Handler) handleDigest(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	action := digestNow
+	if len(fields) > 0 && !strings.HasPrefix(fields[0], "--") {
+		action = fields[0]
+	}
+
+	switch action {
+	case digestNow:
+		if len(fields) > 0 && fields[0] == digestNow {
+			fields = fields[1:]
+		}
+		return h.requestPersonalDigest(args, fields)
+	case scheduleShow:
+		return ephemeral(h.describePersonalDigest(args.UserId))
+	case digest.FrequencyDaily, digest.FrequencyWeekly:
+		return h.setPersonalDigest(args, fields)
+	case scheduleOff:
+		if err := h.personal.DeletePersonalDigest(args.UserId); err != nil {
+			h.client.Log.Error("failed to delete personal digest schedule", "user_id", args.UserId, "error", err.Error())
+			return ephemeral("Failed to turn off your digest.")
+		}
+		return ephemeral("Your scheduled digest was turned off.")
+	default:
+		return ephemeral(digestUsage)
+	}
+}
+
+func (h Handler) requestPersonalDigest(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	fields, language, err := extractLanguage(fields)
+	if err != nil {
+		return ephemeral(fmt.Sprintf("%v\n%s", err, digestUsage))
+	}
+	if len(fields) > 0 {
+		return ephemeral(digestUsage)
+	}
+
+	err = h.queue.Enqueue(&jobs.Job{
+		Type:    personalDigestJobType,
+		UserID:  args.UserId,
+		Payload: map[string]string{payloadLanguage: language},
+	})
+	if err != nil {
+		h.client.Log.Error("failed to enqueue personal digest", "user_id", args.UserId, "error", err.Error())
+		return ephemeral("Failed to schedule your digest.")
+	}
+	return ephemeral("Your digest is being prepared and will arrive as a direct message.")
+}
+
+func (h Handler) setPersonalDigest(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	fields, language, err := extractLanguage(fields)
+	if err != nil {
+		return ephemeral(fmt.Sprintf("%v\n%s", err, digestUsage))
+	}
+	fields, timezone, err := extractTimezone(fields, h.userTimezone(args.UserId))
+	if err != nil {
+		return ephemeral(fmt.Sprintf("%v\n%s", err, digestUsage))
+	}
+
+	schedule, err := digest.Parse(fields)
+	if err != nil {
+		return ephemeral(fmt.Sprintf("%v\n%s", err, digestUsage))
+	}
+	schedule.Timezone = timezone
+	schedule.Delivery = digest.DeliveryDM
+	schedule.Language = language
+	schedule.CreatedBy = args.UserId
+	next := schedule.Next(time.Now())
+	schedule.NextRunAt = next.UnixMilli()
+
+	if err := h.personal.SavePersonalDigest(&schedule); err != nil {
+		h.client.Log.Error("failed to save personal digest schedule", "user_id", args.UserId, "error", err.Error())
+		return ephemeral("Failed to save the schedule of your digest.")
+	}
+	return ephemeral(fmt.Sprintf("Your digest is scheduled %s and will be sent as a direct message. The first digest is due %s.",
+		schedule.Describe(), next.Format(scheduleTimeLayout)))
+}
+
+func (h Handler) describePersonalDigest(userID string) string {
+	schedule, err := h.personal.GetPersonalDigest(userID)
+	if err != nil {
+		h.client.Log.Error("failed to get personal digest schedule", "user_id", userID, "error", err.Error())
+		return "Failed to load the schedule of your digest."
+	}
+	if schedule == nil {
+		return "Your digest is not scheduled. Use `/summary digest` to get it now.\n" + digestUsage
+	}
+	next := time.UnixMilli(schedule.NextRunAt).In(schedule.Location())
+	return fmt.Sprintf("Your digest is scheduled %s. The next digest is due %s.", schedule.Describe(), next.Format(scheduleTimeLayout))
+}
+
+// enqueueDuePersonalDigests enqueues a personal digest job for every user
+// whose digest is due at now.
+func (h Handler) enqueueDuePersonalDigests(now time.Time) {
+	userIDs, err := h.personal.ListPersonalDigestUsers()
+	if err != nil {
+		h.client.Log.Error("failed to list personal digest schedules", "error", err.Error())
+		return
+	}
+
+	for _, userID := range userIDs {
+		var dueAt int64
+		schedule, err := h.personal.UpdatePersonalDigest(userID, claimRun(now, &dueAt))
+		if errors.Is(err, errDigestNotDue) {
+			continue
+		}
+		if err != nil {
+			h.client.Log.Error("failed to claim personal digest", "user_id", userID, "error", err.Error())
+			continue
+		}
+
+		err = h.queue.Enqueue(&jobs.Job{
+			Type:   personalDigestJobType,
+			UserID: userID,
+			Payload: map[string]string{
+				payloadLanguage:  schedule.Language,
+				payloadScheduled: "true",
+			},
+		})
+		if err != nil {
+			h.client.Log.Error("failed to enqueue personal digest", "user_id", userID, "error", err.Error())
+		}
+	}
+}
+
+// processPersonalDigest briefly summarizes the unread messages of the most
+// active channels of the user and sends them as a single direct message.
+// Channels are ranked by unread messages and mentions of the user. Scheduled
+// digests are not sent when there is nothing unread.
+func (h Handler) processPersonalDigest(ctx context.Context, job *jobs.Job) (string, error) {
+	scheduled := job.Payload[payloadScheduled] == "true"
+	if scheduled {
+		schedule, err := h.personal.GetPersonalDigest(job.UserID)
+		if err != nil {
+			return "", err
+		}
+		if schedule == nil {
+			// The digest was turned off after it had been enqueued.
+			return "", nil
+		}
+	}
+
+	activities, err := h.unreadActivity(job.UserID)
+	if err != nil {
+		if !scheduled {
+			h.sendPersonalDigest(job.UserID, "Failed to prepare your digest.")
+		}
+		return "", fmt.Errorf("failed to get unread channels: %w", err)
+	}
+
+	params := domain.Params{Language: job.Payload[payloadLanguage], Timezone: h.userTimezone(job.UserID)}
+	var (
+		sections []string
+		lastErr  error
+	)
+	for _, activity := range digest.Rank(activities, maxDigestChannels) {
+		section, err := h.digestSection(ctx, job.UserID, activity, params)
+		if errors.Is(err, domain.ErrForbidden) {
+			continue
+		}
+		if err != nil {
+			h.client.Log.Error("failed to summarize channel for personal digest", "channel_id", activity.ChannelID, "user_id", job.UserID, "error", err.Error())
+			lastErr = err
+			continue
+		}
+		if section != "" {
+			sections = append(sections, section)
+		}
+	}
+
+	if len(sections) == 0 {
+		if lastErr != nil {
+			if !scheduled {
+				h.sendPersonalDigest(job.UserID, "Failed to prepare your digest.")
+			}
+			return "", fmt.Errorf("failed to generate personal digest: %w", lastErr)
+		}
+		if !scheduled {
+			h.sendPersonalDigest(job.UserID, "You're all caught up: there are no unread messages in your channels.")
+		}
+		return "", nil
+	}
+
+	message := fmt.Sprintf("**Your digest: %d channel(s) with unread messages**\n\n%s", len(sections), strings.Join(sections, "\n\n"))
+	h.sendPersonalDigest(job.UserID, message)
+
+	result, err := json.Marshal(domain.Summary{Text: message})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode digest: %w", err)
+	}
+	return string(result), nil
+}
+
+// unreadActivity returns the unread activity of the user in every channel
+// of every team the user is a member of. Direct and group messages are listed
+// for each team and counted once.
+func (h Handler) unreadActivity(userID string) ([]digest.Activity, error) {
+	teams, err := h.client.Team.List(pluginapi.FilterTeamsByUser(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+
+	var channels []*model.Channel
+	seen := make(map[string]struct{})
+	members := make(map[string]*model.ChannelMember)
+	for _, team := range teams {
+		teamChannels, err := h.client.Channel.ListForTeamForUser(team.Id, userID, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list channels: %w", err)
+		}
+		for _, channel := range teamChannels {
+			if _, ok := seen[channel.Id]; !ok {
+				seen[channel.Id] = struct{}{}
+				channels = append(channels, channel)
+			}
+		}
+
+		for page := 0; ; page++ {
+			teamMembers, err := h.client.Channel.ListMembersForUser(team.Id, userID, page, membersPageSize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list channel memberships: %w", err)
+			}
+			for _, member := range teamMembers {
+				members[member.ChannelId] = member
+			}
+			if len(teamMembers) < membersPageSize {
+				break
+			}
+		}
+	}
+
+	activities := make([]digest.Activity, 0, len(channels))
+	for _, channel := range channels {
+		member, ok := members[channel.Id]
+		if !ok {
+			continue
+		}
+		activities = append(activities, digest.Activity{
+			ChannelID:    channel.Id,
+			Unread:       channel.TotalMsgCount - member.MsgCount,
+			Mentions:     member.MentionCount,
+			LastViewedAt: member.LastViewedAt,
+		})
+	}
+	return activities, nil
+}
+
+// digestSection briefly summarizes the unread messages of one channel,
+// leaving out messages of the user and the bot. It returns an empty section
+// if no such messages are left.
+func (h Handler) digestSection(ctx context.Context, userID string, activity digest.Activity, params domain.Params) (string, error) {
+	if err := h.access.AuthorizeChannel(userID, activity.ChannelID); err != nil {
+		return "", err
+	}
+
+	postList, err := h.collectPosts(activity.ChannelID, postFilter{
+		since:          activity.LastViewedAt,
+		limit:          maxDigestChannelPosts,
+		excludeUserIDs: []string{userID, h.cfg.BotID},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get posts: %w", err)
+	}
+	if len(postList.Order) == 0 {
+		return "", nil
+	}
+	posts := domain.OrderedPosts(postList)
+	brief, err := h.service.GenerateBrief(ctx, posts, params)
+	if err != nil {
+		return "", err
+	}
+
+	channel, err := h.client.Channel.Get(activity.ChannelID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get channel: %w", err)
+	}
+
+	counts := fmt.Sprintf("%d unread", activity.Unread)
+	if activity.Mentions > 0 {
+		counts += fmt.Sprintf(", %d mention(s)", activity.Mentions)
+	}
+	return fmt.Sprintf("**%s** · %s\n%s\n[Jump to the first unread message](%s/_redirect/pl/%s)",
+		h.channelLabel(channel, userID), counts, brief, h.siteURL(), posts[0].Id), nil
+}
+
+// channelLabel names a channel in messages of the bot: channels of a team by
+// a link to them, direct and group messages by their participants.
+func (h Handler) channelLabel(channel *model.Channel, userID string) string {
+	if channel.TeamId == "" {
+		return h.channelName(channel, userID)
+	}
+	return h.channelLink(channel)
+}
+
+// channelName returns the display name of a channel, or the other user of a
+// direct message as seen by userID.
+func (h Handler) channelName(channel *model.Channel, userID string) string {
+	if channel.Type == model.ChannelTypeDirect {
+		if user, err := h.client.User.Get(channel.GetOtherUserIdForDM(userID)); err == nil {
+			return "@" + user.Username
+		}
+		return "Direct message"
+	}
+	if channel.DisplayName != "" {
+		return channel.DisplayName
+	}
+	return channel.Name
+}
+
+func (h Handler) sendPersonalDigest(userID, message string) {
+	if err := h.client.Post.DM(h.cfg.BotID, userID, &model.Post{Message: message}); err != nil {
+		h.client.Log.Error("failed to send personal digest", "user_id", userID, "error", err.Error())
+	}
+}
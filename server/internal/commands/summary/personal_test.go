@@ -0,0 +1,226 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgorTarasov/summary/server/internal/domain/digest"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+type memPersonalDigestStore map[string]*digest.Schedule
+
+func (m memPersonalDigestStore) GetPersonalDigest(userID string) (*digest.Schedule, error) {
+	return m[userID], nil
+}
+
+func (m memPersonalDigestStore) SavePersonalDigest(schedule *digest.Schedule) error {
+	m[schedule.CreatedBy] = schedule
+	return nil
+}
+
+func (m memPersonalDigestStore) UpdatePersonalDigest(userID string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error) {
+	schedule, ok := m[userID]
+	if !ok {
+		return nil, errors.New("digest schedule does not exist")
+	}
+	updated := *schedule
+	if err := update(&updated); err != nil {
+		return nil, err
+	}
+	m[userID] = &updated
+	return &updated, nil
+}
+
+func (m memPersonalDigestStore) DeletePersonalDigest(userID string) error {
+	delete(m, userID)
+	return nil
+}
+
+func (m memPersonalDigestStore) ListPersonalDigestUsers() ([]string, error) {
+	userIDs := make([]string, 0, len(m))
+	for userID := range m {
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+func TestHandler_handleDigest(t *testing.T) {
+	args := &model.CommandArgs{UserId: "user1", ChannelId: "channel1"}
+	setup := func() (Handler, *memQueue, memPersonalDigestStore) {
+		env := setupTest()
+		env.api.On("GetUser", "user1").Return(&model.User{Id: "user1", Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "Europe/Berlin"}}, nil).Maybe()
+		queue := &memQueue{}
+		store := memPersonalDigestStore{}
+		return Handler{client: env.client, queue: queue, personal: store}, queue, store
+	}
+
+	t.Run("should_enqueue_digest_now", func(t *testing.T) {
+		h, queue, _ := setup()
+
+		resp := h.handleDigest(args, []string{"--lang", "en"})
+
+		assert.Contains(t, resp.Text, "will arrive as a direct message")
+		require.Len(t, queue.jobs, 1)
+		assert.Equal(t, personalDigestJobType, queue.jobs[0].Type)
+		assert.Equal(t, "user1", queue.jobs[0].UserID)
+		assert.Equal(t, "en", queue.jobs[0].Payload[payloadLanguage])
+		assert.Empty(t, queue.jobs[0].Payload[payloadScheduled])
+	})
+
+	t.Run("should_schedule_daily_digest_in_user_timezone", func(t *testing.T) {
+		h, queue, store := setup()
+
+		resp := h.handleDigest(args, []string{"daily", "08:00"})
+
+		require.Contains(t, store, "user1")
+		schedule := store["user1"]
+		assert.Equal(t, digest.FrequencyDaily, schedule.Frequency)
+		assert.Equal(t, "Europe/Berlin", schedule.Timezone)
+		assert.Equal(t, digest.DeliveryDM, schedule.Delivery)
+		assert.Contains(t, resp.Text, "daily at 08:00 (Europe/Berlin)")
+		assert.Empty(t, queue.jobs)
+	})
+
+	t.Run("should_reject_invalid_schedule", func(t *testing.T) {
+		h, _, store := setup()
+
+		resp := h.handleDigest(args, []string{"daily", "08:00", "--tz", "Mars/Olympus"})
+
+		assert.Contains(t, resp.Text, "unknown time zone")
+		assert.Empty(t, store)
+	})
+
+	t.Run("should_turn_digest_off", func(t *testing.T) {
+		h, _, store := setup()
+		store["user1"] = &digest.Schedule{CreatedBy: "user1"}
+
+		resp := h.handleDigest(args, []string{"off"})
+
+		assert.Contains(t, resp.Text, "turned off")
+		assert.Empty(t, store)
+	})
+}
+
+func TestHandler_enqueueDuePersonalDigests(t *testing.T) {
+	now := time.Date(2026, 3, 10, 8, 0, 30, 0, time.UTC)
+	dueAt := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+
+	env := setupTest()
+	queue := &memQueue{}
+	store := memPersonalDigestStore{
+		"due":     {CreatedBy: "due", Frequency: digest.FrequencyDaily, Hour: 8, Language: "en", NextRunAt: dueAt.UnixMilli()},
+		"not_due": {CreatedBy: "not_due", Frequency: digest.FrequencyDaily, Hour: 18, NextRunAt: dueAt.Add(10 * time.Hour).UnixMilli()},
+	}
+	h := Handler{client: env.client, queue: queue, schedules: memScheduleStore{}, personal: store}
+
+	h.EnqueueDueDigests(now)
+	h.EnqueueDueDigests(now)
+
+	require.Len(t, queue.jobs, 1, "a due digest is enqueued once")
+	job := queue.jobs[0]
+	assert.Equal(t, personalDigestJobType, job.Type)
+	assert.Equal(t, "due", job.UserID)
+	assert.Equal(t, "en", job.Payload[payloadLanguage])
+	assert.Equal(t, "true", job.Payload[payloadScheduled])
+	assert.Equal(t, dueAt.Add(24*time.Hour).UnixMilli(), store["due"].NextRunAt)
+}
+
+func TestHandler_processPersonalDigest(t *testing.T) {
+	siteURL := "https://chat.example.com"
+	channels := []*model.Channel{
+		{Id: "channel1", TeamId: "team1", Name: "release", DisplayName: "Release", Type: model.ChannelTypeOpen, TotalMsgCount: 8},
+		{Id: "channel2", TeamId: "team1", Name: "secret", DisplayName: "Secret", Type: model.ChannelTypePrivate, TotalMsgCount: 40},
+		{Id: "channel3", TeamId: "team1", Name: "quiet", DisplayName: "Quiet", Type: model.ChannelTypeOpen, TotalMsgCount: 3},
+	}
+	members := []*model.ChannelMember{
+		{ChannelId: "channel1", UserId: "user1", MsgCount: 5, MentionCount: 1, LastViewedAt: 1000},
+		{ChannelId: "channel2", UserId: "user1", MsgCount: 10},
+		{ChannelId: "channel3", UserId: "user1", MsgCount: 3},
+	}
+	postList := &model.PostList{
+		Order: []string{"p3", "p2", "p1", "p0"},
+		Posts: map[string]*model.Post{
+			"p0": {Id: "p0", ChannelId: "channel1", UserId: "user2", Message: "Already read", CreateAt: 500},
+			"p1": {Id: "p1", ChannelId: "channel1", UserId: "user2", Message: "Release moved to Friday", CreateAt: 2000},
+			"p2": {Id: "p2", ChannelId: "channel1", UserId: "user1", Message: "My own reply", CreateAt: 3000},
+			"p3": {Id: "p3", ChannelId: "channel1", UserId: "user2", Message: "@user1 please check", CreateAt: 4000},
+		},
+	}
+
+	setup := func(members []*model.ChannelMember) (*env, Handler, *fakeSummarizer) {
+		env := setupTest()
+		env.api.On("GetTeamsForUser", "user1").Return([]*model.Team{{Id: "team1"}}, nil)
+		env.api.On("GetChannelsForTeamForUser", "team1", "user1", false).Return(channels, nil)
+		env.api.On("GetChannelMembersForUser", "team1", "user1", 0, membersPageSize).Return(members, nil)
+		env.api.On("GetPostsForChannel", "channel1", 0, postsPageSize).Return(postList, nil).Maybe()
+		env.api.On("GetChannel", "channel1").Return(channels[0], nil).Maybe()
+		env.api.On("GetTeam", "team1").Return(&model.Team{Id: "team1", Name: "eng"}, nil).Maybe()
+		env.api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}}).Maybe()
+		env.api.On("GetDirectChannel", "bot1", "user1").Return(&model.Channel{Id: "dm1"}, nil).Maybe()
+		env.api.On("GetUser", "user1").Return(&model.User{Id: "user1"}, nil).Maybe()
+		service := &fakeSummarizer{text: "The release moved to Friday."}
+		h := Handler{
+			client:   env.client,
+			service:  service,
+			access:   fakeAccess{readable: map[string]string{"user1": "channel1"}},
+			personal: memPersonalDigestStore{"user1": {CreatedBy: "user1"}},
+			cfg:      Config{BotID: "bot1", MaxMessages: 100},
+		}
+		return env, h, service
+	}
+
+	t.Run("should_send_brief_of_readable_unread_channels", func(t *testing.T) {
+		env, h, service := setup(members)
+		env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dm1" && post.UserId == "bot1" &&
+				strings.HasPrefix(post.Message, "**Your digest: 1 channel(s) with unread messages**") &&
+				strings.Contains(post.Message, "**[~Release](https://chat.example.com/eng/channels/release)** · 3 unread, 1 mention(s)\nThe release moved to Friday.") &&
+				strings.Contains(post.Message, "(https://chat.example.com/_redirect/pl/p1)")
+		})).Return(&model.Post{}, nil).Once()
+
+		_, err := h.processPersonalDigest(context.Background(), &jobs.Job{Type: personalDigestJobType, UserID: "user1", Payload: map[string]string{}})
+
+		require.NoError(t, err)
+		require.Len(t, service.posts, 2, "own and already read messages are left out")
+		assert.Equal(t, "p1", service.posts[0].Id)
+		assert.Equal(t, "p3", service.posts[1].Id)
+		env.api.AssertExpectations(t)
+		env.api.AssertNotCalled(t, "GetPostsForChannel", "channel2", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should_tell_user_who_is_caught_up", func(t *testing.T) {
+		caughtUp := []*model.ChannelMember{{ChannelId: "channel1", UserId: "user1", MsgCount: 8}}
+		env, h, _ := setup(caughtUp)
+		env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dm1" && strings.Contains(post.Message, "all caught up")
+		})).Return(&model.Post{}, nil).Once()
+
+		_, err := h.processPersonalDigest(context.Background(), &jobs.Job{Type: personalDigestJobType, UserID: "user1", Payload: map[string]string{}})
+
+		require.NoError(t, err)
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("should_not_send_empty_scheduled_digest", func(t *testing.T) {
+		caughtUp := []*model.ChannelMember{{ChannelId: "channel1", UserId: "user1", MsgCount: 8}}
+		env, h, _ := setup(caughtUp)
+
+		_, err := h.processPersonalDigest(context.Background(), &jobs.Job{
+			Type:    personalDigestJobType,
+			UserID:  "user1",
+			Payload: map[string]string{payloadScheduled: "true"},
+		})
+
+		require.NoError(t, err)
+		env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+}
@@ -0,0 +1,114 @@
+package summary
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+const (
+	// postsPageSize is the number of posts requested per page when paging
+	// through channel history.
+	postsPageSize = 60
+
+	// maxScanPages bounds how far back the channel history is scanned when
+	// filtering posts, e.g. by author.
+	maxScanPages = 20
+)
+
+// postFilter selects posts from the channel history.
+type postFilter struct {
+	// since excludes posts created at or before this time (unix millis).
+	since int64
+	// limit is the maximum number of posts returned.
+	limit int
+	// userID, when set, keeps only posts of this user.
+	userID string
+	// excludeUserIDs drops posts of these users, e.g. earlier digests posted
+	// by the bot.
+	excludeUserIDs []string
+	// bots selects posts by whether integrations made them.
+	bots botFilter
+}
+
+// botFilter selects posts of integrations such as bots, webhooks and plugins,
+// so that alerts can be summarized apart from the discussion.
+type botFilter string
+
+const (
+	botsIncluded botFilter = ""
+	botsExcluded botFilter = "exclude"
+	botsOnly     botFilter = "only"
+)
+
+// keep reports whether the filter lets the post through.
+func (f botFilter) keep(post *model.Post) bool {
+	switch f {
+	case botsExcluded:
+		return !domain.IsBotPost(post)
+	case botsOnly:
+		return domain.IsBotPost(post)
+	default:
+		return true
+	}
+}
+
+// getUnreadPosts returns posts created in the channel after the user last
+// viewed it before requesting the summary, newest first, paging back through
+// the channel history as needed. Jobs enqueued without the time of the last
+// visit fall back to the current membership.
+func (h Handler) getUnreadPosts(job *jobs.Job) (*model.PostList, error) {
+	channelID := job.Payload[payloadChannelID]
+	since, err := strconv.ParseInt(job.Payload[payloadSince], 10, 64)
+	if err != nil {
+		member, err := h.client.Channel.GetMember(channelID, job.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get channel membership: %w", err)
+		}
+		since = member.LastViewedAt
+	}
+
+	return h.collectPosts(channelID, postFilter{since: since, limit: h.cfg.MaxMessages})
+}
+
+// collectPosts pages through the channel history from the newest post and
+// collects posts matching the filter, newest first.
+func (h Handler) collectPosts(channelID string, filter postFilter) (*model.PostList, error) {
+	result := model.NewPostList()
+
+	for page := 0; page < maxScanPages; page++ {
+		postList, err := h.client.Post.GetPostsForChannel(channelID, page, postsPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range postList.Order {
+			post := postList.Posts[id]
+			if post.CreateAt <= filter.since {
+				return result, nil
+			}
+			if filter.userID != "" && post.UserId != filter.userID {
+				continue
+			}
+			if slices.Contains(filter.excludeUserIDs, post.UserId) || !filter.bots.keep(post) {
+				continue
+			}
+			result.AddPost(post)
+			result.AddOrder(id)
+			if len(result.Order) >= filter.limit {
+				return result, nil
+			}
+		}
+
+		if len(postList.Order) < postsPageSize {
+			break
+		}
+	}
+
+	return result, nil
+}
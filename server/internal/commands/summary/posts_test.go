@@ -0,0 +1,144 @@
+package summary
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+// channelPage builds a page of posts ordered newest first, with CreateAt values
+// counting down from newest.
+func channelPage(newest int64, size int) *model.PostList {
+	list := model.NewPostList()
+	for i := 0; i < size; i++ {
+		post := &model.Post{Id: fmt.Sprintf("post%d", newest-int64(i)), CreateAt: newest - int64(i)}
+		list.AddPost(post)
+		list.AddOrder(post.Id)
+	}
+	return list
+}
+
+func unreadJob(since string) *jobs.Job {
+	return &jobs.Job{
+		UserID:  "user1",
+		Payload: map[string]string{payloadMode: modeUnread, payloadChannelID: "channel1", payloadSince: since},
+	}
+}
+
+func TestHandler_collectPosts(t *testing.T) {
+	t.Run("should_page_until_last_viewed_post", func(t *testing.T) {
+		env := setupTest()
+		h := Handler{client: env.client, cfg: Config{MaxMessages: 200}}
+
+		env.api.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{LastViewedAt: 1000 - postsPageSize - 10}, nil)
+		env.api.On("GetPostsForChannel", "channel1", 0, postsPageSize).Return(channelPage(1000, postsPageSize), nil)
+		env.api.On("GetPostsForChannel", "channel1", 1, postsPageSize).Return(channelPage(1000-postsPageSize, postsPageSize), nil)
+
+		postList, err := h.getUnreadPosts(unreadJob(""))
+
+		require.NoError(t, err)
+		assert.Len(t, postList.Order, postsPageSize+10)
+		assert.Equal(t, "post1000", postList.Order[0])
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("should_stop_at_end_of_history", func(t *testing.T) {
+		env := setupTest()
+		h := Handler{client: env.client, cfg: Config{MaxMessages: 200}}
+
+		env.api.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{LastViewedAt: 0}, nil)
+		env.api.On("GetPostsForChannel", "channel1", 0, postsPageSize).Return(channelPage(5, 5), nil)
+
+		postList, err := h.getUnreadPosts(unreadJob(""))
+
+		require.NoError(t, err)
+		assert.Len(t, postList.Order, 5)
+	})
+
+	t.Run("should_use_last_visit_before_the_request", func(t *testing.T) {
+		env := setupTest()
+		env.api.On("SendEphemeralPost", "user1", mock.AnythingOfType("*model.Post")).Return(&model.Post{})
+		// Running the command from the channel marks it as viewed before the
+		// job runs.
+		env.api.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{LastViewedAt: 990}, nil).Once()
+		env.api.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{LastViewedAt: 1000}, nil)
+		env.api.On("GetPostsForChannel", "channel1", 0, postsPageSize).Return(channelPage(1000, 20), nil)
+		queue := &memQueue{}
+		h := Handler{client: env.client, access: fakeAccess{readable: map[string]string{"user1": "channel1"}}, queue: queue, cfg: Config{MaxMessages: 200}}
+
+		_, err := h.Handle(&model.CommandArgs{Command: "/summary unread", UserId: "user1", ChannelId: "channel1"})
+		require.NoError(t, err)
+		require.Len(t, queue.jobs, 1)
+		postList, err := h.getUnreadPosts(queue.jobs[0])
+
+		require.NoError(t, err)
+		assert.Len(t, postList.Order, 10)
+		assert.Equal(t, "post1000", postList.Order[0])
+	})
+
+	t.Run("should_respect_limit", func(t *testing.T) {
+		env := setupTest()
+		h := Handler{client: env.client, cfg: Config{MaxMessages: 200}}
+
+		for page := 0; page*postsPageSize < 200; page++ {
+			env.api.On("GetPostsForChannel", "channel1", page, postsPageSize).
+				Return(channelPage(int64(10000-page*postsPageSize), postsPageSize), nil)
+		}
+
+		postList, err := h.collectPosts("channel1", postFilter{limit: 200})
+
+		require.NoError(t, err)
+		assert.Len(t, postList.Order, 200)
+	})
+
+	t.Run("should_filter_by_user_and_stop_at_since", func(t *testing.T) {
+		env := setupTest()
+		h := Handler{client: env.client}
+
+		page := channelPage(100, postsPageSize)
+		page.Posts["post100"].UserId = "user1"
+		page.Posts["post95"].UserId = "user1"
+		page.Posts["post50"].UserId = "user1"
+		env.api.On("GetPostsForChannel", "channel1", 0, postsPageSize).Return(page, nil)
+
+		postList, err := h.collectPosts("channel1", postFilter{since: 60, limit: 10, userID: "user1"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"post100", "post95"}, postList.Order)
+	})
+
+	t.Run("should_filter_bot_posts", func(t *testing.T) {
+		page := channelPage(10, 4)
+		page.Posts["post10"].AddProp(model.PostPropsFromBot, "true")
+		page.Posts["post9"].AddProp(model.PostPropsFromWebhook, "true")
+
+		tests := []struct {
+			name     string
+			bots     botFilter
+			expected []string
+		}{
+			{name: "should_keep_all_posts_by_default", bots: botsIncluded, expected: []string{"post10", "post9", "post8", "post7"}},
+			{name: "should_exclude_bots", bots: botsExcluded, expected: []string{"post8", "post7"}},
+			{name: "should_keep_only_bots", bots: botsOnly, expected: []string{"post10", "post9"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				env := setupTest()
+				h := Handler{client: env.client}
+				env.api.On("GetPostsForChannel", "channel1", 0, postsPageSize).Return(page, nil)
+
+				postList, err := h.collectPosts("channel1", postFilter{limit: 10, bots: tt.bots})
+
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, postList.Order)
+			})
+		}
+	})
+}
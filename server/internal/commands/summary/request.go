@@ -0,0 +1,69 @@
+package summary
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+// SummaryRequest is a summary requested outside of slash commands, e.g.
+// through the REST API. The summary is not posted; it is stored as the
+// result of the job.
+type SummaryRequest struct {
+	UserID    string
+	ChannelID string
+	// RootID selects the thread to summarize. If it is empty, the channel is
+	// summarized.
+	RootID string
+	// Since excludes channel posts created at or before this time (unix millis).
+	Since int64
+	// Limit is the maximum number of channel posts, capped at MaxMessages.
+	// Zero means MaxMessages.
+	Limit    int
+	Language string
+}
+
+// RequestSummary enqueues a summary job for the request. The caller is
+// responsible for checking that the user can read the channel.
+func (h Handler) RequestSummary(request SummaryRequest) (*jobs.Job, error) {
+	payload := map[string]string{
+		payloadMode:      modeThread,
+		payloadChannelID: request.ChannelID,
+		payloadRootID:    request.RootID,
+		payloadLanguage:  request.Language,
+	}
+	if request.RootID == "" {
+		limit := h.cfg.MaxMessages
+		if request.Limit > 0 {
+			limit = min(request.Limit, h.cfg.MaxMessages)
+		}
+		payload[payloadMode] = modeChannel
+		payload[payloadSince] = strconv.FormatInt(request.Since, 10)
+		payload[payloadLimit] = strconv.Itoa(limit)
+	}
+
+	job := &jobs.Job{
+		Type:    summaryJobType,
+		UserID:  request.UserID,
+		Payload: payload,
+	}
+	if err := h.queue.Enqueue(job); err != nil {
+		return nil, fmt.Errorf("failed to enqueue summary job: %w", err)
+	}
+	return job, nil
+}
+
+// JobChannelID returns the channel a summary job reads posts from. It reports
+// false for jobs that are not summary jobs and for searches, which read posts
+// from many channels.
+func JobChannelID(job *jobs.Job) (string, bool) {
+	if job.Type != summaryJobType {
+		return "", false
+	}
+	if isSearchMode(job.Payload[payloadMode]) {
+		return "", false
+	}
+	channelID, ok := job.Payload[payloadChannelID]
+	return channelID, ok && channelID != ""
+}
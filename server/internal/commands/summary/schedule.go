@@ -0,0 +1,393 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/EgorTarasov/summary/server/internal/domain/digest"
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+const (
+	modeSchedule = "schedule"
+
+	scheduleShow        = "show"
+	scheduleOff         = "off"
+	scheduleSubscribe   = "subscribe"
+	scheduleUnsubscribe = "unsubscribe"
+
+	flagDM       = "--dm"
+	flagTimezone = "--tz"
+
+	digestJobType = "digest"
+
+	scheduleUsage = "Usage: /summary schedule [show|daily 09:00|weekly mon 09:00|off|subscribe|unsubscribe] [--dm] [--tz Europe/Berlin] [--lang auto|en|ru|es|fr|de]"
+
+	// scheduleTimeLayout formats the next run of a digest for users.
+	scheduleTimeLayout = "Mon, 02 Jan 2006 15:04 MST"
+)
+
+// errDigestNotDue stops the update of a schedule that is not due yet.
+var errDigestNotDue = errors.New("digest is not due")
+
+func newScheduleAutocompleteData() *model.AutocompleteData {
+	data := model.NewAutocompleteData(modeSchedule, "[show|daily|weekly|off|subscribe|unsubscribe]", "Schedule a daily or weekly digest of this channel")
+	data.AddCommand(model.NewAutocompleteData(scheduleShow, "", "Show the digest schedule of this channel"))
+
+	daily := model.NewAutocompleteData(digest.FrequencyDaily, "09:00 [--dm] [--tz Europe/Berlin] [--lang en]", "Post a digest every day")
+	daily.AddTextArgument("Time of day", "09:00", "[0-9]{1,2}:[0-9]{2}")
+	addScheduleArguments(daily)
+	data.AddCommand(daily)
+
+	weekly := model.NewAutocompleteData(digest.FrequencyWeekly, "mon 09:00 [--dm] [--tz Europe/Berlin] [--lang en]", "Post a digest every week")
+	weekly.AddStaticListArgument("Day of the week", true, weekdayItems())
+	weekly.AddTextArgument("Time of day", "09:00", "[0-9]{1,2}:[0-9]{2}")
+	addScheduleArguments(weekly)
+	data.AddCommand(weekly)
+
+	data.AddCommand(model.NewAutocompleteData(scheduleOff, "", "Stop the digest of this channel"))
+	data.AddCommand(model.NewAutocompleteData(scheduleSubscribe, "", "Receive the digest of this channel as a direct message"))
+	data.AddCommand(model.NewAutocompleteData(scheduleUnsubscribe, "", "Stop receiving the digest of this channel"))
+	return data
+}
+
+// weekdayItems lists the days of the week starting on Monday.
+func weekdayItems() []model.AutocompleteListItem {
+	items := make([]model.AutocompleteListItem, 0, 7)
+	for i := 1; i <= 7; i++ {
+		day := time.Weekday(i % 7)
+		items = append(items, model.AutocompleteListItem{Item: strings.ToLower(day.String()[:3]), HelpText: day.String()})
+	}
+	return items
+}
+
+func addScheduleArguments(data *model.AutocompleteData) {
+	data.AddNamedTextArgument("tz", "Time zone of the schedule, your time zone by default", "Europe/Berlin", "", false)
+	addLanguageArgument(data)
+}
+
+// handleSchedule shows and changes the digest schedule of the channel.
+// Changing the schedule requires the permission to manage the channel;
+// subscribing to DM digests only requires access to the channel.
+func (h Handler) handleSchedule(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	action := scheduleShow
+	if len(fields) > 0 {
+		action = fields[0]
+	}
+
+	switch action {
+	case scheduleShow:
+		return ephemeral(h.describeSchedule(args))
+	case digest.FrequencyDaily, digest.FrequencyWeekly:
+		if !h.canManage(args, false) {
+			return ephemeral("You do not have permission to schedule digests in this channel.")
+		}
+		return h.setSchedule(args, fields)
+	case scheduleOff:
+		if !h.canManage(args, false) {
+			return ephemeral("You do not have permission to schedule digests in this channel.")
+		}
+		if err := h.schedules.DeleteDigestSchedule(args.ChannelId); err != nil {
+			h.client.Log.Error("failed to delete digest schedule", "channel_id", args.ChannelId, "error", err.Error())
+			return ephemeral("Failed to turn off the digest.")
+		}
+		return ephemeral("The digest of this channel was turned off.")
+	case scheduleSubscribe, scheduleUnsubscribe:
+		return h.subscribe(args, action == scheduleSubscribe)
+	default:
+		return ephemeral(scheduleUsage)
+	}
+}
+
+func (h Handler) setSchedule(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	fields, language, err := extractLanguage(fields)
+	if err != nil {
+		return ephemeral(fmt.Sprintf("%v\n%s", err, scheduleUsage))
+	}
+
+	fields, timezone, err := extractTimezone(fields, h.userTimezone(args.UserId))
+	if err != nil {
+		return ephemeral(fmt.Sprintf("%v\n%s", err, scheduleUsage))
+	}
+	delivery := digest.DeliveryChannel
+	rest := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field == flagDM {
+			delivery = digest.DeliveryDM
+			continue
+		}
+		rest = append(rest, field)
+	}
+
+	schedule, err := digest.Parse(rest)
+	if err != nil {
+		return ephemeral(fmt.Sprintf("%v\n%s", err, scheduleUsage))
+	}
+	schedule.ChannelID = args.ChannelId
+	schedule.Timezone = timezone
+	schedule.Delivery = delivery
+	schedule.Language = language
+	schedule.CreatedBy = args.UserId
+	if delivery == digest.DeliveryDM {
+		if previous, err := h.schedules.GetDigestSchedule(args.ChannelId); err == nil && previous != nil {
+			schedule.Subscribers = previous.Subscribers
+		}
+		schedule.Subscribe(args.UserId)
+	}
+	next := schedule.Next(time.Now())
+	schedule.NextRunAt = next.UnixMilli()
+
+	if err := h.schedules.SaveDigestSchedule(&schedule); err != nil {
+		h.client.Log.Error("failed to save digest schedule", "channel_id", args.ChannelId, "error", err.Error())
+		return ephemeral("Failed to save the digest schedule.")
+	}
+
+	target := "posted to this channel"
+	if delivery == digest.DeliveryDM {
+		target = "sent as a direct message to subscribers. You are subscribed; others can use `/summary schedule subscribe`"
+	}
+	return ephemeral(fmt.Sprintf("A digest is scheduled %s and will be %s. The first digest is due %s.",
+		schedule.Describe(), target, next.Format(scheduleTimeLayout)))
+}
+
+// userTimezone returns the preferred time zone of the user, empty if it is
+// unknown.
+func (h Handler) userTimezone(userID string) string {
+	user, err := h.client.User.Get(userID)
+	if err != nil {
+		return ""
+	}
+	return user.GetPreferredTimezone()
+}
+
+func (h Handler) describeSchedule(args *model.CommandArgs) string {
+	schedule, err := h.schedules.GetDigestSchedule(args.ChannelId)
+	if err != nil {
+		h.client.Log.Error("failed to get digest schedule", "channel_id", args.ChannelId, "error", err.Error())
+		return "Failed to load the digest schedule."
+	}
+	if schedule == nil {
+		return "No digest is scheduled for this channel.\n" + scheduleUsage
+	}
+
+	description := fmt.Sprintf("A digest is scheduled %s and posted to this channel.", schedule.Describe())
+	if schedule.Delivery == digest.DeliveryDM {
+		subscribed := "You are not subscribed."
+		for _, id := range schedule.Subscribers {
+			if id == args.UserId {
+				subscribed = "You are subscribed."
+			}
+		}
+		description = fmt.Sprintf("A digest is scheduled %s and sent as a direct message to %d subscriber(s). %s",
+			schedule.Describe(), len(schedule.Subscribers), subscribed)
+	}
+	next := time.UnixMilli(schedule.NextRunAt).In(schedule.Location())
+	return fmt.Sprintf("%s The next digest is due %s.", description, next.Format(scheduleTimeLayout))
+}
+
+func (h Handler) subscribe(args *model.CommandArgs, subscribe bool) *model.CommandResponse {
+	errNotDM := errors.New("digest is not sent as direct messages")
+	changed := false
+	_, err := h.schedules.UpdateDigestSchedule(args.ChannelId, func(schedule *digest.Schedule) error {
+		if schedule.Delivery != digest.DeliveryDM {
+			return errNotDM
+		}
+		if subscribe {
+			changed = schedule.Subscribe(args.UserId)
+		} else {
+			changed = schedule.Unsubscribe(args.UserId)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNotDM):
+		return ephemeral("The digest of this channel is posted to the channel, there is nothing to subscribe to.")
+	case err != nil:
+		if schedule, getErr := h.schedules.GetDigestSchedule(args.ChannelId); getErr == nil && schedule == nil {
+			return ephemeral("No digest is scheduled for this channel.")
+		}
+		h.client.Log.Error("failed to update digest subscribers", "channel_id", args.ChannelId, "error", err.Error())
+		return ephemeral("Failed to update your subscription.")
+	case subscribe && changed:
+		return ephemeral("You will receive the digest of this channel as a direct message.")
+	case subscribe:
+		return ephemeral("You are already subscribed to the digest of this channel.")
+	case changed:
+		return ephemeral("You will no longer receive the digest of this channel.")
+	default:
+		return ephemeral("You are not subscribed to the digest of this channel.")
+	}
+}
+
+// EnqueueDueDigests enqueues a digest job for every channel and personal
+// schedule that is due at now. Each due run is claimed by atomically advancing
+// the schedule, so a digest is only enqueued once even if several nodes check
+// the schedules.
+func (h Handler) EnqueueDueDigests(now time.Time) {
+	h.enqueueDueChannelDigests(now)
+	h.enqueueDuePersonalDigests(now)
+}
+
+func (h Handler) enqueueDueChannelDigests(now time.Time) {
+	channelIDs, err := h.schedules.ListDigestChannels()
+	if err != nil {
+		h.client.Log.Error("failed to list digest schedules", "error", err.Error())
+		return
+	}
+
+	for _, channelID := range channelIDs {
+		var dueAt int64
+		schedule, err := h.schedules.UpdateDigestSchedule(channelID, claimRun(now, &dueAt))
+		if errors.Is(err, errDigestNotDue) {
+			continue
+		}
+		if err != nil {
+			h.client.Log.Error("failed to claim digest", "channel_id", channelID, "error", err.Error())
+			continue
+		}
+
+		since := dueAt - schedule.Period().Milliseconds()
+		err = h.queue.Enqueue(&jobs.Job{
+			Type:   digestJobType,
+			UserID: schedule.CreatedBy,
+			Payload: map[string]string{
+				payloadChannelID: channelID,
+				payloadSince:     strconv.FormatInt(since, 10),
+			},
+		})
+		if err != nil {
+			h.client.Log.Error("failed to enqueue digest", "channel_id", channelID, "error", err.Error())
+		}
+	}
+}
+
+// claimRun returns a schedule update that advances a due schedule to its next
+// run and stores when the claimed run was due in dueAt. Schedules that are not
+// due yet are left unchanged with errDigestNotDue.
+func claimRun(now time.Time, dueAt *int64) func(schedule *digest.Schedule) error {
+	return func(schedule *digest.Schedule) error {
+		if schedule.NextRunAt > now.UnixMilli() {
+			return errDigestNotDue
+		}
+		*dueAt = schedule.NextRunAt
+		schedule.NextRunAt = schedule.Next(now).UnixMilli()
+		return nil
+	}
+}
+
+// processDigest summarizes the channel activity since the previous digest and
+// delivers it as a bot post or as direct messages to the subscribers.
+func (h Handler) processDigest(ctx context.Context, job *jobs.Job) (string, error) {
+	channelID := job.Payload[payloadChannelID]
+	schedule, err := h.schedules.GetDigestSchedule(channelID)
+	if err != nil {
+		return "", err
+	}
+	if schedule == nil {
+		// The digest was turned off after it had been enqueued.
+		return "", nil
+	}
+
+	if err := h.access.AuthorizeChannel(job.UserID, channelID); err != nil {
+		return "", fmt.Errorf("digest owner cannot read the channel: %w", err)
+	}
+
+	// Digests delivered as direct messages are not generated when nobody
+	// would receive them, e.g. after the last subscriber unsubscribed.
+	var recipients []string
+	if schedule.Delivery == digest.DeliveryDM {
+		recipients = h.digestRecipients(channelID, schedule.Subscribers)
+		if len(recipients) == 0 {
+			return "", nil
+		}
+	}
+
+	since, _ := strconv.ParseInt(job.Payload[payloadSince], 10, 64)
+	postList, err := h.collectPosts(channelID, postFilter{
+		since:          since,
+		limit:          h.cfg.MaxMessages,
+		excludeUserIDs: []string{h.cfg.BotID},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get posts: %w", err)
+	}
+	if len(postList.Order) == 0 {
+		return "", nil
+	}
+
+	summary, err := h.service.GenerateSummaryStream(ctx, domain.OrderedPosts(postList), domain.Params{Language: schedule.Language, Timezone: schedule.Timezone}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate digest: %w", err)
+	}
+
+	channel, err := h.client.Channel.Get(channelID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get channel: %w", err)
+	}
+	title := "Daily digest"
+	if schedule.Frequency == digest.FrequencyWeekly {
+		title = "Weekly digest"
+	}
+	message := fmt.Sprintf("**%s of %s**\n%s", title, h.channelLink(channel), summary.Text)
+
+	if schedule.Delivery == digest.DeliveryDM {
+		h.sendDigestDMs(channelID, recipients, message)
+	} else if err := h.client.Post.CreatePost(&model.Post{UserId: h.cfg.BotID, ChannelId: channelID, Message: message}); err != nil {
+		return "", fmt.Errorf("failed to post digest: %w", err)
+	}
+
+	result, err := json.Marshal(summary)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode digest: %w", err)
+	}
+	return string(result), nil
+}
+
+// digestRecipients returns the subscribers that can still read the channel.
+func (h Handler) digestRecipients(channelID string, subscribers []string) []string {
+	var recipients []string
+	for _, userID := range subscribers {
+		if err := h.access.AuthorizeChannel(userID, channelID); err != nil {
+			if !errors.Is(err, domain.ErrForbidden) {
+				h.client.Log.Error("failed to check digest subscriber access", "channel_id", channelID, "user_id", userID, "error", err.Error())
+			}
+			continue
+		}
+		recipients = append(recipients, userID)
+	}
+	return recipients
+}
+
+// sendDigestDMs sends the digest to the recipients.
+func (h Handler) sendDigestDMs(channelID string, recipients []string, message string) {
+	for _, userID := range recipients {
+		if err := h.client.Post.DM(h.cfg.BotID, userID, &model.Post{Message: message}); err != nil {
+			h.client.Log.Error("failed to send digest", "channel_id", channelID, "user_id", userID, "error", err.Error())
+		}
+	}
+}
+
+// channelLink returns a markdown link to the channel, or its display name if
+// the channel has no team, e.g. a direct message.
+func (h Handler) channelLink(channel *model.Channel) string {
+	name := channel.DisplayName
+	if name == "" {
+		name = channel.Name
+	}
+	if channel.TeamId == "" {
+		return name
+	}
+	team, err := h.client.Team.Get(channel.TeamId)
+	if err != nil {
+		return name
+	}
+	return fmt.Sprintf("[~%s](%s/%s/channels/%s)", name, h.siteURL(), team.Name, channel.Name)
+}
@@ -0,0 +1,316 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgorTarasov/summary/server/internal/domain/digest"
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+type memScheduleStore map[string]*digest.Schedule
+
+func (m memScheduleStore) GetDigestSchedule(channelID string) (*digest.Schedule, error) {
+	return m[channelID], nil
+}
+
+func (m memScheduleStore) SaveDigestSchedule(schedule *digest.Schedule) error {
+	m[schedule.ChannelID] = schedule
+	return nil
+}
+
+func (m memScheduleStore) UpdateDigestSchedule(channelID string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error) {
+	schedule, ok := m[channelID]
+	if !ok {
+		return nil, errors.New("digest schedule does not exist")
+	}
+	updated := *schedule
+	if err := update(&updated); err != nil {
+		return nil, err
+	}
+	m[channelID] = &updated
+	return &updated, nil
+}
+
+func (m memScheduleStore) DeleteDigestSchedule(channelID string) error {
+	delete(m, channelID)
+	return nil
+}
+
+func (m memScheduleStore) ListDigestChannels() ([]string, error) {
+	channelIDs := make([]string, 0, len(m))
+	for channelID := range m {
+		channelIDs = append(channelIDs, channelID)
+	}
+	return channelIDs, nil
+}
+
+// memQueue collects enqueued jobs.
+type memQueue struct {
+	jobs []*jobs.Job
+}
+
+func (q *memQueue) Register(string, jobs.Processor) {}
+
+func (q *memQueue) Enqueue(job *jobs.Job) error {
+	job.ID = fmt.Sprintf("job%d", len(q.jobs)+1)
+	job.Status = jobs.StatusPending
+	q.jobs = append(q.jobs, job)
+	return nil
+}
+
+func (q *memQueue) Get(id string) (*jobs.Job, error) {
+	for _, job := range q.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestHandler_handleSchedule(t *testing.T) {
+	args := &model.CommandArgs{UserId: "user1", ChannelId: "channel1"}
+	setup := func(canManage bool) (*env, Handler, memScheduleStore) {
+		env := setupTest()
+		env.api.On("GetUser", "user1").Return(&model.User{Id: "user1", Timezone: model.StringMap{"useAutomaticTimezone": "false", "manualTimezone": "Europe/Berlin"}}, nil).Maybe()
+		env.api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", Type: model.ChannelTypeOpen}, nil).Maybe()
+		env.api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionManagePublicChannelProperties).Return(canManage).Maybe()
+		store := memScheduleStore{}
+		return env, Handler{client: env.client, schedules: store}, store
+	}
+
+	t.Run("should_schedule_weekly_digest_in_user_timezone", func(t *testing.T) {
+		_, h, store := setup(true)
+
+		resp := h.handleSchedule(args, []string{"weekly", "mon", "09:00"})
+
+		require.Contains(t, store, "channel1")
+		schedule := store["channel1"]
+		assert.Equal(t, digest.FrequencyWeekly, schedule.Frequency)
+		assert.Equal(t, time.Monday, schedule.Weekday)
+		assert.Equal(t, "Europe/Berlin", schedule.Timezone)
+		assert.Equal(t, digest.DeliveryChannel, schedule.Delivery)
+		assert.Equal(t, "user1", schedule.CreatedBy)
+		next := time.UnixMilli(schedule.NextRunAt).In(schedule.Location())
+		assert.Equal(t, time.Monday, next.Weekday())
+		assert.Equal(t, 9, next.Hour())
+		assert.Contains(t, resp.Text, "weekly on Monday at 09:00 (Europe/Berlin)")
+	})
+
+	t.Run("should_subscribe_creator_of_dm_digest", func(t *testing.T) {
+		_, h, store := setup(true)
+
+		h.handleSchedule(args, []string{"daily", "18:00", "--dm", "--tz", "Asia/Tokyo", "--lang", "en"})
+
+		schedule := store["channel1"]
+		require.NotNil(t, schedule)
+		assert.Equal(t, digest.DeliveryDM, schedule.Delivery)
+		assert.Equal(t, "Asia/Tokyo", schedule.Timezone)
+		assert.Equal(t, "en", schedule.Language)
+		assert.Equal(t, []string{"user1"}, schedule.Subscribers)
+	})
+
+	t.Run("should_reject_schedule_without_permission", func(t *testing.T) {
+		_, h, store := setup(false)
+
+		resp := h.handleSchedule(args, []string{"daily", "09:00"})
+
+		assert.Contains(t, resp.Text, "do not have permission")
+		assert.Empty(t, store)
+	})
+
+	t.Run("should_reject_invalid_schedule", func(t *testing.T) {
+		_, h, store := setup(true)
+
+		resp := h.handleSchedule(args, []string{"daily", "9am"})
+
+		assert.Contains(t, resp.Text, "invalid time of day")
+		assert.Empty(t, store)
+	})
+
+	t.Run("should_subscribe_and_unsubscribe_other_users", func(t *testing.T) {
+		_, h, store := setup(true)
+		store["channel1"] = &digest.Schedule{ChannelID: "channel1", Delivery: digest.DeliveryDM, Subscribers: []string{"user1"}}
+		other := &model.CommandArgs{UserId: "user2", ChannelId: "channel1"}
+
+		resp := h.handleSchedule(other, []string{"subscribe"})
+		assert.Contains(t, resp.Text, "You will receive")
+		assert.Equal(t, []string{"user1", "user2"}, store["channel1"].Subscribers)
+
+		resp = h.handleSchedule(other, []string{"unsubscribe"})
+		assert.Contains(t, resp.Text, "no longer receive")
+		assert.Equal(t, []string{"user1"}, store["channel1"].Subscribers)
+	})
+
+	t.Run("should_not_subscribe_to_channel_digest", func(t *testing.T) {
+		_, h, store := setup(true)
+		store["channel1"] = &digest.Schedule{ChannelID: "channel1", Delivery: digest.DeliveryChannel}
+
+		resp := h.handleSchedule(args, []string{"subscribe"})
+
+		assert.Contains(t, resp.Text, "posted to the channel")
+	})
+
+	t.Run("should_turn_digest_off", func(t *testing.T) {
+		_, h, store := setup(true)
+		store["channel1"] = &digest.Schedule{ChannelID: "channel1"}
+
+		resp := h.handleSchedule(args, []string{"off"})
+
+		assert.Contains(t, resp.Text, "turned off")
+		assert.Empty(t, store)
+	})
+}
+
+func TestHandler_EnqueueDueDigests(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 0, 30, 0, time.UTC)
+	dueAt := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	env := setupTest()
+	env.api.On("LogError", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	queue := &memQueue{}
+	store := memScheduleStore{
+		"due":     {ChannelID: "due", Frequency: digest.FrequencyDaily, Hour: 9, CreatedBy: "user1", NextRunAt: dueAt.UnixMilli()},
+		"not_due": {ChannelID: "not_due", Frequency: digest.FrequencyDaily, Hour: 18, CreatedBy: "user1", NextRunAt: dueAt.Add(9 * time.Hour).UnixMilli()},
+	}
+	h := Handler{client: env.client, queue: queue, schedules: store, personal: memPersonalDigestStore{}}
+
+	h.EnqueueDueDigests(now)
+	h.EnqueueDueDigests(now)
+
+	require.Len(t, queue.jobs, 1, "a due digest is enqueued once")
+	job := queue.jobs[0]
+	assert.Equal(t, digestJobType, job.Type)
+	assert.Equal(t, "user1", job.UserID)
+	assert.Equal(t, "due", job.Payload[payloadChannelID])
+	assert.Equal(t, "1773046800000", job.Payload[payloadSince], "the digest covers the day before it was due")
+	assert.Equal(t, dueAt.Add(24*time.Hour).UnixMilli(), store["due"].NextRunAt)
+}
+
+// fakeSummarizer answers every request with the same summary.
+type fakeSummarizer struct {
+	text    string
+	skipped []domain.SkippedAttachment
+	posts   []*model.Post
+	groups  []domain.PostGroup
+}
+
+func (f *fakeSummarizer) GenerateSummary(_ context.Context, posts []*model.Post) (domain.Summary, error) {
+	f.posts = posts
+	return domain.Summary{Text: f.text}, nil
+}
+
+func (f *fakeSummarizer) GenerateSummaryStream(_ context.Context, posts []*model.Post, _ domain.Params, _ func(string) error) (domain.Summary, error) {
+	f.posts = posts
+	return domain.Summary{Text: f.text}, nil
+}
+
+func (f *fakeSummarizer) GenerateUnreadSummaryStream(_ context.Context, _, _ string, posts []*model.Post, _ domain.Params, _ func(string) error) (domain.Summary, error) {
+	f.posts = posts
+	return domain.Summary{Text: f.text}, nil
+}
+
+func (f *fakeSummarizer) ExtractActions(context.Context, []*model.Post, domain.Params) ([]domain.ExtractedAction, error) {
+	return nil, nil
+}
+
+func (f *fakeSummarizer) GenerateGroupedSummaryStream(_ context.Context, groups []domain.PostGroup, _ domain.Params, _ func(string) error) (domain.Summary, error) {
+	f.groups = groups
+	return domain.Summary{Text: f.text, SkippedAttachments: f.skipped}, nil
+}
+
+func (f *fakeSummarizer) GenerateBrief(_ context.Context, posts []*model.Post, _ domain.Params) (string, error) {
+	f.posts = posts
+	return f.text, nil
+}
+
+func TestHandler_processDigest(t *testing.T) {
+	siteURL := "https://chat.example.com"
+	postList := &model.PostList{
+		Order: []string{"p2", "p1"},
+		Posts: map[string]*model.Post{
+			"p1": {Id: "p1", ChannelId: "channel1", UserId: "user1", Message: "Ship on Friday", CreateAt: 2000},
+			"p2": {Id: "p2", ChannelId: "channel1", UserId: "bot1", Message: "Previous digest", CreateAt: 3000},
+		},
+	}
+	job := &jobs.Job{Type: digestJobType, UserID: "user1", Payload: map[string]string{payloadChannelID: "channel1", payloadSince: "1000"}}
+
+	setup := func(schedule *digest.Schedule) (*env, Handler, *fakeSummarizer) {
+		env := setupTest()
+		env.api.On("GetPostsForChannel", "channel1", 0, postsPageSize).Return(postList, nil)
+		env.api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", Name: "release", DisplayName: "Release"}, nil)
+		env.api.On("GetTeam", "team1").Return(&model.Team{Id: "team1", Name: "eng"}, nil)
+		env.api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		service := &fakeSummarizer{text: "The team agreed to ship on Friday."}
+		h := Handler{
+			client:    env.client,
+			service:   service,
+			access:    fakeAccess{readable: map[string]string{"user1": "channel1", "user2": "channel1"}},
+			schedules: memScheduleStore{"channel1": schedule},
+			cfg:       Config{BotID: "bot1", MaxMessages: 100},
+		}
+		return env, h, service
+	}
+
+	t.Run("should_post_digest_into_channel", func(t *testing.T) {
+		env, h, service := setup(&digest.Schedule{ChannelID: "channel1", Frequency: digest.FrequencyDaily, Delivery: digest.DeliveryChannel})
+		env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel1" && post.UserId == "bot1" &&
+				post.Message == "**Daily digest of [~Release](https://chat.example.com/eng/channels/release)**\nThe team agreed to ship on Friday."
+		})).Return(&model.Post{}, nil).Once()
+
+		_, err := h.processDigest(context.Background(), job)
+
+		require.NoError(t, err)
+		require.Len(t, service.posts, 1, "earlier digests of the bot are not summarized")
+		assert.Equal(t, "p1", service.posts[0].Id)
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("should_send_digest_to_subscribers_who_can_read_the_channel", func(t *testing.T) {
+		env, h, _ := setup(&digest.Schedule{ChannelID: "channel1", Frequency: digest.FrequencyWeekly, Delivery: digest.DeliveryDM, Subscribers: []string{"user2", "user3"}})
+		env.api.On("GetDirectChannel", "bot1", "user2").Return(&model.Channel{Id: "dm2"}, nil).Once()
+		env.api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "dm2" && strings.HasPrefix(post.Message, "**Weekly digest of")
+		})).Return(&model.Post{}, nil).Once()
+
+		_, err := h.processDigest(context.Background(), job)
+
+		require.NoError(t, err)
+		env.api.AssertExpectations(t)
+		env.api.AssertNotCalled(t, "GetDirectChannel", "bot1", "user3")
+	})
+
+	t.Run("should_not_generate_digest_without_subscribers", func(t *testing.T) {
+		for _, subscribers := range [][]string{nil, {"user3"}} {
+			env, h, service := setup(&digest.Schedule{ChannelID: "channel1", Frequency: digest.FrequencyDaily, Delivery: digest.DeliveryDM, Subscribers: subscribers})
+
+			result, err := h.processDigest(context.Background(), job)
+
+			require.NoError(t, err)
+			assert.Empty(t, result)
+			assert.Empty(t, service.posts, "subscribers: %v", subscribers)
+			env.api.AssertNotCalled(t, "CreatePost", mock.Anything)
+		}
+	})
+
+	t.Run("should_skip_removed_schedule", func(t *testing.T) {
+		env := setupTest()
+		h := Handler{client: env.client, schedules: memScheduleStore{}}
+
+		result, err := h.processDigest(context.Background(), job)
+
+		require.NoError(t, err)
+		assert.Empty(t, result)
+	})
+}
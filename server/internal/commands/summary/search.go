@@ -0,0 +1,213 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+const (
+	modeSearch = "search"
+	modeTag    = "tag"
+
+	payloadQuery = "query"
+
+	searchUsage = "Usage: /summary search <terms> [--lang auto|en|ru|es|fr|de]"
+	tagUsage    = "Usage: /summary tag #hashtag [--lang auto|en|ru|es|fr|de]"
+
+	// excerptRunes is the length of thread excerpts in group titles and
+	// source links.
+	excerptRunes = 60
+)
+
+func newSearchAutocompleteData() *model.AutocompleteData {
+	data := model.NewAutocompleteData(modeSearch, "<terms> [--lang en]", "Summarize messages matching a search across your channels")
+	data.AddTextArgument("Search terms, e.g. release \"code freeze\" from:alice", "<terms>", "")
+	addLanguageArgument(data)
+	return data
+}
+
+func newTagAutocompleteData() *model.AutocompleteData {
+	data := model.NewAutocompleteData(modeTag, "#hashtag [--lang en]", "Summarize messages with a hashtag across your channels")
+	data.AddTextArgument("Hashtag", "#incident", "")
+	addLanguageArgument(data)
+	return data
+}
+
+// parseSearchQuery returns the search terms of `/summary search` or the
+// hashtag of `/summary tag`, which may be given without the leading #.
+func parseSearchQuery(mode string, fields []string) (string, error) {
+	if mode == modeSearch {
+		if len(fields) == 0 {
+			return "", errors.New("missing search terms")
+		}
+		return strings.Join(fields, " "), nil
+	}
+
+	if len(fields) != 1 {
+		return "", errors.New("specify a single hashtag")
+	}
+	tag := "#" + strings.TrimPrefix(fields[0], "#")
+	if utf8.RuneCountInString(tag) < 3 {
+		return "", fmt.Errorf("invalid hashtag: %q", fields[0])
+	}
+	return tag, nil
+}
+
+// processSearch summarizes the posts matching the query of the job across the
+// channels the user can read, grouped by channel and thread, and lists links
+// to the matching posts below the summary.
+func (h Handler) processSearch(ctx context.Context, job *jobs.Job, stream *streamingPost, params domain.Params) (string, error) {
+	posts, err := h.searchPosts(job.UserID, job.Payload[payloadMode], job.Payload[payloadQuery])
+	if err != nil {
+		stream.Fail(fmt.Sprintf("Failed to search posts: %v", err))
+		return "", err
+	}
+	if len(posts) == 0 {
+		stream.Finish("No messages found in your channels.")
+		return "", nil
+	}
+
+	groups, sources := h.groupSearchResults(job.UserID, posts)
+	summary, err := h.service.GenerateGroupedSummaryStream(ctx, groups, params, stream.Write)
+	if err != nil {
+		stream.Fail(describeGenerationError(err))
+		return "", fmt.Errorf("failed to generate summary: %w", err)
+	}
+
+	summary.Text += "\n\n**Sources:**\n" + sources
+	h.deliverSummary(job, stream, posts, summary.Text+describeSkippedAttachments(summary.SkippedAttachments))
+
+	result, err := json.Marshal(summary)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode summary: %w", err)
+	}
+	return string(result), nil
+}
+
+// searchPosts searches every team of the user and returns the matching posts
+// of channels the user can read, at most MaxMessages of the most recent ones,
+// oldest first. Earlier summaries of the bot are left out.
+func (h Handler) searchPosts(userID, mode, query string) ([]*model.Post, error) {
+	params := []*model.SearchParams{{Terms: query, IsHashtag: true}}
+	if mode == modeSearch {
+		params = model.ParseSearchParams(query, 0)
+	}
+
+	teams, err := h.client.Team.List(pluginapi.FilterTeamsByUser(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+
+	readable := make(map[string]bool)
+	seen := make(map[string]struct{})
+	var posts []*model.Post
+	for _, team := range teams {
+		found, err := h.client.Post.SearchPostsInTeam(team.Id, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range found {
+			if _, ok := seen[post.Id]; ok || post.UserId == h.cfg.BotID {
+				continue
+			}
+			seen[post.Id] = struct{}{}
+
+			ok, checked := readable[post.ChannelId]
+			if !checked {
+				err := h.access.AuthorizeChannel(userID, post.ChannelId)
+				if err != nil && !errors.Is(err, domain.ErrForbidden) && !errors.Is(err, pluginapi.ErrNotFound) {
+					return nil, fmt.Errorf("failed to check channel access: %w", err)
+				}
+				ok = err == nil
+				readable[post.ChannelId] = ok
+			}
+			if ok {
+				posts = append(posts, post)
+			}
+		}
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].CreateAt > posts[j].CreateAt
+	})
+	if len(posts) > h.cfg.MaxMessages {
+		posts = posts[:h.cfg.MaxMessages]
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].CreateAt < posts[j].CreateAt
+	})
+	return posts, nil
+}
+
+// groupSearchResults groups posts by channel and then by thread, both in
+// order of their first post. It returns the groups to summarize and a
+// markdown list linking every channel and thread to its first matching post.
+func (h Handler) groupSearchResults(userID string, posts []*model.Post) ([]domain.PostGroup, string) {
+	type thread struct {
+		posts []*model.Post
+	}
+	var channelIDs []string
+	threads := make(map[string][]*thread)
+	byID := make(map[string]*thread)
+	for _, post := range posts {
+		threadID := post.RootId
+		if threadID == "" {
+			threadID = post.Id
+		}
+		t, ok := byID[threadID]
+		if !ok {
+			if _, ok := threads[post.ChannelId]; !ok {
+				channelIDs = append(channelIDs, post.ChannelId)
+			}
+			t = &thread{}
+			byID[threadID] = t
+			threads[post.ChannelId] = append(threads[post.ChannelId], t)
+		}
+		t.posts = append(t.posts, post)
+	}
+
+	siteURL := h.siteURL()
+	var (
+		groups  []domain.PostGroup
+		sources strings.Builder
+	)
+	for _, channelID := range channelIDs {
+		name, label := channelID, channelID
+		if channel, err := h.client.Channel.Get(channelID); err == nil {
+			name, label = h.channelName(channel, userID), h.channelLabel(channel, userID)
+		}
+		fmt.Fprintf(&sources, "- %s\n", label)
+
+		for _, t := range threads[channelID] {
+			first := t.posts[0]
+			title := name
+			if first.RootId != "" || len(t.posts) > 1 {
+				title = fmt.Sprintf("%s, thread: %s", name, excerpt(first.Message))
+			}
+			groups = append(groups, domain.PostGroup{Title: title, Posts: t.posts})
+			fmt.Fprintf(&sources, "  - [%s](%s/_redirect/pl/%s) · %d message(s)\n", excerpt(first.Message), siteURL, first.Id, len(t.posts))
+		}
+	}
+	return groups, strings.TrimSuffix(sources.String(), "\n")
+}
+
+// excerpt shortens a message to a single line of at most excerptRunes runes
+// that can be used as the text of a markdown link.
+func excerpt(message string) string {
+	text := strings.NewReplacer("[", "(", "]", ")").Replace(strings.Join(strings.Fields(message), " "))
+	if utf8.RuneCountInString(text) <= excerptRunes {
+		return text
+	}
+	return string([]rune(text)[:excerptRunes-1]) + "…"
+}
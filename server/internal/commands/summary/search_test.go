@@ -0,0 +1,214 @@
+package summary
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	domain "github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		fields      []string
+		expected    string
+		expectError string
+	}{
+		{
+			name:     "should_join_search_terms",
+			mode:     modeSearch,
+			fields:   []string{"release", `"code`, `freeze"`},
+			expected: `release "code freeze"`,
+		},
+		{
+			name:        "should_require_search_terms",
+			mode:        modeSearch,
+			expectError: "missing search terms",
+		},
+		{
+			name:     "should_keep_hashtag",
+			mode:     modeTag,
+			fields:   []string{"#incident"},
+			expected: "#incident",
+		},
+		{
+			name:     "should_add_missing_hash",
+			mode:     modeTag,
+			fields:   []string{"incident"},
+			expected: "#incident",
+		},
+		{
+			name:        "should_reject_several_hashtags",
+			mode:        modeTag,
+			fields:      []string{"#incident", "#outage"},
+			expectError: "single hashtag",
+		},
+		{
+			name:        "should_reject_too_short_hashtag",
+			mode:        modeTag,
+			fields:      []string{"#a"},
+			expectError: "invalid hashtag",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := parseSearchQuery(tt.mode, tt.fields)
+
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	assert.Equal(t, "deploy (prod) failed", excerpt("deploy [prod]\n  failed"))
+
+	long := excerpt(strings.Repeat("a", 100))
+	assert.Equal(t, excerptRunes, len([]rune(long)))
+	assert.True(t, strings.HasSuffix(long, "…"))
+}
+
+func TestHandler_processSearch(t *testing.T) {
+	siteURL := "https://chat.example.com"
+	found := []*model.Post{
+		{Id: "p3", ChannelId: "channel1", UserId: "user2", RootId: "p1", Message: "#incident resolved", CreateAt: 3000},
+		{Id: "p4", ChannelId: "channel2", UserId: "user2", Message: "#incident in a secret channel", CreateAt: 4000},
+		{Id: "p2", ChannelId: "channel1", UserId: "bot1", Message: "Summary of #incident", CreateAt: 2500},
+		{Id: "p1", ChannelId: "channel1", UserId: "user2", Message: "#incident db is down", CreateAt: 2000},
+		{Id: "p0", ChannelId: "channel1", UserId: "user3", Message: "#incident unrelated", CreateAt: 1000},
+	}
+	job := &jobs.Job{
+		Type:   summaryJobType,
+		UserID: "user1",
+		Payload: map[string]string{
+			payloadMode:      modeTag,
+			payloadChannelID: "channel1",
+			payloadPostID:    "post1",
+			payloadQuery:     "#incident",
+		},
+	}
+
+	setup := func(maxMessages int) (*env, Handler, *fakeSummarizer) {
+		env := setupTest()
+		env.api.On("GetTeamsForUser", "user1").Return([]*model.Team{{Id: "team1"}}, nil)
+		env.api.On("SearchPostsInTeam", "team1", []*model.SearchParams{{Terms: "#incident", IsHashtag: true}}).Return(found, nil)
+		env.api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", Name: "ops", DisplayName: "Ops", Type: model.ChannelTypeOpen}, nil)
+		env.api.On("GetTeam", "team1").Return(&model.Team{Id: "team1", Name: "eng"}, nil)
+		env.api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		env.api.On("GetDirectChannel", "bot1", "user1").Return(&model.Channel{Id: "dm1"}, nil).Maybe()
+		env.api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(&model.Post{Id: "summary1"}, nil).Maybe()
+		service := &fakeSummarizer{text: "The database outage was resolved."}
+		h := Handler{
+			client:  env.client,
+			service: service,
+			access:  fakeAccess{readable: map[string]string{"user1": "channel1"}},
+			cfg:     Config{BotID: "bot1", MaxMessages: maxMessages},
+		}
+		return env, h, service
+	}
+
+	t.Run("should_summarize_readable_posts_grouped_by_thread", func(t *testing.T) {
+		env, h, service := setup(100)
+		env.api.On("UpdateEphemeralPost", "user1", mock.MatchedBy(func(post *model.Post) bool {
+			return strings.Contains(post.Message, "The database outage was resolved.\n\n**Sources:**\n"+
+				"- [~Ops](https://chat.example.com/eng/channels/ops)\n"+
+				"  - [#incident unrelated](https://chat.example.com/_redirect/pl/p0) · 1 message(s)\n"+
+				"  - [#incident db is down](https://chat.example.com/_redirect/pl/p1) · 2 message(s)")
+		})).Return(&model.Post{}).Once()
+
+		stream := resumeStreamingPost(env.client, "bot1", "user1", "channel1", "", "post1", "Search Summary (#incident):")
+		_, err := h.processSearch(context.Background(), job, stream, domain.Params{})
+
+		require.NoError(t, err)
+		require.Len(t, service.groups, 2)
+		assert.Equal(t, "Ops", service.groups[0].Title)
+		assert.Equal(t, "Ops, thread: #incident db is down", service.groups[1].Title)
+		require.Len(t, service.groups[1].Posts, 2)
+		assert.Equal(t, "p1", service.groups[1].Posts[0].Id)
+		assert.Equal(t, "p3", service.groups[1].Posts[1].Id)
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("should_list_skipped_attachments", func(t *testing.T) {
+		env, h, service := setup(100)
+		service.skipped = []domain.SkippedAttachment{{PostID: "p1", Name: "screen.png", Reason: "unsupported file type"}}
+		env.api.On("UpdateEphemeralPost", "user1", mock.MatchedBy(func(post *model.Post) bool {
+			return strings.Contains(post.Message, "\n\n_Attachments not included in the summary: screen.png (unsupported file type)_")
+		})).Return(&model.Post{}).Once()
+
+		stream := resumeStreamingPost(env.client, "bot1", "user1", "channel1", "", "post1", "Search Summary (#incident):")
+		_, err := h.processSearch(context.Background(), job, stream, domain.Params{})
+
+		require.NoError(t, err)
+		env.api.AssertExpectations(t)
+	})
+
+	t.Run("should_save_summary_post_without_sharing", func(t *testing.T) {
+		env := setupTest()
+		env.api.On("GetTeamsForUser", "user1").Return([]*model.Team{{Id: "team1"}}, nil)
+		env.api.On("SearchPostsInTeam", "team1", mock.Anything).Return(found, nil)
+		env.api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", Name: "ops", DisplayName: "Ops", Type: model.ChannelTypeOpen}, nil)
+		env.api.On("GetTeam", "team1").Return(&model.Team{Id: "team1", Name: "eng"}, nil)
+		env.api.On("GetConfig").Return(&model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &siteURL}})
+		env.api.On("GetDirectChannel", "bot1", "user1").Return(&model.Channel{Id: "dm1"}, nil)
+		var saved *model.Post
+		env.api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(a mock.Arguments) {
+			saved = a.Get(0).(*model.Post).Clone()
+		}).Return(&model.Post{Id: "summary1"}, nil)
+		var finished string
+		env.api.On("UpdateEphemeralPost", "user1", mock.Anything).Run(func(a mock.Arguments) {
+			finished = a.Get(1).(*model.Post).Message
+		}).Return(&model.Post{})
+		h := Handler{
+			client:  env.client,
+			service: &fakeSummarizer{text: "The database outage was resolved."},
+			access:  fakeAccess{readable: map[string]string{"user1": "channel1"}},
+			cfg:     Config{BotID: "bot1", MaxMessages: 100},
+		}
+
+		stream := resumeStreamingPost(env.client, "bot1", "user1", "channel1", "", "post1", "Search Summary (#incident):")
+		_, err := h.processSearch(context.Background(), job, stream, domain.Params{})
+
+		require.NoError(t, err)
+		require.NotNil(t, saved)
+		assert.Equal(t, "#incident", toAnyMap(saved.GetProp(propSummaryRequest))[payloadQuery], "search summaries can be regenerated")
+		attachment := saved.Attachments()[0]
+		assert.Equal(t, "Summary of messages tagged #incident", attachment.Text)
+		for _, action := range attachment.Actions {
+			assert.NotEqual(t, actionShare, action.Id, "search results span channels")
+		}
+		assert.True(t, strings.HasSuffix(finished, "where you can regenerate, shorten or translate it._"))
+	})
+
+	t.Run("should_keep_most_recent_posts", func(t *testing.T) {
+		env, h, service := setup(2)
+		env.api.On("UpdateEphemeralPost", "user1", mock.Anything).Return(&model.Post{})
+
+		stream := resumeStreamingPost(env.client, "bot1", "user1", "channel1", "", "post1", "Search Summary (#incident):")
+		_, err := h.processSearch(context.Background(), job, stream, domain.Params{})
+
+		require.NoError(t, err)
+		require.Len(t, service.groups, 1)
+		assert.Len(t, service.groups[0].Posts, 2)
+	})
+}
+
+func TestJobChannelID_search(t *testing.T) {
+	_, ok := JobChannelID(&jobs.Job{Type: summaryJobType, Payload: map[string]string{payloadMode: modeSearch, payloadChannelID: "channel1"}})
+
+	assert.False(t, ok, "search results span channels and are not exposed by channel access")
+}
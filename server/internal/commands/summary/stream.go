@@ -0,0 +1,140 @@
+package summary
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+const (
+	// streamUpdateInterval throttles post edits so that fast models don't flood
+	// the server and clients with websocket events.
+	streamUpdateInterval = 500 * time.Millisecond
+
+	pendingMessage = "_Generating summary..._"
+	cursorMarker   = " ▌"
+
+	// propStructuredSummary holds the structured form of a summary on its post.
+	propStructuredSummary = "structured_summary"
+)
+
+// streamingPost renders a summary as an ephemeral bot post and updates it in
+// place while the summary is being generated. Summaries regenerated from a
+// summary post are streamed into that post, which is persistent.
+type streamingPost struct {
+	client     *pluginapi.Client
+	userID     string
+	title      string
+	post       *model.Post
+	persistent bool
+	text       strings.Builder
+	lastEdit   time.Time
+}
+
+func newStreamingPost(client *pluginapi.Client, botID string, args *model.CommandArgs, title string) *streamingPost {
+	s := &streamingPost{
+		client: client,
+		userID: args.UserId,
+		title:  title,
+		post: &model.Post{
+			UserId:    botID,
+			ChannelId: args.ChannelId,
+			RootId:    args.RootId,
+		},
+	}
+	s.post.Message = s.render(pendingMessage)
+	client.Post.SendEphemeralPost(s.userID, s.post)
+	s.lastEdit = time.Now()
+
+	return s
+}
+
+// resumeStreamingPost continues updating an ephemeral post that was sent earlier,
+// possibly by another plugin instance.
+func resumeStreamingPost(client *pluginapi.Client, botID, userID, channelID, rootID, postID, title string) *streamingPost {
+	return &streamingPost{
+		client: client,
+		userID: userID,
+		title:  title,
+		post: &model.Post{
+			Id:        postID,
+			UserId:    botID,
+			ChannelId: channelID,
+			RootId:    rootID,
+		},
+	}
+}
+
+// resumePersistentPost continues updating a persistent summary post, e.g. the
+// summary post a user asked to regenerate.
+func resumePersistentPost(client *pluginapi.Client, userID string, post *model.Post, title string) *streamingPost {
+	return &streamingPost{
+		client:     client,
+		userID:     userID,
+		title:      title,
+		post:       post,
+		persistent: true,
+	}
+}
+
+// Write appends a chunk of the summary and refreshes the post at most once per
+// streamUpdateInterval.
+func (s *streamingPost) Write(chunk string) error {
+	s.text.WriteString(chunk)
+	if time.Since(s.lastEdit) < streamUpdateInterval {
+		return nil
+	}
+
+	s.update(s.text.String() + cursorMarker)
+	return nil
+}
+
+// SetProp attaches a property to the post; it is sent with the next update.
+func (s *streamingPost) SetProp(key string, value any) {
+	s.post.AddProp(key, value)
+}
+
+// DelProp removes a property from the post with the next update.
+func (s *streamingPost) DelProp(key string) {
+	s.post.DelProp(key)
+}
+
+// SetAttachments replaces the message attachments of the post, such as the
+// summary actions; they are sent with the next update.
+func (s *streamingPost) SetAttachments(attachments []*model.SlackAttachment) {
+	model.ParseSlackAttachment(s.post, attachments)
+}
+
+// Finish replaces the post content with the complete summary.
+func (s *streamingPost) Finish(summary string) {
+	s.update(summary)
+}
+
+// Fail replaces the post content with an error message for the user.
+func (s *streamingPost) Fail(message string) {
+	s.update(message)
+}
+
+func (s *streamingPost) update(body string) {
+	if s.post.Id == "" {
+		// Summaries requested through the API are not delivered as a post;
+		// the result is only stored in the job.
+		return
+	}
+	s.post.Message = s.render(body)
+	s.lastEdit = time.Now()
+	if !s.persistent {
+		s.client.Post.UpdateEphemeralPost(s.userID, s.post)
+		return
+	}
+	if err := s.client.Post.UpdatePost(s.post); err != nil {
+		s.client.Log.Error("failed to update summary post", "post_id", s.post.Id, "error", err.Error())
+	}
+}
+
+func (s *streamingPost) render(body string) string {
+	return fmt.Sprintf("**%s**\n%s", s.title, body)
+}
@@ -0,0 +1,53 @@
+package summary
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStreamingPost(t *testing.T) {
+	env := setupTest()
+	args := &model.CommandArgs{UserId: "user1", ChannelId: "channel1", RootId: "root1"}
+
+	var messages []string
+	env.api.On("SendEphemeralPost", "user1", mock.AnythingOfType("*model.Post")).
+		Run(func(a mock.Arguments) {
+			messages = append(messages, a.Get(1).(*model.Post).Message)
+		}).
+		Return(func(_ string, post *model.Post) *model.Post {
+			sent := post.Clone()
+			sent.Id = "post1"
+			return sent
+		})
+	env.api.On("UpdateEphemeralPost", "user1", mock.AnythingOfType("*model.Post")).
+		Run(func(a mock.Arguments) {
+			post := a.Get(1).(*model.Post)
+			assert.Equal(t, "post1", post.Id)
+			messages = append(messages, post.Message)
+		}).
+		Return(func(_ string, post *model.Post) *model.Post { return post })
+
+	stream := newStreamingPost(env.client, "bot1", args, "Thread Summary:")
+
+	assert.Equal(t, "bot1", stream.post.UserId)
+	assert.Equal(t, "root1", stream.post.RootId)
+
+	// Chunks arriving faster than the update interval are buffered.
+	assert.NoError(t, stream.Write("Hello"))
+	assert.Len(t, messages, 1)
+
+	stream.lastEdit = time.Now().Add(-streamUpdateInterval)
+	assert.NoError(t, stream.Write(" world"))
+
+	stream.Finish("Hello world!")
+
+	assert.Equal(t, []string{
+		"**Thread Summary:**\n" + pendingMessage,
+		"**Thread Summary:**\nHello world" + cursorMarker,
+		"**Thread Summary:**\nHello world!",
+	}, messages)
+}
@@ -0,0 +1,142 @@
+package summary
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	modeTemplate = "template"
+
+	templateList  = "list"
+	templateSet   = "set"
+	templateReset = "reset"
+	teamFlag      = "--team"
+
+	templateUsage = "Usage: /summary template [list|set <name> [--team]|reset [--team]]"
+)
+
+func newTemplateAutocompleteData(names []string) *model.AutocompleteData {
+	data := model.NewAutocompleteData(modeTemplate, "[list|set|reset]", "Choose the prompt template used for summaries")
+	data.AddCommand(model.NewAutocompleteData(templateList, "", "Show available templates and the current selection"))
+
+	set := model.NewAutocompleteData(templateSet, "<name> [--team]", "Use a template in this channel, or in the whole team with --team")
+	items := make([]model.AutocompleteListItem, 0, len(names))
+	for _, name := range names {
+		items = append(items, model.AutocompleteListItem{Item: name})
+	}
+	set.AddStaticListArgument("Template name", true, items)
+	data.AddCommand(set)
+
+	data.AddCommand(model.NewAutocompleteData(templateReset, "[--team]", "Go back to the team or default template"))
+	return data
+}
+
+// handleTemplate lists prompt templates and selects one for the current
+// channel or team. Selecting requires the permission to manage the channel or
+// team respectively.
+func (h Handler) handleTemplate(args *model.CommandArgs, fields []string) *model.CommandResponse {
+	action := templateList
+	if len(fields) > 0 {
+		action = fields[0]
+	}
+
+	switch action {
+	case templateList:
+		return ephemeral(h.describeTemplates(args))
+	case templateSet:
+		if len(fields) < 2 || len(fields) > 3 || (len(fields) == 3 && fields[2] != teamFlag) {
+			return ephemeral(templateUsage)
+		}
+		name := fields[1]
+		if !slices.Contains(h.cfg.PromptTemplates, name) {
+			return ephemeral(fmt.Sprintf("Unknown template: %s. Available templates: %s", name, strings.Join(h.cfg.PromptTemplates, ", ")))
+		}
+		return h.selectTemplate(args, name, len(fields) == 3)
+	case templateReset:
+		if len(fields) > 2 || (len(fields) == 2 && fields[1] != teamFlag) {
+			return ephemeral(templateUsage)
+		}
+		return h.selectTemplate(args, "", len(fields) == 2)
+	default:
+		return ephemeral(templateUsage)
+	}
+}
+
+func (h Handler) selectTemplate(args *model.CommandArgs, name string, team bool) *model.CommandResponse {
+	scopeID, scope := args.ChannelId, "channel"
+	if team {
+		scopeID, scope = args.TeamId, "team"
+	}
+	if scopeID == "" {
+		return ephemeral(fmt.Sprintf("There is no %s to set the template for.", scope))
+	}
+	if !h.canManage(args, team) {
+		return ephemeral(fmt.Sprintf("You do not have permission to change the template of this %s.", scope))
+	}
+
+	if err := h.templates.SetPromptTemplate(scopeID, name); err != nil {
+		h.client.Log.Error("failed to save prompt template", "scope_id", scopeID, "error", err.Error())
+		return ephemeral("Failed to save the template selection.")
+	}
+
+	if name == "" {
+		return ephemeral(fmt.Sprintf("The %s template selection was reset.", scope))
+	}
+	return ephemeral(fmt.Sprintf("Summaries in this %s now use the `%s` template.", scope, name))
+}
+
+// canManage reports whether the user can change settings of the channel or,
+// if team is set, of the team.
+func (h Handler) canManage(args *model.CommandArgs, team bool) bool {
+	if team {
+		return h.client.User.HasPermissionToTeam(args.UserId, args.TeamId, model.PermissionManageTeam)
+	}
+
+	channel, err := h.client.Channel.Get(args.ChannelId)
+	if err != nil {
+		return false
+	}
+
+	permission := model.PermissionReadChannel
+	switch channel.Type {
+	case model.ChannelTypeOpen:
+		permission = model.PermissionManagePublicChannelProperties
+	case model.ChannelTypePrivate:
+		permission = model.PermissionManagePrivateChannelProperties
+	}
+	return h.client.User.HasPermissionToChannel(args.UserId, args.ChannelId, permission)
+}
+
+func (h Handler) describeTemplates(args *model.CommandArgs) string {
+	channelTemplate, _ := h.templates.GetPromptTemplate(args.ChannelId)
+	var teamTemplate string
+	if args.TeamId != "" {
+		teamTemplate, _ = h.templates.GetPromptTemplate(args.TeamId)
+	}
+
+	var b strings.Builder
+	b.WriteString("Available templates:\n")
+	for _, name := range h.cfg.PromptTemplates {
+		b.WriteString("- `" + name + "`")
+		if name == channelTemplate {
+			b.WriteString(" (channel)")
+		}
+		if name == teamTemplate {
+			b.WriteString(" (team)")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(templateUsage)
+	return b.String()
+}
+
+func ephemeral(text string) *model.CommandResponse {
+	return &model.CommandResponse{
+		ResponseType: model.CommandResponseTypeEphemeral,
+		Text:         text,
+	}
+}
@@ -0,0 +1,89 @@
+package summary
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memTemplateStore map[string]string
+
+func (m memTemplateStore) GetPromptTemplate(scopeID string) (string, error) {
+	return m[scopeID], nil
+}
+
+func (m memTemplateStore) SetPromptTemplate(scopeID, name string) error {
+	if name == "" {
+		delete(m, scopeID)
+		return nil
+	}
+	m[scopeID] = name
+	return nil
+}
+
+func TestHandler_handleTemplate(t *testing.T) {
+	args := &model.CommandArgs{UserId: "user1", ChannelId: "channel1", TeamId: "team1"}
+	cfg := Config{PromptTemplates: []string{"brief", "default"}}
+
+	t.Run("should_set_channel_template_for_channel_admin", func(t *testing.T) {
+		env := setupTest()
+		store := memTemplateStore{}
+		h := Handler{client: env.client, templates: store, cfg: cfg}
+		env.api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", Type: model.ChannelTypeOpen}, nil)
+		env.api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionManagePublicChannelProperties).Return(true)
+
+		resp := h.handleTemplate(args, []string{"set", "brief"})
+
+		assert.Contains(t, resp.Text, "now use the `brief` template")
+		assert.Equal(t, "brief", store["channel1"])
+	})
+
+	t.Run("should_reject_channel_template_without_permission", func(t *testing.T) {
+		env := setupTest()
+		store := memTemplateStore{}
+		h := Handler{client: env.client, templates: store, cfg: cfg}
+		env.api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", Type: model.ChannelTypePrivate}, nil)
+		env.api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionManagePrivateChannelProperties).Return(false)
+
+		resp := h.handleTemplate(args, []string{"set", "brief"})
+
+		assert.Contains(t, resp.Text, "do not have permission")
+		assert.Empty(t, store)
+	})
+
+	t.Run("should_set_and_reset_team_template", func(t *testing.T) {
+		env := setupTest()
+		store := memTemplateStore{}
+		h := Handler{client: env.client, templates: store, cfg: cfg}
+		env.api.On("HasPermissionToTeam", "user1", "team1", model.PermissionManageTeam).Return(true)
+
+		h.handleTemplate(args, []string{"set", "brief", "--team"})
+		require.Equal(t, "brief", store["team1"])
+
+		resp := h.handleTemplate(args, []string{"reset", "--team"})
+
+		assert.Contains(t, resp.Text, "reset")
+		assert.Empty(t, store)
+	})
+
+	t.Run("should_reject_unknown_template", func(t *testing.T) {
+		env := setupTest()
+		h := Handler{client: env.client, templates: memTemplateStore{}, cfg: cfg}
+
+		resp := h.handleTemplate(args, []string{"set", "verbose"})
+
+		assert.Contains(t, resp.Text, "Unknown template: verbose")
+	})
+
+	t.Run("should_list_templates_with_selection", func(t *testing.T) {
+		env := setupTest()
+		h := Handler{client: env.client, templates: memTemplateStore{"channel1": "brief", "team1": "default"}, cfg: cfg}
+
+		resp := h.handleTemplate(args, nil)
+
+		assert.Contains(t, resp.Text, "- `brief` (channel)")
+		assert.Contains(t, resp.Text, "- `default` (team)")
+	})
+}
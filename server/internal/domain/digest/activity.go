@@ -0,0 +1,40 @@
+package digest
+
+import "sort"
+
+// mentionWeight is how many unread messages a mention of the user is worth
+// when channels are ranked for the personal digest.
+const mentionWeight = 10
+
+// Activity is the unread activity of a user in one channel.
+type Activity struct {
+	ChannelID string
+	Unread    int64
+	Mentions  int64
+	// LastViewedAt is when the user last viewed the channel (unix millis).
+	LastViewedAt int64
+}
+
+// Score ranks the activity: mentions of the user weigh more than other
+// unread messages.
+func (a Activity) Score() int64 {
+	return a.Unread + mentionWeight*a.Mentions
+}
+
+// Rank returns the channels with unread messages ordered by score, at most
+// limit of them. Channels with the same score keep their order.
+func Rank(activities []Activity, limit int) []Activity {
+	ranked := make([]Activity, 0, len(activities))
+	for _, activity := range activities {
+		if activity.Unread > 0 {
+			ranked = append(ranked, activity)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score() > ranked[j].Score()
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
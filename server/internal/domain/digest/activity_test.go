@@ -0,0 +1,66 @@
+package digest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name       string
+		activities []Activity
+		limit      int
+		expected   []string
+	}{
+		{
+			name: "should_order_by_unread_messages",
+			activities: []Activity{
+				{ChannelID: "quiet", Unread: 2},
+				{ChannelID: "busy", Unread: 40},
+			},
+			limit:    10,
+			expected: []string{"busy", "quiet"},
+		},
+		{
+			name: "should_rank_mentions_above_unread_messages",
+			activities: []Activity{
+				{ChannelID: "busy", Unread: 15},
+				{ChannelID: "mentioned", Unread: 3, Mentions: 2},
+			},
+			limit:    10,
+			expected: []string{"mentioned", "busy"},
+		},
+		{
+			name: "should_skip_read_channels",
+			activities: []Activity{
+				{ChannelID: "read"},
+				{ChannelID: "unread", Unread: 1},
+			},
+			limit:    10,
+			expected: []string{"unread"},
+		},
+		{
+			name: "should_keep_order_of_equal_scores_and_limit",
+			activities: []Activity{
+				{ChannelID: "a", Unread: 5},
+				{ChannelID: "b", Unread: 5},
+				{ChannelID: "c", Unread: 5},
+			},
+			limit:    2,
+			expected: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := Rank(tt.activities, tt.limit)
+
+			channelIDs := make([]string, 0, len(ranked))
+			for _, activity := range ranked {
+				channelIDs = append(channelIDs, activity.ChannelID)
+			}
+			assert.Equal(t, tt.expected, channelIDs)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package digest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+
+	// DeliveryChannel posts the digest into the channel.
+	DeliveryChannel = "channel"
+	// DeliveryDM sends the digest as a direct message to every subscriber.
+	DeliveryDM = "dm"
+)
+
+// Schedule is the digest schedule of a channel.
+type Schedule struct {
+	ChannelID string `json:"channel_id"`
+	Frequency string `json:"frequency"`
+	// Weekday is the day weekly digests run on.
+	Weekday time.Weekday `json:"weekday,omitempty"`
+	Hour    int          `json:"hour"`
+	Minute  int          `json:"minute"`
+	// Timezone is the IANA time zone the time of day is interpreted in.
+	Timezone    string   `json:"timezone"`
+	Delivery    string   `json:"delivery"`
+	Subscribers []string `json:"subscribers,omitempty"`
+	Language    string   `json:"language,omitempty"`
+	// CreatedBy is the user who scheduled the digest. Posts are only read
+	// while this user can read the channel.
+	CreatedBy string `json:"created_by"`
+	// NextRunAt is when the digest is due next (unix millis).
+	NextRunAt int64 `json:"next_run_at"`
+}
+
+// Parse reads `daily 09:00` or `weekly mon 09:00`.
+func Parse(fields []string) (Schedule, error) {
+	if len(fields) == 0 {
+		return Schedule{}, fmt.Errorf("missing frequency: use daily or weekly")
+	}
+
+	s := Schedule{Frequency: strings.ToLower(fields[0]), Delivery: DeliveryChannel}
+	rest := fields[1:]
+	switch s.Frequency {
+	case FrequencyDaily:
+	case FrequencyWeekly:
+		if len(rest) == 0 {
+			return Schedule{}, fmt.Errorf("missing weekday: use mon, tue, wed, thu, fri, sat or sun")
+		}
+		weekday, ok := parseWeekday(rest[0])
+		if !ok {
+			return Schedule{}, fmt.Errorf("invalid weekday: %q", rest[0])
+		}
+		s.Weekday = weekday
+		rest = rest[1:]
+	default:
+		return Schedule{}, fmt.Errorf("invalid frequency: %q, use daily or weekly", fields[0])
+	}
+
+	if len(rest) != 1 {
+		return Schedule{}, fmt.Errorf("missing time of day, e.g. 09:00")
+	}
+	hour, minute, err := parseTimeOfDay(rest[0])
+	if err != nil {
+		return Schedule{}, err
+	}
+	s.Hour, s.Minute = hour, minute
+	return s, nil
+}
+
+// parseWeekday accepts full and three-letter English day names.
+func parseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(value)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if value == name || value == name[:3] {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+func parseTimeOfDay(value string) (int, int, error) {
+	hourText, minuteText, ok := strings.Cut(value, ":")
+	hour, hourErr := strconv.Atoi(hourText)
+	minute, minuteErr := strconv.Atoi(minuteText)
+	if !ok || hourErr != nil || minuteErr != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid time of day: %q, use HH:MM", value)
+	}
+	return hour, minute, nil
+}
+
+// Location returns the time zone of the schedule, UTC if it is unknown.
+func (s Schedule) Location() *time.Location {
+	if loc, err := time.LoadLocation(s.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Next returns the first time after t the digest is due.
+func (s Schedule) Next(t time.Time) time.Time {
+	local := t.In(s.Location())
+	next := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, s.Minute, 0, 0, local.Location())
+	if s.Frequency == FrequencyWeekly {
+		next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(t) {
+		if s.Frequency == FrequencyWeekly {
+			next = next.AddDate(0, 0, 7)
+		} else {
+			next = next.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
+
+// Period is the time span a digest covers.
+func (s Schedule) Period() time.Duration {
+	if s.Frequency == FrequencyWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Describe returns a human readable form such as "weekly on Monday at 09:00
+// (Europe/Berlin)".
+func (s Schedule) Describe() string {
+	when := fmt.Sprintf("daily at %02d:%02d", s.Hour, s.Minute)
+	if s.Frequency == FrequencyWeekly {
+		when = fmt.Sprintf("weekly on %s at %02d:%02d", s.Weekday, s.Hour, s.Minute)
+	}
+	return fmt.Sprintf("%s (%s)", when, s.Location())
+}
+
+// Subscribe adds the user to the recipients of DM digests. It reports whether
+// the user was not subscribed yet.
+func (s *Schedule) Subscribe(userID string) bool {
+	for _, id := range s.Subscribers {
+		if id == userID {
+			return false
+		}
+	}
+	s.Subscribers = append(s.Subscribers, userID)
+	return true
+}
+
+// Unsubscribe removes the user from the recipients of DM digests. It reports
+// whether the user was subscribed.
+func (s *Schedule) Unsubscribe(userID string) bool {
+	for i, id := range s.Subscribers {
+		if id == userID {
+			s.Subscribers = append(s.Subscribers[:i], s.Subscribers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package digest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		fields      []string
+		expected    Schedule
+		expectError string
+	}{
+		{
+			name:     "should_parse_daily",
+			fields:   []string{"daily", "09:00"},
+			expected: Schedule{Frequency: FrequencyDaily, Hour: 9, Delivery: DeliveryChannel},
+		},
+		{
+			name:     "should_parse_weekly",
+			fields:   []string{"Weekly", "Mon", "17:30"},
+			expected: Schedule{Frequency: FrequencyWeekly, Weekday: time.Monday, Hour: 17, Minute: 30, Delivery: DeliveryChannel},
+		},
+		{
+			name:     "should_accept_full_weekday_name",
+			fields:   []string{"weekly", "friday", "9:05"},
+			expected: Schedule{Frequency: FrequencyWeekly, Weekday: time.Friday, Hour: 9, Minute: 5, Delivery: DeliveryChannel},
+		},
+		{
+			name:        "should_reject_unknown_frequency",
+			fields:      []string{"hourly", "09:00"},
+			expectError: "invalid frequency",
+		},
+		{
+			name:        "should_reject_invalid_weekday",
+			fields:      []string{"weekly", "mo", "09:00"},
+			expectError: "invalid weekday",
+		},
+		{
+			name:        "should_reject_invalid_time",
+			fields:      []string{"daily", "24:00"},
+			expectError: "invalid time of day",
+		},
+		{
+			name:        "should_require_time",
+			fields:      []string{"daily"},
+			expectError: "missing time of day",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.fields)
+
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, schedule)
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "should_run_later_today",
+			schedule: Schedule{Frequency: FrequencyDaily, Hour: 9, Timezone: "Europe/Berlin"},
+			after:    time.Date(2026, 3, 10, 7, 0, 0, 0, berlin),
+			expected: time.Date(2026, 3, 10, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:     "should_run_tomorrow_when_time_has_passed",
+			schedule: Schedule{Frequency: FrequencyDaily, Hour: 9, Timezone: "Europe/Berlin"},
+			after:    time.Date(2026, 3, 10, 9, 0, 0, 0, berlin),
+			expected: time.Date(2026, 3, 11, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:     "should_keep_local_time_across_dst_change",
+			schedule: Schedule{Frequency: FrequencyDaily, Hour: 9, Timezone: "Europe/Berlin"},
+			after:    time.Date(2026, 3, 28, 10, 0, 0, 0, berlin),
+			expected: time.Date(2026, 3, 29, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:     "should_run_on_next_weekday",
+			schedule: Schedule{Frequency: FrequencyWeekly, Weekday: time.Monday, Hour: 9, Timezone: "Europe/Berlin"},
+			after:    time.Date(2026, 3, 11, 12, 0, 0, 0, berlin),
+			expected: time.Date(2026, 3, 16, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:     "should_run_next_week_when_time_has_passed",
+			schedule: Schedule{Frequency: FrequencyWeekly, Weekday: time.Monday, Hour: 9, Timezone: "Europe/Berlin"},
+			after:    time.Date(2026, 3, 16, 10, 0, 0, 0, berlin),
+			expected: time.Date(2026, 3, 23, 9, 0, 0, 0, berlin),
+		},
+		{
+			name:     "should_use_utc_for_unknown_timezone",
+			schedule: Schedule{Frequency: FrequencyDaily, Hour: 9, Timezone: "Mars/Olympus"},
+			after:    time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC),
+			expected: time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.expected.Equal(tt.schedule.Next(tt.after)), "expected %s, got %s", tt.expected, tt.schedule.Next(tt.after))
+		})
+	}
+}
+
+func TestSchedule_Subscribe(t *testing.T) {
+	var schedule Schedule
+
+	assert.True(t, schedule.Subscribe("user1"))
+	assert.False(t, schedule.Subscribe("user1"))
+	assert.True(t, schedule.Subscribe("user2"))
+	assert.True(t, schedule.Unsubscribe("user1"))
+	assert.False(t, schedule.Unsubscribe("user1"))
+	assert.Equal(t, []string{"user2"}, schedule.Subscribers)
+}
@@ -0,0 +1,247 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// ExtractedAction is an action item found in a conversation.
+type ExtractedAction struct {
+	Text string `json:"text"`
+	// OwnerID is the Mattermost user ID of the owner if the owner named by
+	// the model could be matched to a user.
+	OwnerID string `json:"owner_id,omitempty"`
+	// Owner is the username of the matched owner, or the name given by the
+	// model otherwise.
+	Owner string `json:"owner,omitempty"`
+	// DueDate is formatted as YYYY-MM-DD when it could be parsed.
+	DueDate string `json:"due_date,omitempty"`
+	// PostID is the post the action item was agreed on in, and RootID the
+	// thread that post belongs to.
+	PostID string `json:"post_id,omitempty"`
+	RootID string `json:"root_id,omitempty"`
+}
+
+// actionsSchema is the JSON schema of the extraction answer. Source is the
+// number of the message the action item comes from.
+var actionsSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"action_items": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"text": {"type": "string"},
+					"owner": {"type": "string"},
+					"due_date": {"type": "string"},
+					"source": {"type": "integer"}
+				},
+				"required": ["text", "owner", "due_date", "source"],
+				"additionalProperties": false
+			}
+		}
+	},
+	"required": ["action_items"],
+	"additionalProperties": false
+}`)
+
+var mentionPattern = regexp.MustCompile(`@([a-z0-9][a-z0-9._-]*[a-z0-9_])`)
+
+// ExtractActions finds the action items in posts. Messages are numbered in
+// the prompt so that every item can be traced back to its post, and owners
+// are matched against the authors and mentioned users of the conversation.
+// Conversations that do not fit into the model context are processed in
+// chunks.
+func (s Service) ExtractActions(ctx context.Context, posts []*model.Post, params Params) ([]ExtractedAction, error) {
+	language := s.resolveLanguage(params.Language, posts)
+	prompts := builtinPrompts[language]
+
+	numbered, lines, people := s.numberedLines(posts)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("no messages")
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	var actions []ExtractedAction
+	seen := make(map[string]struct{})
+	for i, chunk := range splitIntoChunks(lines, chunkBudget(s.llm.ContextSize())) {
+		answer, err := s.llm.GenerateJSON(ctx, fmt.Sprintf(prompts.actions, today, chunk), actionsSchema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract action items from chunk %d: %w", i+1, err)
+		}
+
+		items, err := parseActions(answer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse action items: %w", err)
+		}
+		for _, item := range items {
+			action := ExtractedAction{Text: item.text, DueDate: item.dueDate}
+			if post, ok := numbered[item.source]; ok {
+				action.PostID = post.Id
+				action.RootID = post.RootId
+			}
+			action.OwnerID, action.Owner = people.match(item.owner)
+
+			key := action.PostID + "\x00" + strings.ToLower(action.Text)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+// numberedLines renders posts as "[n] date author (@username): message" and
+// returns the posts by number together with the people the conversation
+// involves.
+func (s Service) numberedLines(posts []*model.Post) (map[int]*model.Post, []string, people) {
+	numbered := make(map[int]*model.Post, len(posts))
+	lines := make([]string, 0, len(posts))
+	known := people{}
+	for _, post := range conversationPosts(posts) {
+		author := "unknown user"
+		if user, err := s.userProvider.Get(post.UserId); err == nil && user != nil {
+			known.add(user)
+			author = fmt.Sprintf("%s (@%s)", strings.TrimSpace(user.FirstName+" "+user.LastName), user.Username)
+		}
+		if name := overrideUsername(post); name != "" {
+			author = name
+		}
+		text := postText(post)
+		for _, match := range mentionPattern.FindAllStringSubmatch(strings.ToLower(text), -1) {
+			known.addUsername(s.userProvider, match[1])
+		}
+
+		n := len(lines) + 1
+		numbered[n] = post
+		date := time.UnixMilli(post.CreateAt).UTC().Format("2006-01-02")
+		lines = append(lines, fmt.Sprintf("[%d] %s %s: %s", n, date, author, text))
+	}
+	return numbered, lines, known
+}
+
+// people are the users a conversation involves by user ID.
+type people map[string]*model.User
+
+func (p people) add(user *model.User) {
+	p[user.Id] = user
+}
+
+// addUsername adds a mentioned user, ignoring mentions that are not users,
+// such as @channel.
+func (p people) addUsername(users userProvider, username string) {
+	for _, user := range p {
+		if user.Username == username {
+			return
+		}
+	}
+	if user, err := users.GetByUsername(username); err == nil && user != nil {
+		p.add(user)
+	}
+}
+
+// match finds the user the model named as owner by username, full name or
+// first name. It returns the user ID and username of a unique match, and the
+// name as given otherwise.
+func (p people) match(name string) (userID, owner string) {
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "@"))
+	if name == "" {
+		return "", ""
+	}
+
+	matchers := []func(user *model.User) bool{
+		func(user *model.User) bool { return strings.EqualFold(user.Username, name) },
+		func(user *model.User) bool {
+			return strings.EqualFold(strings.TrimSpace(user.FirstName+" "+user.LastName), name)
+		},
+		func(user *model.User) bool { return strings.EqualFold(user.FirstName, name) },
+	}
+	for _, matches := range matchers {
+		var found *model.User
+		for _, user := range p {
+			if !matches(user) {
+				continue
+			}
+			if found != nil {
+				// Ambiguous, e.g. two people with the same first name.
+				return "", name
+			}
+			found = user
+		}
+		if found != nil {
+			return found.Id, found.Username
+		}
+	}
+	return "", name
+}
+
+// parsedAction is an action item as answered by the model.
+type parsedAction struct {
+	text, owner, dueDate string
+	source               int
+}
+
+// parseActions reads the extraction answer, repairing it the same way as
+// parseStructuredSummary. A list without items is valid.
+func parseActions(answer string) ([]parsedAction, error) {
+	start := strings.Index(answer, "{")
+	end := strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return nil, errNotJSON
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(removeTrailingCommas(answer[start:end+1])), &fields); err != nil {
+		return nil, fmt.Errorf("invalid action items JSON: %w", err)
+	}
+	normalized := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		normalized[normalizeKey(key)] = value
+	}
+
+	var list []json.RawMessage
+	if raw := firstField(normalized, "actionitems", "actions", "tasks"); len(raw) > 0 {
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, fmt.Errorf("action items must be a list: %w", err)
+		}
+	}
+
+	actions := make([]parsedAction, 0, len(list))
+	for _, entry := range list {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(entry, &object); err != nil {
+			continue
+		}
+		item := make(map[string]json.RawMessage, len(object))
+		for key, value := range object {
+			item[normalizeKey(key)] = value
+		}
+
+		action := parsedAction{
+			text:    decodeText(firstField(item, "text", "task", "action", "title", "description")),
+			owner:   decodeText(firstField(item, "owner", "assignee", "responsible")),
+			dueDate: normalizeDueDate(decodeText(firstField(item, "duedate", "due", "deadline"))),
+			source:  decodeSource(firstField(item, "source", "message", "post")),
+		}
+		if action.text != "" {
+			actions = append(actions, action)
+		}
+	}
+	return actions, nil
+}
+
+// decodeSource reads a message number given as 3, "3" or "[3]".
+func decodeSource(raw json.RawMessage) int {
+	source, _ := strconv.Atoi(strings.Trim(decodeText(raw), "[]# "))
+	return source
+}
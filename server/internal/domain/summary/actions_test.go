@@ -0,0 +1,105 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_ExtractActions(t *testing.T) {
+	users := fakeUsers{
+		"u1": {Id: "u1", Username: "ivan", FirstName: "Ivan", LastName: "Petrov"},
+		"u2": {Id: "u2", Username: "anna", FirstName: "Anna", LastName: "Smirnova"},
+		"u3": {Id: "u3", Username: "oleg", FirstName: "Oleg"},
+	}
+	posts := func() []*model.Post {
+		return []*model.Post{
+			{Id: "p1", ChannelId: "c1", UserId: "u1", Message: "Release on Friday", CreateAt: 1741600000000},
+			{Id: "p2", ChannelId: "c1", RootId: "p1", UserId: "u2", Message: "I'll update the API, @oleg please write docs", CreateAt: 1741600060000},
+		}
+	}
+
+	t.Run("should_map_sources_and_owners", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000, answer: `{"action_items": [
+			{"text": "Update the API", "owner": "@anna", "due_date": "2025-03-14", "source": 2},
+			{"text": "Write docs", "owner": "Oleg", "due_date": "", "source": "[2]"},
+			{"text": "Prepare demo", "owner": "Maria", "due_date": "next week", "source": 7}
+		]}`}
+		service := NewService(llm, users, WithLanguage(LanguageEnglish))
+
+		actions, err := service.ExtractActions(context.Background(), posts(), Params{})
+
+		require.NoError(t, err)
+		assert.Equal(t, []ExtractedAction{
+			{Text: "Update the API", OwnerID: "u2", Owner: "anna", DueDate: "2025-03-14", PostID: "p2", RootID: "p1"},
+			{Text: "Write docs", OwnerID: "u3", Owner: "oleg", PostID: "p2", RootID: "p1"},
+			{Text: "Prepare demo", Owner: "Maria", DueDate: "next week"},
+		}, actions)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "[1] 2025-03-10 Ivan Petrov (@ivan): Release on Friday")
+		assert.Contains(t, llm.prompts[0], "[2] 2025-03-10 Anna Smirnova (@anna): I'll update the API")
+	})
+
+	t.Run("should_return_no_actions", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000, answer: `{"action_items": []}`}
+		service := NewService(llm, users)
+
+		actions, err := service.ExtractActions(context.Background(), posts(), Params{})
+
+		require.NoError(t, err)
+		assert.Empty(t, actions)
+	})
+
+	t.Run("should_fail_on_answer_without_json", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000, answer: "Anna updates the API."}
+		service := NewService(llm, users)
+
+		_, err := service.ExtractActions(context.Background(), posts(), Params{})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse action items")
+	})
+
+	t.Run("should_extract_from_every_chunk_without_duplicates", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 4000, answer: `{"action_items": [{"text": "Update the API", "owner": "anna", "due_date": "", "source": 1}]}`}
+		service := NewService(llm, users)
+
+		actions, err := service.ExtractActions(context.Background(), newPosts(10, 1800), Params{})
+
+		require.NoError(t, err)
+		assert.Greater(t, len(llm.prompts), 1)
+		assert.Len(t, actions, 1)
+	})
+}
+
+func TestPeople_match(t *testing.T) {
+	known := people{
+		"u1": {Id: "u1", Username: "ivan.p", FirstName: "Ivan", LastName: "Petrov"},
+		"u2": {Id: "u2", Username: "ivan.s", FirstName: "Ivan", LastName: "Sidorov"},
+	}
+
+	tests := []struct {
+		name           string
+		owner          string
+		expectedID     string
+		expectedString string
+	}{
+		{name: "should_match_username", owner: "@ivan.s", expectedID: "u2", expectedString: "ivan.s"},
+		{name: "should_match_full_name", owner: "ivan petrov", expectedID: "u1", expectedString: "ivan.p"},
+		{name: "should_keep_ambiguous_first_name", owner: "Ivan", expectedString: "Ivan"},
+		{name: "should_keep_unknown_name", owner: "Maria", expectedString: "Maria"},
+		{name: "should_ignore_empty_owner", owner: " "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, owner := known.match(tt.owner)
+
+			assert.Equal(t, tt.expectedID, userID)
+			assert.Equal(t, tt.expectedString, owner)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// maxAttachmentSize is the size of the largest file whose text is read.
+	// Larger files are skipped rather than truncated: the file store returns
+	// whole files.
+	maxAttachmentSize = 1 << 20
+
+	// maxAttachments caps the number of files read for one summary.
+	maxAttachments = 20
+
+	// attachmentExcerptRunes is the length of the excerpt of a file included
+	// in the conversation.
+	attachmentExcerptRunes = 2000
+
+	// maxImageSize is the size of the largest image passed to the model.
+	maxImageSize = 5 << 20
+
+	// maxImagesSize caps the total size of the images of one request.
+	maxImagesSize = 20 << 20
+)
+
+// textExtensions are the extensions of plain text, markdown, code, CSV and
+// JSON files, whose content is read as is.
+var textExtensions = map[string]struct{}{
+	"txt": {}, "text": {}, "log": {}, "md": {}, "markdown": {}, "rst": {},
+	"csv": {}, "tsv": {}, "json": {}, "jsonl": {}, "ndjson": {}, "yaml": {}, "yml": {}, "toml": {}, "ini": {}, "xml": {},
+	"go": {}, "py": {}, "js": {}, "jsx": {}, "ts": {}, "tsx": {}, "java": {}, "kt": {}, "c": {}, "h": {}, "cpp": {},
+	"hpp": {}, "cs": {}, "rs": {}, "rb": {}, "php": {}, "swift": {}, "scala": {}, "sh": {}, "bash": {}, "ps1": {},
+	"sql": {}, "html": {}, "css": {}, "scss": {}, "diff": {}, "patch": {}, "proto": {}, "tf": {}, "dockerfile": {},
+}
+
+// SkippedAttachment is a file attached to a summarized post whose content
+// the summary does not take into account.
+type SkippedAttachment struct {
+	PostID string `json:"post_id"`
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// attachment is a file attached to a post of the conversation.
+type attachment struct {
+	info *model.FileInfo
+	// readable is set for files whose text is included in the conversation.
+	readable bool
+	// image is the number of an image passed along with the prompt, zero
+	// for other files.
+	image int
+}
+
+// attachments are the files of the posts of a conversation by post ID. The
+// text of readable files and images are only read when they are used, so
+// that summaries found in the cache do not read any files.
+type attachments struct {
+	files   fileProvider
+	byPost  map[string][]attachment
+	images  []*model.FileInfo
+	skipped []SkippedAttachment
+}
+
+// loadAttachments looks up the files of posts and decides which of them can
+// be included in the conversation. With images set, images are selected for
+// the request if the model accepts them, up to the configured number and
+// maxImagesSize. Without a file provider files are not taken into account at
+// all.
+func (s Service) loadAttachments(ctx context.Context, posts []*model.Post, images bool) attachments {
+	a := attachments{files: s.files, byPost: make(map[string][]attachment)}
+	if s.files == nil {
+		return a
+	}
+
+	readable := 0
+	var imagesSize int64
+	// The model is only asked whether it accepts images once there is one.
+	var vision *bool
+	acceptsImages := func() bool {
+		if vision == nil {
+			supported := images && s.supportsImages(ctx)
+			vision = &supported
+		}
+		return *vision
+	}
+	for _, post := range conversationPosts(posts) {
+		for _, fileID := range post.FileIds {
+			info, err := s.files.GetInfo(fileID)
+			if err != nil || info == nil {
+				a.skipped = append(a.skipped, SkippedAttachment{PostID: post.Id, Name: fileID, Reason: "could not be loaded"})
+				continue
+			}
+
+			if info.IsImage() && acceptsImages() {
+				reason := imageSkipReason(info, len(a.images), s.maxImages, imagesSize)
+				if reason != "" {
+					a.skipped = append(a.skipped, SkippedAttachment{PostID: post.Id, Name: info.Name, Reason: reason})
+					a.byPost[post.Id] = append(a.byPost[post.Id], attachment{info: info})
+					continue
+				}
+				a.images = append(a.images, info)
+				imagesSize += info.Size
+				a.byPost[post.Id] = append(a.byPost[post.Id], attachment{info: info, image: len(a.images)})
+				continue
+			}
+
+			reason := skipReason(info)
+			if reason == "" && readable == maxAttachments {
+				reason = fmt.Sprintf("more than %d files", maxAttachments)
+			}
+			if reason != "" {
+				a.skipped = append(a.skipped, SkippedAttachment{PostID: post.Id, Name: info.Name, Reason: reason})
+			} else {
+				readable++
+			}
+			a.byPost[post.Id] = append(a.byPost[post.Id], attachment{info: info, readable: reason == ""})
+		}
+	}
+	return a
+}
+
+// skipReason tells why the text of a file cannot be included, empty if it
+// can. Besides text files this covers documents such as PDFs whose text the
+// server has extracted.
+func skipReason(info *model.FileInfo) string {
+	if info.Content != "" {
+		return ""
+	}
+	if !isText(info) {
+		return "unsupported file type"
+	}
+	if info.Size > maxAttachmentSize {
+		return fmt.Sprintf("larger than %d MB", maxAttachmentSize>>20)
+	}
+	return ""
+}
+
+// imageSkipReason tells why an image cannot be passed to the model, empty if
+// it can.
+func imageSkipReason(info *model.FileInfo, count, maxCount int, size int64) string {
+	switch {
+	case count == maxCount:
+		return fmt.Sprintf("more than %d images", maxCount)
+	case info.Size > maxImageSize:
+		return fmt.Sprintf("larger than %d MB", maxImageSize>>20)
+	case size+info.Size > maxImagesSize:
+		return fmt.Sprintf("images exceed %d MB in total", maxImagesSize>>20)
+	default:
+		return ""
+	}
+}
+
+// supportsImages reports whether images may be passed to the model. Failures
+// to find out are treated as no.
+func (s Service) supportsImages(ctx context.Context) bool {
+	generator, ok := s.llm.(imageGenerator)
+	if !ok || s.maxImages <= 0 {
+		return false
+	}
+	supported, err := generator.SupportsImages(ctx)
+	return err == nil && supported
+}
+
+func isText(info *model.FileInfo) bool {
+	if _, ok := textExtensions[strings.ToLower(strings.TrimPrefix(info.Extension, "."))]; ok {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(info.MimeType)
+	return strings.HasPrefix(mediaType, "text/") || mediaType == "application/json"
+}
+
+// render returns the files of a post as "[label: name]" followed by an
+// excerpt of their text, or the number of the image for images passed along
+// with the prompt. Skipped files are listed by name only.
+func (a attachments) render(postID string, labels conversationLabels) []string {
+	var rendered []string
+	for _, file := range a.byPost[postID] {
+		text := fmt.Sprintf("[%s: %s]", labels.file, file.info.Name)
+		if file.image != 0 {
+			text = fmt.Sprintf("[%s: %s, %s]", labels.file, file.info.Name, fmt.Sprintf(labels.image, file.image))
+		}
+		if file.readable {
+			if excerpt, truncated := a.excerpt(file.info); excerpt != "" {
+				text += "\n" + excerpt
+				if truncated {
+					text += "\n" + labels.truncated
+				}
+			}
+		}
+		rendered = append(rendered, text)
+	}
+	return rendered
+}
+
+// excerpt returns the beginning of the text of a file and whether the text
+// goes on. Files that cannot be read or are not valid text give no excerpt.
+func (a attachments) excerpt(info *model.FileInfo) (string, bool) {
+	text := info.Content
+	if text == "" {
+		reader, err := a.files.Get(info.Id)
+		if err != nil {
+			return "", false
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, maxAttachmentSize))
+		if err != nil || !utf8.Valid(data) {
+			return "", false
+		}
+		text = string(data)
+	}
+
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if utf8.RuneCountInString(text) <= attachmentExcerptRunes {
+		return text, false
+	}
+	return string([]rune(text)[:attachmentExcerptRunes]), true
+}
+
+// readImages returns the content of the selected images. Images that cannot
+// be read are left out.
+func (a attachments) readImages() [][]byte {
+	images := make([][]byte, 0, len(a.images))
+	for _, info := range a.images {
+		reader, err := a.files.Get(info.Id)
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, maxImageSize))
+		if err != nil {
+			continue
+		}
+		images = append(images, data)
+	}
+	return images
+}
@@ -0,0 +1,288 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFiles serves file infos and contents by file ID.
+type fakeFiles struct {
+	infos    map[string]*model.FileInfo
+	contents map[string]string
+}
+
+func (f fakeFiles) GetInfo(fileID string) (*model.FileInfo, error) {
+	info, ok := f.infos[fileID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return info, nil
+}
+
+func (f fakeFiles) Get(fileID string) (io.Reader, error) {
+	content, ok := f.contents[fileID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return bytes.NewReader([]byte(content)), nil
+}
+
+func TestService_loadAttachments(t *testing.T) {
+	files := fakeFiles{
+		infos: map[string]*model.FileInfo{
+			"log":    {Id: "log", Name: "deploy.log", Extension: "log", Size: 20},
+			"csv":    {Id: "csv", Name: "report.csv", Extension: "csv", MimeType: "text/csv", Size: 20},
+			"pdf":    {Id: "pdf", Name: "plan.pdf", Extension: "pdf", Size: 5000, Content: "Release plan"},
+			"png":    {Id: "png", Name: "screen.png", Extension: "png", MimeType: "image/png", Size: 5000},
+			"huge":   {Id: "huge", Name: "dump.json", Extension: "json", Size: maxAttachmentSize + 1},
+			"plain":  {Id: "plain", Name: "notes", MimeType: "text/plain; charset=utf-8", Size: 20},
+			"broken": {Id: "broken", Name: "broken.txt", Extension: "txt", Size: 20},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		fileIDs         []string
+		expectedReady   []string
+		expectedSkipped []SkippedAttachment
+	}{
+		{
+			name:          "should_accept_text_files_and_extracted_documents",
+			fileIDs:       []string{"log", "csv", "pdf", "plain"},
+			expectedReady: []string{"deploy.log", "report.csv", "plan.pdf", "notes"},
+		},
+		{
+			name:            "should_skip_unsupported_and_large_files",
+			fileIDs:         []string{"png", "huge", "log"},
+			expectedReady:   []string{"deploy.log"},
+			expectedSkipped: []SkippedAttachment{{PostID: "p1", Name: "screen.png", Reason: "unsupported file type"}, {PostID: "p1", Name: "dump.json", Reason: "larger than 1 MB"}},
+		},
+		{
+			name:            "should_report_files_that_cannot_be_loaded",
+			fileIDs:         []string{"missing"},
+			expectedSkipped: []SkippedAttachment{{PostID: "p1", Name: "missing", Reason: "could not be loaded"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&fakeLLM{}, fakeUsers{}, WithAttachments(files))
+
+			a := service.loadAttachments(context.Background(), []*model.Post{{Id: "p1", Message: "files", FileIds: tt.fileIDs}}, true)
+
+			var ready []string
+			for _, file := range a.byPost["p1"] {
+				if file.readable {
+					ready = append(ready, file.info.Name)
+				}
+			}
+			assert.Equal(t, tt.expectedReady, ready)
+			assert.Equal(t, tt.expectedSkipped, a.skipped)
+		})
+	}
+
+	t.Run("should_limit_number_of_files", func(t *testing.T) {
+		many := fakeFiles{infos: map[string]*model.FileInfo{}}
+		var fileIDs []string
+		for i := 0; i <= maxAttachments; i++ {
+			id := fmt.Sprintf("f%d", i)
+			many.infos[id] = &model.FileInfo{Id: id, Name: id + ".txt", Extension: "txt"}
+			fileIDs = append(fileIDs, id)
+		}
+		service := NewService(&fakeLLM{}, fakeUsers{}, WithAttachments(many))
+
+		a := service.loadAttachments(context.Background(), []*model.Post{{Id: "p1", FileIds: fileIDs[:10]}, {Id: "p2", FileIds: fileIDs[10:]}}, true)
+
+		require.Len(t, a.skipped, 1)
+		assert.Equal(t, SkippedAttachment{PostID: "p2", Name: "f20.txt", Reason: "more than 20 files"}, a.skipped[0])
+	})
+
+	t.Run("should_ignore_files_without_provider", func(t *testing.T) {
+		service := NewService(&fakeLLM{}, fakeUsers{})
+
+		a := service.loadAttachments(context.Background(), []*model.Post{{Id: "p1", FileIds: []string{"log"}}}, true)
+
+		assert.Empty(t, a.byPost)
+		assert.Empty(t, a.skipped)
+	})
+
+	t.Run("should_render_excerpts_under_the_message", func(t *testing.T) {
+		files.contents = map[string]string{
+			"log":    "step 1 ok\r\nstep 2 failed\n",
+			"broken": "\xff\xfe",
+			"csv":    strings.Repeat("a", attachmentExcerptRunes+10),
+		}
+		service := NewService(&fakeLLM{}, fakeUsers{}, WithAttachments(files))
+		posts := []*model.Post{{Id: "p1", Message: "see logs", FileIds: []string{"log", "pdf", "png", "broken", "csv"}}}
+		c := conversation{users: fakeUsers{}, labels: builtinPrompts[LanguageEnglish].labels, location: loadLocation(""), attachments: service.loadAttachments(context.Background(), posts, true), refs: citations{}}
+
+		lines, _ := c.lines(posts)
+
+		require.Len(t, lines, 1)
+		assert.Equal(t, "[1] 1970-01-01 00:00 unknown user: see logs\n"+
+			"    [attached file: deploy.log]\n    step 1 ok\n    step 2 failed\n"+
+			"    [attached file: plan.pdf]\n    Release plan\n"+
+			"    [attached file: screen.png]\n"+
+			"    [attached file: broken.txt]\n"+
+			"    [attached file: report.csv]\n    "+strings.Repeat("a", attachmentExcerptRunes)+"\n    (truncated)", lines[0])
+	})
+}
+
+func TestService_GenerateSummary_attachments(t *testing.T) {
+	files := fakeFiles{
+		infos: map[string]*model.FileInfo{
+			"log": {Id: "log", Name: "deploy.log", Extension: "log", Size: 20},
+			"png": {Id: "png", Name: "screen.png", Extension: "png", Size: 5000},
+		},
+		contents: map[string]string{"log": "panic: nil map"},
+	}
+	posts := []*model.Post{{Id: "p1", UserId: "u1", Message: "", FileIds: []string{"log", "png"}}}
+
+	llm := &fakeLLM{contextSize: 64000}
+	service := NewService(llm, fakeUsers{}, WithAttachments(files), WithCache(newMemCache()))
+
+	for i := 0; i < 2; i++ {
+		summary, err := service.GenerateSummary(context.Background(), posts)
+
+		require.NoError(t, err)
+		assert.Equal(t, []SkippedAttachment{{PostID: "p1", Name: "screen.png", Reason: "unsupported file type"}}, summary.SkippedAttachments)
+	}
+	require.Len(t, llm.prompts, 1, "the second summary comes from the cache")
+	assert.Contains(t, llm.prompts[0], "unknown user: [attached file: deploy.log]\n    panic: nil map\n")
+}
+
+// fakeVisionLLM is a model that accepts images when vision is set.
+type fakeVisionLLM struct {
+	fakeLLM
+	vision bool
+	images [][]byte
+}
+
+func (f *fakeVisionLLM) SupportsImages(context.Context) (bool, error) {
+	return f.vision, nil
+}
+
+func (f *fakeVisionLLM) GenerateWithImages(ctx context.Context, prompt string, images [][]byte, onChunk func(chunk string) error) (string, error) {
+	f.images = images
+	return f.GenerateStream(ctx, prompt, onChunk)
+}
+
+func TestService_loadAttachments_images(t *testing.T) {
+	files := fakeFiles{
+		infos: map[string]*model.FileInfo{
+			"a":     {Id: "a", Name: "a.png", Extension: "png", MimeType: "image/png", Size: 1000},
+			"b":     {Id: "b", Name: "b.jpg", Extension: "jpg", MimeType: "image/jpeg", Size: 1000},
+			"c":     {Id: "c", Name: "c.png", Extension: "png", MimeType: "image/png", Size: 1000},
+			"large": {Id: "large", Name: "large.png", Extension: "png", MimeType: "image/png", Size: maxImageSize + 1},
+			"big1":  {Id: "big1", Name: "big1.png", Extension: "png", MimeType: "image/png", Size: maxImageSize},
+			"big2":  {Id: "big2", Name: "big2.png", Extension: "png", MimeType: "image/png", Size: maxImageSize},
+			"big3":  {Id: "big3", Name: "big3.png", Extension: "png", MimeType: "image/png", Size: maxImageSize},
+			"big4":  {Id: "big4", Name: "big4.png", Extension: "png", MimeType: "image/png", Size: maxImageSize},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		vision          bool
+		maxImages       int
+		withImages      bool
+		fileIDs         []string
+		expectedImages  []string
+		expectedSkipped []SkippedAttachment
+	}{
+		{
+			name:           "should_select_images_for_vision_models",
+			vision:         true,
+			maxImages:      4,
+			withImages:     true,
+			fileIDs:        []string{"a", "b"},
+			expectedImages: []string{"a.png", "b.jpg"},
+		},
+		{
+			name:            "should_limit_number_of_images",
+			vision:          true,
+			maxImages:       2,
+			withImages:      true,
+			fileIDs:         []string{"a", "b", "c"},
+			expectedImages:  []string{"a.png", "b.jpg"},
+			expectedSkipped: []SkippedAttachment{{PostID: "p1", Name: "c.png", Reason: "more than 2 images"}},
+		},
+		{
+			name:            "should_limit_size_of_images",
+			vision:          true,
+			maxImages:       10,
+			withImages:      true,
+			fileIDs:         []string{"large", "big1", "big2", "big3", "big4", "a"},
+			expectedImages:  []string{"big1.png", "big2.png", "big3.png", "big4.png"},
+			expectedSkipped: []SkippedAttachment{{PostID: "p1", Name: "large.png", Reason: "larger than 5 MB"}, {PostID: "p1", Name: "a.png", Reason: "images exceed 20 MB in total"}},
+		},
+		{
+			name:            "should_skip_images_for_text_models",
+			maxImages:       4,
+			withImages:      true,
+			fileIDs:         []string{"a"},
+			expectedSkipped: []SkippedAttachment{{PostID: "p1", Name: "a.png", Reason: "unsupported file type"}},
+		},
+		{
+			name:            "should_skip_images_when_disabled",
+			vision:          true,
+			withImages:      true,
+			fileIDs:         []string{"a"},
+			expectedSkipped: []SkippedAttachment{{PostID: "p1", Name: "a.png", Reason: "unsupported file type"}},
+		},
+		{
+			name:            "should_skip_images_when_not_requested",
+			vision:          true,
+			maxImages:       4,
+			fileIDs:         []string{"a"},
+			expectedSkipped: []SkippedAttachment{{PostID: "p1", Name: "a.png", Reason: "unsupported file type"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&fakeVisionLLM{vision: tt.vision}, fakeUsers{}, WithAttachments(files), WithImages(tt.maxImages))
+
+			a := service.loadAttachments(context.Background(), []*model.Post{{Id: "p1", FileIds: tt.fileIDs}}, tt.withImages)
+
+			var images []string
+			for _, info := range a.images {
+				images = append(images, info.Name)
+			}
+			assert.Equal(t, tt.expectedImages, images)
+			assert.Equal(t, tt.expectedSkipped, a.skipped)
+		})
+	}
+}
+
+func TestService_GenerateSummary_images(t *testing.T) {
+	files := fakeFiles{
+		infos: map[string]*model.FileInfo{
+			"png":    {Id: "png", Name: "screen.png", Extension: "png", MimeType: "image/png", Size: 3},
+			"broken": {Id: "broken", Name: "gone.png", Extension: "png", MimeType: "image/png", Size: 3},
+		},
+		contents: map[string]string{"png": "png"},
+	}
+	posts := []*model.Post{{Id: "p1", UserId: "u1", Message: "look", FileIds: []string{"png", "broken"}}}
+
+	llm := &fakeVisionLLM{fakeLLM: fakeLLM{contextSize: 64000}, vision: true}
+	service := NewService(llm, fakeUsers{}, WithAttachments(files), WithImages(4), WithLanguage(LanguageEnglish))
+
+	summary, err := service.GenerateSummary(context.Background(), posts)
+
+	require.NoError(t, err)
+	assert.Equal(t, "summary", summary.Text)
+	assert.Empty(t, summary.SkippedAttachments)
+	assert.Equal(t, [][]byte{[]byte("png")}, llm.images, "images that cannot be read are left out")
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "unknown user: look\n    [attached file: screen.png, image 1]\n    [attached file: gone.png, image 2]")
+}
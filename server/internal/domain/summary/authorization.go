@@ -0,0 +1,67 @@
+package summary
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// ErrForbidden reports that a user may not read the channel a summary is
+// requested for.
+var ErrForbidden = errors.New("user cannot read the channel")
+
+// Authorizer decides whether a user may summarize a channel or thread. Every
+// entry point (slash commands, the REST API, background jobs) must consult it
+// before loading posts on behalf of a user.
+type Authorizer struct {
+	permissions permissionChecker
+	channels    channelMembers
+	posts       postProvider
+}
+
+func NewAuthorizer(permissions permissionChecker, channels channelMembers, posts postProvider) *Authorizer {
+	return &Authorizer{
+		permissions: permissions,
+		channels:    channels,
+		posts:       posts,
+	}
+}
+
+// AuthorizeChannel returns nil if the user can read the channel: members can,
+// and so can users allowed to read public channels of the team or, e.g. as
+// system admins, any channel. It returns ErrForbidden otherwise.
+func (a Authorizer) AuthorizeChannel(userID, channelID string) error {
+	if userID == "" || channelID == "" {
+		return ErrForbidden
+	}
+
+	if member, err := a.channels.GetMember(channelID, userID); err == nil && member != nil {
+		return nil
+	}
+
+	channel, err := a.channels.Get(channelID)
+	if err != nil {
+		return fmt.Errorf("failed to get channel: %w", err)
+	}
+	if channel.Type == model.ChannelTypeOpen && a.permissions.HasPermissionToTeam(userID, channel.TeamId, model.PermissionReadPublicChannel) {
+		return nil
+	}
+	if a.permissions.HasPermissionToChannel(userID, channelID, model.PermissionReadChannel) {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// AuthorizeThread checks that the user can read the channel of the post and
+// returns the post. The post may be the root or any reply of the thread.
+func (a Authorizer) AuthorizeThread(userID, postID string) (*model.Post, error) {
+	post, err := a.posts.GetPost(postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if err := a.AuthorizeChannel(userID, post.ChannelId); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
@@ -0,0 +1,139 @@
+package summary
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAuthorizer() (*Authorizer, *plugintest.API) {
+	api := &plugintest.API{}
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+	return NewAuthorizer(&client.User, &client.Channel, &client.Post), api
+}
+
+func notFound() *model.AppError {
+	return model.NewAppError("test", "not_found", nil, "", http.StatusNotFound)
+}
+
+func TestAuthorizer_AuthorizeChannel(t *testing.T) {
+	tests := []struct {
+		name          string
+		setup         func(api *plugintest.API)
+		expectedError error
+	}{
+		{
+			name: "should_allow_channel_member",
+			setup: func(api *plugintest.API) {
+				api.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{ChannelId: "channel1", UserId: "user1"}, nil)
+			},
+		},
+		{
+			name: "should_allow_reading_public_channel_of_the_team",
+			setup: func(api *plugintest.API) {
+				api.On("GetChannelMember", "channel1", "user1").Return(nil, notFound())
+				api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", Type: model.ChannelTypeOpen}, nil)
+				api.On("HasPermissionToTeam", "user1", "team1", model.PermissionReadPublicChannel).Return(true)
+			},
+		},
+		{
+			name: "should_allow_user_with_read_permission",
+			setup: func(api *plugintest.API) {
+				api.On("GetChannelMember", "channel1", "user1").Return(nil, notFound())
+				api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", Type: model.ChannelTypePrivate}, nil)
+				api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionReadChannel).Return(true)
+			},
+		},
+		{
+			name: "should_forbid_private_channel_for_non_member",
+			setup: func(api *plugintest.API) {
+				api.On("GetChannelMember", "channel1", "user1").Return(nil, notFound())
+				api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team1", Type: model.ChannelTypePrivate}, nil)
+				api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionReadChannel).Return(false)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name: "should_forbid_public_channel_of_another_team",
+			setup: func(api *plugintest.API) {
+				api.On("GetChannelMember", "channel1", "user1").Return(nil, notFound())
+				api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", TeamId: "team2", Type: model.ChannelTypeOpen}, nil)
+				api.On("HasPermissionToTeam", "user1", "team2", model.PermissionReadPublicChannel).Return(false)
+				api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionReadChannel).Return(false)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name: "should_fail_for_unknown_channel",
+			setup: func(api *plugintest.API) {
+				api.On("GetChannelMember", "channel1", "user1").Return(nil, notFound())
+				api.On("GetChannel", "channel1").Return(nil, notFound())
+			},
+			expectedError: pluginapi.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorizer, api := setupAuthorizer()
+			tt.setup(api)
+
+			err := authorizer.AuthorizeChannel("user1", "channel1")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			api.AssertExpectations(t)
+		})
+	}
+
+	t.Run("should_forbid_missing_user", func(t *testing.T) {
+		authorizer, api := setupAuthorizer()
+
+		err := authorizer.AuthorizeChannel("", "channel1")
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		api.AssertNotCalled(t, "GetChannelMember")
+	})
+}
+
+func TestAuthorizer_AuthorizeThread(t *testing.T) {
+	t.Run("should_return_post_of_readable_channel", func(t *testing.T) {
+		authorizer, api := setupAuthorizer()
+		api.On("GetPost", "reply1").Return(&model.Post{Id: "reply1", RootId: "root1", ChannelId: "channel1"}, nil)
+		api.On("GetChannelMember", "channel1", "user1").Return(&model.ChannelMember{}, nil)
+
+		post, err := authorizer.AuthorizeThread("user1", "reply1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "root1", post.RootId)
+	})
+
+	t.Run("should_forbid_post_of_unreadable_channel", func(t *testing.T) {
+		authorizer, api := setupAuthorizer()
+		api.On("GetPost", "root1").Return(&model.Post{Id: "root1", ChannelId: "channel1"}, nil)
+		api.On("GetChannelMember", "channel1", "user1").Return(nil, notFound())
+		api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1", Type: model.ChannelTypePrivate}, nil)
+		api.On("HasPermissionToChannel", "user1", "channel1", model.PermissionReadChannel).Return(false)
+
+		_, err := authorizer.AuthorizeThread("user1", "root1")
+
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("should_fail_for_unknown_post", func(t *testing.T) {
+		authorizer, api := setupAuthorizer()
+		api.On("GetPost", "root1").Return(nil, notFound())
+
+		_, err := authorizer.AuthorizeThread("user1", "root1")
+
+		assert.ErrorIs(t, err, pluginapi.ErrNotFound)
+	})
+}
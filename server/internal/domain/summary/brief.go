@@ -0,0 +1,35 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// GenerateBrief summarizes posts in a few sentences for the personal digest.
+// A brief is a single request: when the posts do not fit into the model
+// context only the newest of them are taken into account. Citations the model
+// adds anyway are linked like in summaries.
+func (s Service) GenerateBrief(ctx context.Context, posts []*model.Post, params Params) (string, error) {
+	language := s.resolveLanguage(params.Language, posts)
+	refs := citations{}
+	lines, _ := conversation{
+		users:       s.userProvider,
+		labels:      builtinPrompts[language].labels,
+		location:    loadLocation(params.Timezone),
+		attachments: s.loadAttachments(ctx, posts, false),
+		refs:        refs,
+	}.lines(posts)
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no messages")
+	}
+
+	chunks := splitIntoChunks(lines, chunkBudget(s.llm.ContextSize()))
+	brief, err := s.generate(ctx, fmt.Sprintf(builtinPrompts[language].brief, chunks[len(chunks)-1]))
+	if err != nil {
+		return "", err
+	}
+	return s.linkText(strings.TrimSpace(brief), refs), nil
+}
@@ -0,0 +1,53 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_GenerateBrief(t *testing.T) {
+	users := fakeUsers{"u1": {FirstName: "Ivan"}}
+
+	t.Run("should_use_brief_prompt_in_requested_language", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users, WithLanguage(LanguageRussian))
+
+		brief, err := service.GenerateBrief(context.Background(),
+			[]*model.Post{{UserId: "u1", Message: "release moved to friday"}}, Params{Language: LanguageEnglish})
+
+		require.NoError(t, err)
+		assert.Equal(t, "summary", brief)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "1–3 sentences")
+		assert.Contains(t, llm.prompts[0], "release moved to friday")
+	})
+
+	t.Run("should_keep_newest_posts_when_conversation_does_not_fit", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 1000}
+		posts := newPosts(30, 300)
+		posts[0].Message = "oldest"
+		posts[len(posts)-1].Message = "newest"
+		service := NewService(llm, users, WithLanguage(LanguageEnglish))
+
+		_, err := service.GenerateBrief(context.Background(), posts, Params{})
+
+		require.NoError(t, err)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "newest")
+		assert.NotContains(t, llm.prompts[0], "oldest")
+	})
+
+	t.Run("should_fail_without_messages", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users)
+
+		_, err := service.GenerateBrief(context.Background(), nil, Params{})
+
+		require.Error(t, err)
+		assert.Empty(t, llm.prompts)
+	})
+}
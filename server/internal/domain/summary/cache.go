@@ -0,0 +1,74 @@
+package summary
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// promptVersion identifies the built-in prompts. Changing any of them
+// invalidates previously cached summaries.
+var promptVersion = func() string {
+	var prompts []string
+	for _, language := range []string{LanguageEnglish, LanguageRussian, LanguageSpanish, LanguageFrench, LanguageGerman} {
+		p := builtinPrompts[language]
+		prompts = append(prompts, p.chunk, p.merge, p.part, p.citations, p.shorter, p.longer, p.final, p.structured,
+			p.labels.threadStart, p.labels.reply, p.labels.replyTo, p.labels.edited,
+			p.labels.file, p.labels.truncated, p.labels.image, p.labels.bot)
+	}
+	return hashStrings(prompts...)
+}()
+
+// cacheScope returns the ID invalidation is tracked by: the thread root ID
+// when all posts belong to one thread, the channel ID otherwise.
+func cacheScope(posts []*model.Post) string {
+	if len(posts) == 0 {
+		return ""
+	}
+
+	rootID := posts[0].RootId
+	if rootID == "" {
+		rootID = posts[0].Id
+	}
+	for _, post := range posts {
+		if post.Id != rootID && post.RootId != rootID {
+			return posts[0].ChannelId
+		}
+	}
+	return rootID
+}
+
+// cacheFingerprint identifies the summarized post range together with the
+// model and prompts used. It does not depend on the order of posts.
+func (s Service) cacheFingerprint(p prompt, posts []*model.Post) string {
+	firstCreateAt, lastCreateAt := postsTimeRange(posts)
+
+	var lastUpdateAt int64
+	for _, post := range posts {
+		lastUpdateAt = max(lastUpdateAt, post.UpdateAt)
+	}
+
+	return hashStrings(
+		fmt.Sprintf("%d:%d:%d:%d", len(posts), firstCreateAt, lastCreateAt, lastUpdateAt),
+		s.llm.Model(),
+		promptVersion,
+		p.source(),
+		p.data.Language,
+		p.location.String(),
+		"length:"+p.length,
+		fmt.Sprintf("structured:%t", s.structured),
+		fmt.Sprintf("attachments:%t", s.files != nil),
+		fmt.Sprintf("images:%d", len(p.attachments.images)),
+	)
+}
+
+func hashStrings(values ...string) string {
+	h := sha256.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
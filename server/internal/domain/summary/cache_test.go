@@ -0,0 +1,146 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type latestEntry struct {
+	summary    string
+	lastPostAt int64
+}
+
+type memCache struct {
+	summaries map[string]string
+	latest    map[string]latestEntry
+}
+
+func newMemCache() *memCache {
+	return &memCache{summaries: map[string]string{}, latest: map[string]latestEntry{}}
+}
+
+func (c *memCache) GetCachedSummary(scopeID, fingerprint string) (string, error) {
+	return c.summaries[scopeID+"/"+fingerprint], nil
+}
+
+func (c *memCache) SetCachedSummary(scopeID, fingerprint, summary string) error {
+	c.summaries[scopeID+"/"+fingerprint] = summary
+	return nil
+}
+
+func (c *memCache) GetLatestSummary(userID, channelID string) (string, int64, error) {
+	entry := c.latest[userID+"/"+channelID]
+	return entry.summary, entry.lastPostAt, nil
+}
+
+func (c *memCache) SetLatestSummary(userID, channelID, summary string, lastPostAt int64) error {
+	c.latest[userID+"/"+channelID] = latestEntry{summary: summary, lastPostAt: lastPostAt}
+	return nil
+}
+
+func TestCacheScope(t *testing.T) {
+	thread := []*model.Post{
+		{Id: "root", ChannelId: "channel"},
+		{Id: "reply1", RootId: "root", ChannelId: "channel"},
+		{Id: "reply2", RootId: "root", ChannelId: "channel"},
+	}
+	assert.Equal(t, "root", cacheScope(thread))
+
+	channel := []*model.Post{
+		{Id: "root", ChannelId: "channel"},
+		{Id: "other", ChannelId: "channel"},
+	}
+	assert.Equal(t, "channel", cacheScope(channel))
+}
+
+func TestService_Cache(t *testing.T) {
+	users := fakeUsers{"u1": {FirstName: "Ivan"}}
+	posts := func() []*model.Post {
+		return []*model.Post{
+			{Id: "p1", ChannelId: "c1", UserId: "u1", Message: "hi", CreateAt: 1, UpdateAt: 1},
+			{Id: "p2", ChannelId: "c1", UserId: "u1", Message: "hello", CreateAt: 2, UpdateAt: 2},
+		}
+	}
+
+	t.Run("should_reuse_cached_summary", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		cache := newMemCache()
+		service := NewService(llm, users, WithCache(cache))
+
+		first, err := service.GenerateSummary(context.Background(), posts())
+		require.NoError(t, err)
+
+		var chunks []string
+		second, err := service.GenerateSummaryStream(context.Background(), posts(), Params{}, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, first, second)
+		assert.Equal(t, []string{first.Text}, chunks)
+		assert.Len(t, llm.prompts, 1)
+		assert.Len(t, cache.summaries, 1)
+	})
+
+	t.Run("should_miss_when_a_post_was_edited", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users, WithCache(newMemCache()))
+
+		_, err := service.GenerateSummary(context.Background(), posts())
+		require.NoError(t, err)
+
+		edited := posts()
+		edited[0].Message = "hi there"
+		edited[0].UpdateAt = 3
+		_, err = service.GenerateSummary(context.Background(), edited)
+		require.NoError(t, err)
+
+		assert.Len(t, llm.prompts, 2)
+	})
+
+	t.Run("should_regenerate_fresh_summary", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		cache := newMemCache()
+		service := NewService(llm, users, WithCache(cache))
+
+		_, err := service.GenerateSummary(context.Background(), posts())
+		require.NoError(t, err)
+		_, err = service.GenerateSummaryStream(context.Background(), posts(), Params{Fresh: true}, nil)
+		require.NoError(t, err)
+
+		assert.Len(t, llm.prompts, 2)
+		assert.Len(t, cache.summaries, 1, "the fresh summary replaces the cached one")
+	})
+
+	t.Run("should_cache_lengths_separately", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		cache := newMemCache()
+		service := NewService(llm, users, WithCache(cache), WithLanguage(LanguageEnglish))
+
+		for _, length := range []string{"", LengthShort, LengthLong} {
+			_, err := service.GenerateSummaryStream(context.Background(), posts(), Params{Length: length}, nil)
+			require.NoError(t, err)
+		}
+
+		require.Len(t, llm.prompts, 3)
+		assert.Len(t, cache.summaries, 3)
+		assert.NotContains(t, llm.prompts[0], builtinPrompts[LanguageEnglish].shorter)
+		assert.Contains(t, llm.prompts[1], builtinPrompts[LanguageEnglish].shorter)
+		assert.Contains(t, llm.prompts[2], builtinPrompts[LanguageEnglish].longer)
+	})
+
+	t.Run("should_not_cache_failures", func(t *testing.T) {
+		cache := newMemCache()
+		service := NewService(&fakeLLM{contextSize: 64000, err: assert.AnError}, users, WithCache(cache))
+
+		_, err := service.GenerateSummary(context.Background(), posts())
+
+		assert.Error(t, err)
+		assert.Empty(t, cache.summaries)
+	})
+}
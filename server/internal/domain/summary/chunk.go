@@ -0,0 +1,88 @@
+package summary
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// runesPerToken is a conservative estimate of characters per token. Real
+	// tokenizers average ~4 characters for English and closer to 3 for Cyrillic,
+	// so using 3 keeps chunks safely below the model limit for both.
+	runesPerToken = 3
+
+	// responseReserveRatio is the share of the context window kept free for the
+	// prompt template and the model's answer.
+	responseReserveRatio = 4
+
+	// minChunkTokens guards against degenerate budgets on very small models.
+	minChunkTokens = 256
+)
+
+// estimateTokens approximates the number of tokens the model needs for text.
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + runesPerToken - 1) / runesPerToken
+}
+
+// chunkBudget returns the number of conversation tokens that fit into a single
+// prompt for a model with the given context size.
+func chunkBudget(contextSize int) int {
+	budget := contextSize - contextSize/responseReserveRatio
+	if budget < minChunkTokens {
+		return minChunkTokens
+	}
+	return budget
+}
+
+// splitIntoChunks groups lines into chunks whose estimated size does not exceed
+// budget tokens. Lines are never reordered; a single line larger than the
+// budget is split on rune boundaries.
+func splitIntoChunks(lines []string, budget int) []string {
+	var (
+		chunks  []string
+		current strings.Builder
+		used    int
+	)
+
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			used = 0
+		}
+	}
+
+	for _, line := range lines {
+		for _, part := range splitLine(line, budget) {
+			tokens := estimateTokens(part) + 1 // account for the line separator
+			if used+tokens > budget {
+				flush()
+			}
+			if current.Len() > 0 {
+				current.WriteByte('\n')
+			}
+			current.WriteString(part)
+			used += tokens
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// splitLine cuts a line that does not fit into budget tokens into smaller parts.
+func splitLine(line string, budget int) []string {
+	if estimateTokens(line)+1 <= budget {
+		return []string{line}
+	}
+
+	maxRunes := (budget - 1) * runesPerToken
+	runes := []rune(line)
+	parts := make([]string, 0, len(runes)/maxRunes+1)
+	for len(runes) > 0 {
+		n := min(maxRunes, len(runes))
+		parts = append(parts, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return parts
+}
@@ -0,0 +1,60 @@
+package summary
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, estimateTokens(""))
+	assert.Equal(t, 1, estimateTokens("ab"))
+	assert.Equal(t, 2, estimateTokens("abcd"))
+	assert.Equal(t, 2, estimateTokens("приве"))
+}
+
+func TestChunkBudget(t *testing.T) {
+	assert.Equal(t, 48000, chunkBudget(64000))
+	assert.Equal(t, minChunkTokens, chunkBudget(100))
+}
+
+func TestSplitIntoChunks(t *testing.T) {
+	t.Run("should_keep_everything_in_one_chunk_when_it_fits", func(t *testing.T) {
+		chunks := splitIntoChunks([]string{"a: hi", "b: hello"}, 100)
+
+		require.Len(t, chunks, 1)
+		assert.Equal(t, "a: hi\nb: hello", chunks[0])
+	})
+
+	t.Run("should_split_lines_preserving_order", func(t *testing.T) {
+		lines := []string{
+			strings.Repeat("a", 30),
+			strings.Repeat("b", 30),
+			strings.Repeat("c", 30),
+		}
+
+		chunks := splitIntoChunks(lines, 25)
+
+		require.Len(t, chunks, 2)
+		assert.Equal(t, lines[0]+"\n"+lines[1], chunks[0])
+		assert.Equal(t, lines[2], chunks[1])
+	})
+
+	t.Run("should_split_line_larger_than_budget", func(t *testing.T) {
+		line := strings.Repeat("я", 100)
+
+		chunks := splitIntoChunks([]string{line}, 10)
+
+		require.Len(t, chunks, 4)
+		assert.Equal(t, line, strings.Join(chunks, ""))
+		for _, chunk := range chunks {
+			assert.LessOrEqual(t, estimateTokens(chunk)+1, 10)
+		}
+	})
+
+	t.Run("should_return_nothing_for_no_lines", func(t *testing.T) {
+		assert.Empty(t, splitIntoChunks(nil, 10))
+	})
+}
@@ -0,0 +1,107 @@
+package summary
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// citationPattern matches a citation such as [3] or [3, 7] together with the
+// space before it, and citations that are already links, e.g. copied from a
+// previous summary, which are kept as they are.
+var citationPattern = regexp.MustCompile(` ?\[\[\d+\]\]\([^)\s]*\)| ?\[\d+(?:\s*,\s*\d+)*\]`)
+
+// citations maps the reference numbers of the messages in a prompt to the
+// IDs of their posts. Numbers start at 1 and follow the order of the posts.
+type citations map[int]string
+
+// add numbers the next message and returns its reference number.
+func (c citations) add(post *model.Post) int {
+	n := len(c) + 1
+	c[n] = post.Id
+	return n
+}
+
+// citationsOf numbers posts the way conversation.lines does.
+func citationsOf(posts []*model.Post) citations {
+	refs := citations{}
+	for _, post := range conversationPosts(posts) {
+		refs.add(post)
+	}
+	return refs
+}
+
+// omitted reports posts that are left out of the conversation: deleted posts
+// without a message.
+func omitted(post *model.Post) bool {
+	return post.DeleteAt != 0 && post.Message == ""
+}
+
+// linkCitations replaces the citations in the summary with permalinks to the
+// cited posts. References that do not match a message of the prompt were
+// made up by the model and are removed.
+func (s Service) linkCitations(summary Summary, refs citations) Summary {
+	summary.Text = s.linkText(summary.Text, refs)
+	if summary.Structured == nil {
+		return summary
+	}
+
+	structured := *summary.Structured
+	structured.Overview = s.linkText(structured.Overview, refs)
+	structured.Decisions = s.linkList(structured.Decisions, refs)
+	structured.OpenQuestions = s.linkList(structured.OpenQuestions, refs)
+	structured.ActionItems = make([]ActionItem, 0, len(summary.Structured.ActionItems))
+	for _, item := range summary.Structured.ActionItems {
+		item.Task = s.linkText(item.Task, refs)
+		structured.ActionItems = append(structured.ActionItems, item)
+	}
+	summary.Structured = &structured
+	return summary
+}
+
+func (s Service) linkList(items []string, refs citations) []string {
+	linked := make([]string, 0, len(items))
+	for _, item := range items {
+		linked = append(linked, s.linkText(item, refs))
+	}
+	return linked
+}
+
+func (s Service) linkText(text string, refs citations) string {
+	return citationPattern.ReplaceAllStringFunc(text, func(match string) string {
+		citation := strings.TrimPrefix(match, " ")
+		if strings.HasPrefix(citation, "[[") {
+			return match
+		}
+
+		var links strings.Builder
+		for _, number := range strings.Split(strings.Trim(citation, "[]"), ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(number))
+			if err != nil {
+				continue
+			}
+			if postID, ok := refs[n]; ok {
+				fmt.Fprintf(&links, "[[%d]](%s)", n, s.permalink(postID))
+			}
+		}
+		if links.Len() == 0 {
+			return ""
+		}
+		return match[:len(match)-len(citation)] + links.String()
+	})
+}
+
+// permalink returns the link to a post, relative to the server if the site
+// URL is unknown.
+func (s Service) permalink(postID string) string {
+	var siteURL string
+	if s.config != nil {
+		if config := s.config.GetConfig(); config != nil && config.ServiceSettings.SiteURL != nil {
+			siteURL = strings.TrimSuffix(*config.ServiceSettings.SiteURL, "/")
+		}
+	}
+	return siteURL + "/_redirect/pl/" + postID
+}
@@ -0,0 +1,106 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConfig struct {
+	siteURL string
+}
+
+func (f fakeConfig) GetConfig() *model.Config {
+	return &model.Config{ServiceSettings: model.ServiceSettings{SiteURL: &f.siteURL}}
+}
+
+func TestService_linkText(t *testing.T) {
+	refs := citations{1: "post1", 3: "post3", 7: "post7"}
+
+	tests := []struct {
+		name     string
+		config   configProvider
+		text     string
+		expected string
+	}{
+		{
+			name:     "should_link_citation_to_permalink",
+			config:   fakeConfig{siteURL: "https://chat.example.com/"},
+			text:     "The release moves to Friday [3].",
+			expected: "The release moves to Friday [[3]](https://chat.example.com/_redirect/pl/post3).",
+		},
+		{
+			name:     "should_link_every_cited_message",
+			config:   fakeConfig{siteURL: "https://chat.example.com"},
+			text:     "Ivan will update the API [3, 7]",
+			expected: "Ivan will update the API [[3]](https://chat.example.com/_redirect/pl/post3)[[7]](https://chat.example.com/_redirect/pl/post7)",
+		},
+		{
+			name:     "should_drop_made_up_references",
+			text:     "Budget was approved [12]. Docs are pending [1, 42].",
+			expected: "Budget was approved. Docs are pending [[1]](/_redirect/pl/post1).",
+		},
+		{
+			name:     "should_keep_linked_citations",
+			text:     "Carried over [[5]](https://chat.example.com/_redirect/pl/old5) and new [1]",
+			expected: "Carried over [[5]](https://chat.example.com/_redirect/pl/old5) and new [[1]](/_redirect/pl/post1)",
+		},
+		{
+			name:     "should_leave_markdown_links_alone",
+			text:     "See [the plan](https://example.com) and [docs]",
+			expected: "See [the plan](https://example.com) and [docs]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&fakeLLM{}, fakeUsers{}, WithPermalinks(tt.config))
+
+			assert.Equal(t, tt.expected, service.linkText(tt.text, refs))
+		})
+	}
+}
+
+func TestService_GenerateSummary_citations(t *testing.T) {
+	users := fakeUsers{"u1": {FirstName: "Ivan", LastName: "Petrov"}}
+	posts := []*model.Post{
+		{Id: "post1", UserId: "u1", Message: "let's ship on Friday"},
+		{Id: "post2", UserId: "u1", DeleteAt: 1},
+		{Id: "post3", UserId: "u1", Message: "I'll update the API"},
+	}
+
+	t.Run("should_number_messages_and_ask_for_citations", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users, WithLanguage(LanguageEnglish))
+
+		_, err := service.GenerateSummary(context.Background(), posts)
+
+		require.NoError(t, err)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "[1] 1970-01-01 00:00 Ivan Petrov: let's ship on Friday")
+		assert.Contains(t, llm.prompts[0], "[2] 1970-01-01 00:00 Ivan Petrov: I'll update the API")
+		assert.Contains(t, llm.prompts[0], builtinPrompts[LanguageEnglish].citations)
+	})
+
+	t.Run("should_link_structured_citations", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000, answer: `{"overview": "Release planning [1].",
+			"action_items": [{"task": "Update API [2]", "assignee": "Ivan"}], "decisions": ["Ship on Friday [1, 9]"]}`}
+		service := NewService(llm, users, WithLanguage(LanguageEnglish), WithStructuredOutput(),
+			WithPermalinks(fakeConfig{siteURL: "https://chat.example.com"}), WithCache(newMemCache()))
+
+		for i := 0; i < 2; i++ {
+			summary, err := service.GenerateSummary(context.Background(), posts)
+
+			require.NoError(t, err)
+			require.NotNil(t, summary.Structured)
+			assert.Equal(t, "Release planning [[1]](https://chat.example.com/_redirect/pl/post1).", summary.Structured.Overview)
+			assert.Equal(t, []string{"Ship on Friday [[1]](https://chat.example.com/_redirect/pl/post1)"}, summary.Structured.Decisions)
+			assert.Equal(t, "Update API [[2]](https://chat.example.com/_redirect/pl/post3)", summary.Structured.ActionItems[0].Task)
+			assert.Contains(t, summary.Text, "Update API [[2]](https://chat.example.com/_redirect/pl/post3)")
+		}
+		assert.Len(t, llm.prompts, 1, "the second summary comes from the cache")
+	})
+}
@@ -2,6 +2,8 @@ package summary
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 
 	"github.com/mattermost/mattermost/server/public/model"
 )
@@ -9,8 +11,49 @@ import (
 type (
 	llm interface {
 		Generate(ctx context.Context, prompt string) (string, error)
+		GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string) error) (string, error)
+		GenerateJSON(ctx context.Context, prompt string, schema json.RawMessage) (string, error)
+		Model() string
+		ContextSize() int
+	}
+	// imageGenerator is implemented by models that may accept images, see
+	// llm.ImageProvider.
+	imageGenerator interface {
+		SupportsImages(ctx context.Context) (bool, error)
+		GenerateWithImages(ctx context.Context, prompt string, images [][]byte, onChunk func(chunk string) error) (string, error)
 	}
 	userProvider interface {
 		Get(userID string) (*model.User, error)
+		GetByUsername(username string) (*model.User, error)
+	}
+	channelProvider interface {
+		Get(channelID string) (*model.Channel, error)
+	}
+	channelMembers interface {
+		Get(channelID string) (*model.Channel, error)
+		GetMember(channelID, userID string) (*model.ChannelMember, error)
+	}
+	postProvider interface {
+		GetPost(postID string) (*model.Post, error)
+	}
+	permissionChecker interface {
+		HasPermissionToTeam(userID, teamID string, permission *model.Permission) bool
+		HasPermissionToChannel(userID, channelID string, permission *model.Permission) bool
+	}
+	fileProvider interface {
+		GetInfo(fileID string) (*model.FileInfo, error)
+		Get(fileID string) (io.Reader, error)
+	}
+	configProvider interface {
+		GetConfig() *model.Config
+	}
+	templateSelections interface {
+		GetPromptTemplate(scopeID string) (string, error)
+	}
+	cache interface {
+		GetCachedSummary(scopeID, fingerprint string) (string, error)
+		SetCachedSummary(scopeID, fingerprint, summary string) error
+		GetLatestSummary(userID, channelID string) (summary string, lastPostAt int64, err error)
+		SetLatestSummary(userID, channelID, summary string, lastPostAt int64) error
 	}
 )
@@ -0,0 +1,192 @@
+package summary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// timestampLayout is the format of message times in the rendered conversation.
+const timestampLayout = "2006-01-02 15:04"
+
+// conversationLabels are the localized marks of root posts, replies, edited
+// messages, integrations and attached files in the rendered conversation.
+type conversationLabels struct {
+	// threadStart marks a root post whose replies are in the conversation.
+	threadStart string
+	// reply marks a reply whose root post is not in the conversation.
+	reply string
+	// replyTo marks a reply and takes the number of its root post.
+	replyTo string
+	// edited follows the message of an edited post.
+	edited string
+	// file introduces an attached file.
+	file string
+	// truncated follows an excerpt that does not cover the whole file.
+	truncated string
+	// image numbers an image passed along with the prompt.
+	image string
+	// bot follows the name of an integration, such as a bot or a webhook.
+	bot string
+}
+
+// OrderedPosts returns the posts of list oldest first. Posts are taken in the
+// order of list.Order, newest first, and sorted by creation time, so posts
+// created in the same millisecond keep their order.
+func OrderedPosts(list *model.PostList) []*model.Post {
+	if list == nil {
+		return nil
+	}
+
+	posts := make([]*model.Post, 0, len(list.Order))
+	seen := make(map[string]struct{}, len(list.Order))
+	for i := len(list.Order) - 1; i >= 0; i-- {
+		post, ok := list.Posts[list.Order[i]]
+		if _, listed := seen[list.Order[i]]; !ok || listed {
+			continue
+		}
+		seen[post.Id] = struct{}{}
+		posts = append(posts, post)
+	}
+	sortByCreateAt(posts)
+	return posts
+}
+
+// conversationPosts returns the posts that make up the conversation oldest
+// first. Previous versions of edited posts, which keep the ID of the edited
+// post in OriginalId, and deleted posts without a message are left out.
+func conversationPosts(posts []*model.Post) []*model.Post {
+	result := make([]*model.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.OriginalId != "" || omitted(post) {
+			continue
+		}
+		result = append(result, post)
+	}
+	sortByCreateAt(result)
+	return result
+}
+
+func sortByCreateAt(posts []*model.Post) {
+	sort.SliceStable(posts, func(i, j int) bool {
+		return posts[i].CreateAt < posts[j].CreateAt
+	})
+}
+
+// conversation renders posts for a prompt.
+type conversation struct {
+	users    userProvider
+	labels   conversationLabels
+	location *time.Location
+	// attachments are the files attached to the posts.
+	attachments attachments
+	// refs numbers the rendered messages for citations.
+	refs citations
+}
+
+// newConversation returns the renderer for the prompt p, numbering messages
+// in refs.
+func (s Service) newConversation(p prompt, refs citations) conversation {
+	return conversation{
+		users:       s.userProvider,
+		labels:      p.builtin.labels,
+		location:    p.location,
+		attachments: p.attachments,
+		refs:        refs,
+	}
+}
+
+// lines renders the posts oldest first, one message per line as
+// "[n] time author, mark: message", where the mark tells thread roots and
+// replies apart. The message includes the text of message attachments of
+// integrations. Continuation lines of a message and the attached files are
+// indented. It returns the names of the authors in order of first appearance.
+func (c conversation) lines(posts []*model.Post) (lines, participants []string) {
+	posts = conversationPosts(posts)
+
+	replied := make(map[string]bool)
+	for _, post := range posts {
+		if post.RootId != "" {
+			replied[post.RootId] = true
+		}
+	}
+
+	lines = make([]string, 0, len(posts))
+	numbers := make(map[string]int, len(posts))
+	seen := make(map[string]struct{})
+	for _, post := range posts {
+		source, name := c.author(post)
+		n := c.refs.add(post)
+		numbers[post.Id] = n
+
+		header := fmt.Sprintf("[%d] %s %s", n, time.UnixMilli(post.CreateAt).In(c.location).Format(timestampLayout), source)
+		switch {
+		case post.RootId == "" && replied[post.Id]:
+			header += ", " + c.labels.threadStart
+		case post.RootId != "":
+			if root, ok := numbers[post.RootId]; ok {
+				header += ", " + fmt.Sprintf(c.labels.replyTo, root)
+			} else {
+				header += ", " + c.labels.reply
+			}
+		}
+
+		message := strings.ReplaceAll(postText(post), "\n", "\n    ")
+		if post.EditAt != 0 {
+			message += " " + c.labels.edited
+		}
+		for _, file := range c.attachments.render(post.Id, c.labels) {
+			if message != "" {
+				message += "\n    "
+			}
+			message += strings.ReplaceAll(file, "\n", "\n    ")
+		}
+		lines = append(lines, header+": "+message)
+
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			participants = append(participants, name)
+		}
+	}
+	return lines, participants
+}
+
+// author returns how the author of a post is shown in the conversation, with
+// the position if there is one, and the name listed among the participants.
+// Integrations are shown under the name they post as, marked as such.
+func (c conversation) author(post *model.Post) (source, name string) {
+	name = overrideUsername(post)
+	user, err := c.users.Get(post.UserId)
+	switch {
+	case name != "":
+	case err != nil || user == nil:
+		name = "unknown user"
+	default:
+		name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		if name == "" {
+			name = user.Username
+		}
+	}
+
+	switch {
+	case IsBotPost(post):
+		return fmt.Sprintf("%s (%s)", name, c.labels.bot), name
+	case user == nil || user.Position == "" || err != nil:
+		return name, name
+	default:
+		return fmt.Sprintf("%s (%s)", name, user.Position), name
+	}
+}
+
+// loadLocation returns the time zone with the given IANA name, UTC when the
+// name is empty or unknown.
+func loadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
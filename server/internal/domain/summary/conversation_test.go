@@ -0,0 +1,198 @@
+package summary
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPostList builds a post list the way the server returns it: Order lists
+// the posts newest first.
+func newPostList(posts ...*model.Post) *model.PostList {
+	list := model.NewPostList()
+	for _, post := range posts {
+		list.AddPost(post)
+		list.AddOrder(post.Id)
+	}
+	list.SortByCreateAt()
+	return list
+}
+
+// at returns the time in milliseconds of a UTC time on 14 March 2025.
+func at(hour, minute int) int64 {
+	return time.Date(2025, time.March, 14, hour, minute, 0, 0, time.UTC).UnixMilli()
+}
+
+func TestOrderedPosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		list     *model.PostList
+		expected []string
+	}{
+		{
+			name:     "should_return_nil_for_nil_list",
+			list:     nil,
+			expected: nil,
+		},
+		{
+			name: "should_order_posts_oldest_first",
+			list: newPostList(
+				&model.Post{Id: "p2", CreateAt: at(10, 5)},
+				&model.Post{Id: "p3", CreateAt: at(10, 7)},
+				&model.Post{Id: "p1", CreateAt: at(10, 1)},
+			),
+			expected: []string{"p1", "p2", "p3"},
+		},
+		{
+			name: "should_follow_order_for_posts_created_together",
+			list: &model.PostList{
+				Order: []string{"p3", "p2", "p1"},
+				Posts: map[string]*model.Post{
+					"p1": {Id: "p1", CreateAt: at(10, 1)},
+					"p2": {Id: "p2", CreateAt: at(10, 1)},
+					"p3": {Id: "p3", CreateAt: at(10, 1)},
+				},
+			},
+			expected: []string{"p1", "p2", "p3"},
+		},
+		{
+			name: "should_skip_repeated_and_missing_posts",
+			list: &model.PostList{
+				Order: []string{"p2", "gone", "p1", "p2"},
+				Posts: map[string]*model.Post{
+					"p1":   {Id: "p1", CreateAt: at(10, 1)},
+					"p2":   {Id: "p2", CreateAt: at(10, 2)},
+					"root": {Id: "root", CreateAt: at(9, 0)},
+				},
+			},
+			expected: []string{"p1", "p2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, post := range OrderedPosts(tt.list) {
+				ids = append(ids, post.Id)
+			}
+
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+}
+
+func TestConversation_lines(t *testing.T) {
+	users := fakeUsers{
+		"u1": {FirstName: "Ivan", LastName: "Petrov", Position: "Developer"},
+		"u2": {Username: "anna"},
+	}
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name                 string
+		list                 *model.PostList
+		location             *time.Location
+		expected             []string
+		expectedParticipants []string
+	}{
+		{
+			name: "should_render_times_in_channel_time_zone",
+			list: newPostList(
+				&model.Post{Id: "p2", UserId: "u2", Message: "hi", CreateAt: at(21, 30)},
+				&model.Post{Id: "p1", UserId: "u1", Message: "hello", CreateAt: at(21, 0)},
+			),
+			location: moscow,
+			expected: []string{
+				"[1] 2025-03-15 00:00 Ivan Petrov (Developer): hello",
+				"[2] 2025-03-15 00:30 anna: hi",
+			},
+			expectedParticipants: []string{"Ivan Petrov", "anna"},
+		},
+		{
+			name: "should_mark_thread_roots_and_replies",
+			list: newPostList(
+				&model.Post{Id: "root", UserId: "u1", Message: "db is down", CreateAt: at(10, 0)},
+				&model.Post{Id: "other", UserId: "u2", Message: "lunch?", CreateAt: at(10, 1)},
+				&model.Post{Id: "reply", UserId: "u2", RootId: "root", Message: "looking", CreateAt: at(10, 2)},
+				&model.Post{Id: "late", UserId: "u2", RootId: "old", Message: "still broken", CreateAt: at(10, 3)},
+			),
+			location: time.UTC,
+			expected: []string{
+				"[1] 2025-03-14 10:00 Ivan Petrov (Developer), thread start: db is down",
+				"[2] 2025-03-14 10:01 anna: lunch?",
+				"[3] 2025-03-14 10:02 anna, reply to [1]: looking",
+				"[4] 2025-03-14 10:03 anna, reply in a thread: still broken",
+			},
+			expectedParticipants: []string{"Ivan Petrov", "anna"},
+		},
+		{
+			name: "should_collapse_edits",
+			list: newPostList(
+				&model.Post{Id: "p1", UserId: "u1", Message: "ship on Friday", CreateAt: at(10, 0), EditAt: at(10, 5)},
+				&model.Post{Id: "v1", UserId: "u1", OriginalId: "p1", Message: "ship on Thursday", CreateAt: at(10, 0), DeleteAt: at(10, 5)},
+				&model.Post{Id: "p2", UserId: "u2", Message: "", CreateAt: at(10, 1), DeleteAt: at(10, 2)},
+			),
+			location: time.UTC,
+			expected: []string{
+				"[1] 2025-03-14 10:00 Ivan Petrov (Developer): ship on Friday (edited)",
+			},
+			expectedParticipants: []string{"Ivan Petrov"},
+		},
+		{
+			name: "should_indent_continuation_lines",
+			list: newPostList(
+				&model.Post{Id: "p1", UserId: "u3", Message: "steps:\n1. build\n2. deploy\n", CreateAt: at(10, 0)},
+			),
+			location: time.UTC,
+			expected: []string{
+				"[1] 2025-03-14 10:00 unknown user: steps:\n    1. build\n    2. deploy",
+			},
+			expectedParticipants: []string{"unknown user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs := citations{}
+			c := conversation{users: users, labels: builtinPrompts[LanguageEnglish].labels, location: tt.location, refs: refs}
+
+			lines, participants := c.lines(OrderedPosts(tt.list))
+
+			assert.Equal(t, tt.expected, lines)
+			assert.Equal(t, tt.expectedParticipants, participants)
+			assert.Equal(t, citationsOf(OrderedPosts(tt.list)), refs, "citations number the rendered messages")
+		})
+	}
+}
+
+func TestService_GenerateSummaryStream_timezone(t *testing.T) {
+	users := fakeUsers{"u1": {FirstName: "Ivan", LastName: "Petrov"}}
+	posts := []*model.Post{{Id: "p1", UserId: "u1", Message: "hello", CreateAt: at(21, 0)}}
+
+	tests := []struct {
+		name     string
+		timezone string
+		expected string
+	}{
+		{name: "should_use_timezone_of_params", timezone: "Asia/Tokyo", expected: "[1] 2025-03-15 06:00 Ivan Petrov: hello"},
+		{name: "should_fall_back_to_utc", timezone: "Mars/Olympus", expected: "[1] 2025-03-14 21:00 Ivan Petrov: hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &fakeLLM{contextSize: 64000}
+			service := NewService(llm, users, WithLanguage(LanguageEnglish))
+
+			_, err := service.GenerateSummaryStream(context.Background(), posts, Params{Timezone: tt.timezone}, nil)
+
+			require.NoError(t, err)
+			require.Len(t, llm.prompts, 1)
+			assert.Contains(t, llm.prompts[0], tt.expected)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package summary
+
+import (
+	"context"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// PostGroup is a titled part of a conversation gathered from several places,
+// e.g. one thread of a channel.
+type PostGroup struct {
+	Title string
+	Posts []*model.Post
+}
+
+// GenerateGroupedSummaryStream summarizes posts gathered from several
+// channels and threads in a single summary. Every group is introduced by its
+// title so that the model can tell the conversations apart, and messages are
+// numbered across groups. Grouped summaries are not cached.
+func (s Service) GenerateGroupedSummaryStream(ctx context.Context, groups []PostGroup, params Params, onChunk func(chunk string) error) (Summary, error) {
+	var posts []*model.Post
+	for _, group := range groups {
+		posts = append(posts, group.Posts...)
+	}
+	p := s.newPrompt(ctx, posts, params)
+
+	var lines, participants []string
+	refs := citations{}
+	seen := make(map[string]struct{})
+	for _, group := range groups {
+		groupLines, groupParticipants := s.newConversation(p, refs).lines(group.Posts)
+		if len(groupLines) == 0 {
+			continue
+		}
+		lines = append(lines, "=== "+group.Title+" ===")
+		lines = append(lines, groupLines...)
+		for _, name := range groupParticipants {
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				participants = append(participants, name)
+			}
+		}
+	}
+	summary, err := s.generateFromLines(ctx, p, "", lines, participants, onChunk)
+	if err != nil {
+		return Summary{}, err
+	}
+	summary.SkippedAttachments = p.attachments.skipped
+	return s.linkCitations(summary, refs), nil
+}
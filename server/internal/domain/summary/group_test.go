@@ -0,0 +1,63 @@
+package summary
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_GenerateGroupedSummaryStream(t *testing.T) {
+	users := fakeUsers{
+		"u1": {FirstName: "Ivan", LastName: "Petrov"},
+		"u2": {FirstName: "Anna", LastName: "Smirnova"},
+	}
+	onChunk := func(string) error { return nil }
+
+	t.Run("should_introduce_every_group_by_its_title", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users, WithLanguage(LanguageEnglish))
+
+		summary, err := service.GenerateGroupedSummaryStream(context.Background(), []PostGroup{
+			{Title: "~Incidents, thread: db is down", Posts: []*model.Post{{UserId: "u1", Message: "db is down"}}},
+			{Title: "~Support", Posts: []*model.Post{{UserId: "u2", Message: "customers report errors"}}},
+		}, Params{}, onChunk)
+
+		require.NoError(t, err)
+		assert.Equal(t, "summary", summary.Text)
+		require.Len(t, llm.prompts, 1)
+		incidents := strings.Index(llm.prompts[0], "=== ~Incidents, thread: db is down ===")
+		support := strings.Index(llm.prompts[0], "=== ~Support ===")
+		require.NotEqual(t, -1, incidents)
+		require.NotEqual(t, -1, support)
+		assert.Less(t, incidents, strings.Index(llm.prompts[0], "db is down\n"))
+		assert.Less(t, support, strings.Index(llm.prompts[0], "customers report errors"))
+	})
+
+	t.Run("should_skip_empty_groups", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users, WithLanguage(LanguageEnglish))
+
+		_, err := service.GenerateGroupedSummaryStream(context.Background(), []PostGroup{
+			{Title: "~Empty"},
+			{Title: "~Support", Posts: []*model.Post{{UserId: "u2", Message: "hello"}}},
+		}, Params{}, onChunk)
+
+		require.NoError(t, err)
+		require.Len(t, llm.prompts, 1)
+		assert.NotContains(t, llm.prompts[0], "~Empty")
+	})
+
+	t.Run("should_fail_without_messages", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users)
+
+		_, err := service.GenerateGroupedSummaryStream(context.Background(), []PostGroup{{Title: "~Empty"}}, Params{}, onChunk)
+
+		require.Error(t, err)
+		assert.Empty(t, llm.prompts)
+	})
+}
@@ -0,0 +1,114 @@
+package summary
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// postPropsFromPlugin marks posts created by plugins. The model package has
+// no constant for it.
+const postPropsFromPlugin = "from_plugin"
+
+// IsBotPost reports whether a post was made by an integration rather than a
+// person: a bot account, an incoming webhook or a plugin.
+func IsBotPost(post *model.Post) bool {
+	return propIsTrue(post, model.PostPropsFromBot) ||
+		propIsTrue(post, model.PostPropsFromWebhook) ||
+		propIsTrue(post, postPropsFromPlugin)
+}
+
+// propIsTrue reports whether a flag prop is set. Integrations store flags as
+// the string "true", some as a boolean.
+func propIsTrue(post *model.Post, key string) bool {
+	switch value := post.GetProp(key).(type) {
+	case string:
+		return value == "true"
+	case bool:
+		return value
+	default:
+		return false
+	}
+}
+
+// overrideUsername returns the name an integration post is shown under in
+// place of the name of its author, empty if there is none.
+func overrideUsername(post *model.Post) string {
+	if !IsBotPost(post) {
+		return ""
+	}
+	name, _ := post.GetProp(model.PostPropsOverrideUsername).(string)
+	return strings.TrimSpace(name)
+}
+
+// postText returns the text of a post: the message followed by the text of
+// its message attachments, which is where integrations such as CI and
+// alerting put most of their content.
+func postText(post *model.Post) string {
+	parts := []string{strings.TrimSpace(post.Message)}
+	for _, attachment := range post.Attachments() {
+		parts = append(parts, attachmentText(attachment))
+	}
+	return joinNonEmpty(parts, "\n")
+}
+
+// attachmentText flattens a message attachment to its pretext, author, title,
+// text and fields, one per line, with the names of interactive buttons. The
+// fallback is used for attachments without any of them.
+func attachmentText(attachment *model.SlackAttachment) string {
+	if attachment == nil {
+		return ""
+	}
+
+	parts := []string{attachment.Pretext, attachment.AuthorName, attachment.Title, attachment.Text}
+	for _, field := range attachment.Fields {
+		if field == nil {
+			continue
+		}
+		value := strings.TrimSpace(fieldValue(field.Value))
+		title := strings.TrimSpace(field.Title)
+		if title != "" && value != "" {
+			parts = append(parts, title+": "+value)
+		} else {
+			parts = append(parts, title+value)
+		}
+	}
+
+	var buttons []string
+	for _, action := range attachment.Actions {
+		if action != nil && action.Type != model.PostActionTypeSelect && strings.TrimSpace(action.Name) != "" {
+			buttons = append(buttons, "["+strings.TrimSpace(action.Name)+"]")
+		}
+	}
+	parts = append(parts, strings.Join(buttons, " "))
+
+	text := joinNonEmpty(parts, "\n")
+	if text == "" {
+		return strings.TrimSpace(attachment.Fallback)
+	}
+	return text
+}
+
+// fieldValue returns the value of an attachment field, which integrations
+// send as a string or as a number.
+func fieldValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func joinNonEmpty(parts []string, sep string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
@@ -0,0 +1,107 @@
+package summary
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBotPost(t *testing.T) {
+	tests := []struct {
+		name     string
+		props    model.StringInterface
+		expected bool
+	}{
+		{name: "should_detect_bot_accounts", props: model.StringInterface{model.PostPropsFromBot: "true"}, expected: true},
+		{name: "should_detect_webhooks", props: model.StringInterface{model.PostPropsFromWebhook: "true"}, expected: true},
+		{name: "should_detect_plugins", props: model.StringInterface{"from_plugin": true}, expected: true},
+		{name: "should_ignore_people", props: nil, expected: false},
+		{name: "should_ignore_unset_flags", props: model.StringInterface{model.PostPropsFromBot: "false"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &model.Post{}
+			post.SetProps(tt.props)
+
+			assert.Equal(t, tt.expected, IsBotPost(post))
+		})
+	}
+}
+
+func TestPostText(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		attachments []any
+		expected    string
+	}{
+		{
+			name:     "should_return_message_without_attachments",
+			message:  " deploy done ",
+			expected: "deploy done",
+		},
+		{
+			name:    "should_flatten_attachments",
+			message: "CI report",
+			attachments: []any{
+				map[string]any{
+					"pretext": "Pipeline #42",
+					"title":   "Build failed",
+					"text":    "tests failed in pkg/api",
+					"fields": []any{
+						map[string]any{"title": "Branch", "value": "main", "short": true},
+						map[string]any{"title": "Duration", "value": 93},
+					},
+					"actions": []any{
+						map[string]any{"name": "Retry", "type": "button"},
+						map[string]any{"name": "Assign", "type": "select"},
+					},
+				},
+			},
+			expected: "CI report\nPipeline #42\nBuild failed\ntests failed in pkg/api\nBranch: main\nDuration: 93\n[Retry]",
+		},
+		{
+			name:        "should_use_fallback_of_empty_attachments",
+			attachments: []any{map[string]any{"fallback": "Disk usage 91% on db-1", "color": "#ff0000"}},
+			expected:    "Disk usage 91% on db-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := &model.Post{Message: tt.message}
+			if tt.attachments != nil {
+				post.AddProp("attachments", tt.attachments)
+			}
+
+			assert.Equal(t, tt.expected, postText(post))
+		})
+	}
+}
+
+func TestConversation_lines_integrations(t *testing.T) {
+	users := fakeUsers{
+		"u1":  {FirstName: "Ivan", LastName: "Petrov", Position: "Developer"},
+		"bot": {Username: "alertbot", IsBot: true},
+	}
+	alert := &model.Post{Id: "p1", UserId: "bot", CreateAt: at(10, 0)}
+	alert.AddProp(model.PostPropsFromWebhook, "true")
+	alert.AddProp(model.PostPropsOverrideUsername, "Prometheus")
+	alert.AddProp("attachments", []*model.SlackAttachment{{Title: "HighLatency", Text: "p99 above 2s\non api-1"}})
+	ci := &model.Post{Id: "p2", UserId: "bot", Message: "build green", CreateAt: at(10, 1)}
+	ci.AddProp(model.PostPropsFromBot, "true")
+	reply := &model.Post{Id: "p3", UserId: "u1", Message: "looking", CreateAt: at(10, 2)}
+
+	c := conversation{users: users, labels: builtinPrompts[LanguageEnglish].labels, location: loadLocation(""), refs: citations{}}
+
+	lines, participants := c.lines([]*model.Post{alert, ci, reply})
+
+	require.Len(t, lines, 3)
+	assert.Equal(t, "[1] 2025-03-14 10:00 Prometheus (bot): HighLatency\n    p99 above 2s\n    on api-1", lines[0])
+	assert.Equal(t, "[2] 2025-03-14 10:01 alertbot (bot): build green", lines[1])
+	assert.Equal(t, "[3] 2025-03-14 10:02 Ivan Petrov (Developer): looking", lines[2])
+	assert.Equal(t, []string{"Prometheus", "alertbot", "Ivan Petrov"}, participants)
+}
@@ -0,0 +1,140 @@
+package summary
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// Supported summary languages.
+const (
+	LanguageAuto    = "auto"
+	LanguageEnglish = "en"
+	LanguageRussian = "ru"
+	LanguageSpanish = "es"
+	LanguageFrench  = "fr"
+	LanguageGerman  = "de"
+)
+
+// languageSampleRunes bounds how much of the conversation is inspected to
+// detect its language.
+const languageSampleRunes = 4000
+
+// languageNames are the names of the supported languages as written in the
+// language itself. They are available to prompt templates as .LanguageName.
+var languageNames = map[string]string{
+	LanguageEnglish: "English",
+	LanguageRussian: "Русский",
+	LanguageSpanish: "Español",
+	LanguageFrench:  "Français",
+	LanguageGerman:  "Deutsch",
+}
+
+// IsSupportedLanguage reports whether language is a supported language code
+// or LanguageAuto.
+func IsSupportedLanguage(language string) bool {
+	if language == LanguageAuto {
+		return true
+	}
+	_, ok := languageNames[language]
+	return ok
+}
+
+// stopwords are frequent short words that tell Latin-script languages apart.
+var stopwords = map[string][]string{
+	LanguageEnglish: {"the", "and", "is", "are", "to", "of", "in", "it", "that", "this", "we", "you", "for", "with", "not", "be", "have", "will", "can", "on"},
+	LanguageSpanish: {"el", "la", "los", "las", "que", "de", "y", "es", "en", "un", "una", "por", "para", "con", "no", "lo", "se", "del", "como", "pero"},
+	LanguageFrench:  {"le", "la", "les", "des", "et", "est", "que", "qui", "un", "une", "pour", "avec", "pas", "dans", "sur", "ce", "nous", "vous", "du", "mais"},
+	LanguageGerman:  {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "von", "ich", "wir", "sie", "es", "auf", "auch", "für", "aber"},
+}
+
+// diacritics are letters specific to one of the Latin-script languages.
+var diacritics = map[rune]string{
+	'ñ': LanguageSpanish, '¿': LanguageSpanish, '¡': LanguageSpanish,
+	'ç': LanguageFrench, 'è': LanguageFrench, 'ê': LanguageFrench, 'à': LanguageFrench, 'œ': LanguageFrench,
+	'ß': LanguageGerman, 'ä': LanguageGerman, 'ö': LanguageGerman, 'ü': LanguageGerman,
+}
+
+var stopwordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for language, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], language)
+		}
+	}
+	return index
+}()
+
+// DetectLanguage guesses the language of text without calling the model.
+// Cyrillic text is treated as Russian; Latin-script languages are told apart
+// by their most frequent words and specific letters. Text without any hints
+// is reported as English.
+func DetectLanguage(text string) string {
+	text = strings.ToLower(text)
+
+	var cyrillic, latin int
+	scores := make(map[string]int)
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+		if language, ok := diacritics[r]; ok {
+			scores[language] += 2
+		}
+	}
+	if cyrillic > latin {
+		return LanguageRussian
+	}
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		for _, language := range stopwordIndex[word] {
+			scores[language]++
+		}
+	}
+
+	best, bestScore := LanguageEnglish, 0
+	for _, language := range []string{LanguageEnglish, LanguageSpanish, LanguageFrench, LanguageGerman} {
+		if scores[language] > bestScore {
+			best, bestScore = language, scores[language]
+		}
+	}
+	return best
+}
+
+// resolveLanguage returns the language summaries of posts are written in: the
+// requested one, the configured one, or the language detected in the posts.
+func (s Service) resolveLanguage(requested string, posts []*model.Post) string {
+	language := requested
+	if language == "" {
+		language = s.language
+	}
+	if _, ok := languageNames[language]; ok {
+		return language
+	}
+	return DetectLanguage(languageSample(posts))
+}
+
+func languageSample(posts []*model.Post) string {
+	var (
+		b     strings.Builder
+		runes int
+	)
+	for _, post := range posts {
+		if runes >= languageSampleRunes {
+			break
+		}
+		text := postText(post)
+		b.WriteString(text)
+		b.WriteByte('\n')
+		runes += utf8.RuneCountInString(text) + 1
+	}
+	return b.String()
+}
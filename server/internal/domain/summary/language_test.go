@@ -0,0 +1,103 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "should_detect_russian",
+			text:     "Привет! Давайте обсудим релиз в пятницу, нужно обновить API.",
+			expected: LanguageRussian,
+		},
+		{
+			name:     "should_detect_english",
+			text:     "We need to ship the release on Friday and it is not ready yet.",
+			expected: LanguageEnglish,
+		},
+		{
+			name:     "should_detect_spanish",
+			text:     "¿Podemos mover la reunión para el viernes? No tengo tiempo hoy y los datos no están listos.",
+			expected: LanguageSpanish,
+		},
+		{
+			name:     "should_detect_french",
+			text:     "Nous devons préparer la démo pour le client, mais les tests ne sont pas encore prêts.",
+			expected: LanguageFrench,
+		},
+		{
+			name:     "should_detect_german",
+			text:     "Wir müssen das Release auf Freitag verschieben, die Tests sind noch nicht fertig und ich bin auch krank.",
+			expected: LanguageGerman,
+		},
+		{
+			name:     "should_fall_back_to_english",
+			text:     "ok 👍 +1",
+			expected: LanguageEnglish,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectLanguage(tt.text))
+		})
+	}
+}
+
+func TestService_Language(t *testing.T) {
+	users := fakeUsers{"u1": {FirstName: "Ivan"}}
+	posts := []*model.Post{{UserId: "u1", Message: "Нужно выпустить релиз в пятницу"}}
+
+	tests := []struct {
+		name       string
+		configured string
+		requested  string
+		expected   string
+	}{
+		{
+			name:       "should_detect_language_in_auto_mode",
+			configured: LanguageAuto,
+			expected:   "ПЕРЕПИСКА:",
+		},
+		{
+			name:       "should_use_configured_language",
+			configured: LanguageGerman,
+			expected:   "GESPRÄCH:",
+		},
+		{
+			name:       "should_prefer_requested_language",
+			configured: LanguageGerman,
+			requested:  LanguageSpanish,
+			expected:   "CONVERSACIÓN:",
+		},
+		{
+			name:       "should_detect_requested_auto_language",
+			configured: LanguageFrench,
+			requested:  LanguageAuto,
+			expected:   "ПЕРЕПИСКА:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &fakeLLM{contextSize: 64000}
+			service := NewService(llm, users, WithLanguage(tt.configured))
+
+			_, err := service.GenerateSummaryStream(context.Background(), posts, Params{Language: tt.requested}, func(string) error { return nil })
+
+			require.NoError(t, err)
+			require.Len(t, llm.prompts, 1)
+			assert.Contains(t, llm.prompts[0], tt.expected)
+		})
+	}
+}
@@ -1,5 +1,17 @@
 package summary
 
+// Summary is a generated summary.
+type Summary struct {
+	// Text is the markdown shown to users.
+	Text string `json:"text"`
+	// Structured is the machine-readable form of the summary. It is only set
+	// when structured output is enabled.
+	Structured *StructuredSummary `json:"structured,omitempty"`
+	// SkippedAttachments are the attached files whose content the summary
+	// does not take into account.
+	SkippedAttachments []SkippedAttachment `json:"skipped_attachments,omitempty"`
+}
+
 type Post struct {
 	Message string
 	UserID  string
@@ -0,0 +1,489 @@
+package summary
+
+// localizedPrompts are the built-in prompts in one language.
+type localizedPrompts struct {
+	// chunk summarizes one part of a conversation that is too long for a
+	// single request (map step). It takes the part number, the number of
+	// parts and the conversation.
+	chunk string
+
+	// merge condenses several partial summaries into one when they still do
+	// not fit into a single final request (intermediate reduce step).
+	merge string
+
+	// part labels a numbered partial summary.
+	part string
+
+	// citations is appended to the chunk, merge and final prompts and asks
+	// the model to cite the numbered messages its statements are based on.
+	citations string
+
+	// shorter and longer are appended to the final prompt when a summary
+	// more concise or more detailed than usual is requested.
+	shorter string
+	longer  string
+
+	// labels mark root posts, replies, edited messages and attached files in
+	// the rendered conversation.
+	labels conversationLabels
+
+	// final is the built-in default template rendering the final summary
+	// request. It covers the three cases the service needs: a whole
+	// conversation, partial summaries of a long one and new messages on top
+	// of a previous summary.
+	final string
+
+	// structured is appended to the final prompt when the summary is
+	// requested as JSON. It describes the fields of StructuredSummary.
+	structured string
+
+	// sections are the headings of a rendered structured summary.
+	sections sectionTitles
+
+	// actions extracts action items from numbered messages. It takes the
+	// current date and the conversation.
+	actions string
+
+	// brief summarizes the unread messages of a channel in a few sentences
+	// for the personal digest. It takes the conversation.
+	brief string
+}
+
+// builtinPrompts holds the built-in prompts by language code.
+var builtinPrompts = map[string]localizedPrompts{
+	LanguageRussian: {
+		chunk: `Ниже приведена часть %d из %d длинной командной беседы.
+Кратко перечислите темы, принятые решения, поставленные задачи (с исполнителями и сроками) и участников этой части.
+Не добавляйте вступлений и выводов, сохраняйте имена и конкретные факты.
+
+ПЕРЕПИСКА:
+%s`,
+		merge: `Ниже приведены краткие резюме последовательных частей одной командной беседы.
+Объедините их в одно краткое резюме, сохранив темы, решения, задачи (с исполнителями и сроками) и участников.
+Не добавляйте вступлений и выводов.
+
+РЕЗЮМЕ ЧАСТЕЙ:
+%s`,
+		part:      "Часть %d:",
+		citations: `Сообщения пронумерованы в квадратных скобках. После каждого утверждения укажите номера сообщений, на которых оно основано, в том же виде, например [3] или [3, 7]. Ссылайтесь только на номера, которые есть выше, и сохраняйте ссылки, уже приведенные в резюме.`,
+		shorter:   `Сделайте резюме заметно короче обычного: только самое важное, не более трех-пяти пунктов.`,
+		longer:    `Сделайте резюме подробнее обычного: раскройте ход обсуждения, аргументы и детали каждого решения.`,
+		labels: conversationLabels{
+			threadStart: "начало треда",
+			reply:       "ответ в треде",
+			replyTo:     "ответ на [%d]",
+			edited:      "(изменено)",
+			file:        "вложение",
+			truncated:   "(обрезано)",
+			image:       "изображение %d",
+			bot:         "бот",
+		},
+		final: `{{if .PreviousSummary -}}
+Ниже приведено резюме предыдущей части командной беседы и новые сообщения, появившиеся после него.
+Составьте резюме, в котором главное внимание уделено новым сообщениям, а предыдущее резюме используется как контекст:
+
+ПРЕДЫДУЩЕЕ РЕЗЮМЕ:
+{{.PreviousSummary}}
+
+НОВЫЕ СООБЩЕНИЯ:
+{{.Conversation}}
+{{- else if .Partial -}}
+Ниже приведены краткие резюме последовательных частей одной командной беседы.
+Проанализируйте их и составьте единое итоговое резюме всей беседы:
+
+РЕЗЮМЕ ЧАСТЕЙ:
+{{.Conversation}}
+{{- else -}}
+Проанализируйте и обобщите следующую командную беседу:
+
+ПЕРЕПИСКА:
+{{.Conversation}}
+{{- end}}
+
+Структура резюме:
+• **Краткое содержание:** основные темы и направления обсуждения
+• **Ключевые решения:** принятые решения и достигнутые договоренности
+• **План действий:** поставленные задачи и сроки выполнения
+• **Участники:** активные участники и их роль в обсуждении
+
+Используйте четкое форматирование markdown. Ответ напишите на русском языке.`,
+		structured: `Вместо markdown ответьте одним JSON-объектом со следующими полями:
+- "overview": краткое содержание беседы, 2–4 предложения;
+- "decisions": список принятых решений;
+- "action_items": список задач, у каждой есть "task" (что сделать), "assignee" (исполнитель, как он назван в переписке, или пустая строка) и "due_date" (срок в формате ГГГГ-ММ-ДД или пустая строка);
+- "open_questions": список вопросов, оставшихся без ответа;
+- "participants": имена участников беседы.
+Пустые разделы оставьте пустыми списками. Значения напишите на русском языке.`,
+		sections: sectionTitles{
+			overview:      "Краткое содержание",
+			decisions:     "Ключевые решения",
+			actionItems:   "План действий",
+			openQuestions: "Открытые вопросы",
+			participants:  "Участники",
+			due:           "срок",
+		},
+		actions: `Сегодня %s. Ниже приведены пронумерованные сообщения командной беседы.
+Найдите все задачи, о которых договорились участники, и ответьте JSON-объектом с полем "action_items" - списком задач, у каждой есть:
+- "text": что нужно сделать, одним предложением;
+- "owner": имя пользователя исполнителя (@username из переписки) или пустая строка, если исполнитель не назначен;
+- "due_date": срок в формате ГГГГ-ММ-ДД (относительные сроки, например "в пятницу", отсчитывайте от даты сообщения) или пустая строка;
+- "source": номер сообщения, в котором появилась задача.
+Не придумывайте задачи, которых нет в переписке. Если задач нет, верните пустой список. Текст задач напишите на русском языке.
+
+ПЕРЕПИСКА:
+%s`,
+		brief: `Ниже приведены непрочитанные сообщения одного канала.
+Перескажите самое важное в 1–3 предложениях: о чем шла речь, что решили и что требует внимания читателя. Не добавляйте вступлений, заголовков и списков. Ответ напишите на русском языке.
+
+СООБЩЕНИЯ:
+%s`,
+	},
+	LanguageEnglish: {
+		chunk: `Below is part %d of %d of a long team conversation.
+Briefly list the topics, decisions made, assigned tasks (with owners and deadlines) and participants of this part.
+Do not add introductions or conclusions, keep names and specific facts.
+
+CONVERSATION:
+%s`,
+		merge: `Below are short summaries of consecutive parts of one team conversation.
+Combine them into one short summary, keeping the topics, decisions, tasks (with owners and deadlines) and participants.
+Do not add introductions or conclusions.
+
+PART SUMMARIES:
+%s`,
+		part:      "Part %d:",
+		citations: `Messages are numbered in square brackets. After each statement, cite the messages it is based on in the same form, e.g. [3] or [3, 7]. Only cite numbers that appear above and keep citations already present in the summaries.`,
+		shorter:   `Make the summary considerably shorter than usual: only the most important points, at most three to five.`,
+		longer:    `Make the summary more detailed than usual: cover the course of the discussion, the arguments and the details of each decision.`,
+		labels: conversationLabels{
+			threadStart: "thread start",
+			reply:       "reply in a thread",
+			replyTo:     "reply to [%d]",
+			edited:      "(edited)",
+			file:        "attached file",
+			truncated:   "(truncated)",
+			image:       "image %d",
+			bot:         "bot",
+		},
+		final: `{{if .PreviousSummary -}}
+Below is a summary of the earlier part of a team conversation and the new messages posted after it.
+Write a summary that focuses on the new messages and uses the previous summary as context:
+
+PREVIOUS SUMMARY:
+{{.PreviousSummary}}
+
+NEW MESSAGES:
+{{.Conversation}}
+{{- else if .Partial -}}
+Below are short summaries of consecutive parts of one team conversation.
+Analyze them and write a single final summary of the whole conversation:
+
+PART SUMMARIES:
+{{.Conversation}}
+{{- else -}}
+Analyze and summarize the following team conversation:
+
+CONVERSATION:
+{{.Conversation}}
+{{- end}}
+
+Summary structure:
+• **Overview:** main topics and directions of the discussion
+• **Key decisions:** decisions made and agreements reached
+• **Action items:** assigned tasks and deadlines
+• **Participants:** active participants and their role in the discussion
+
+Use clear markdown formatting. Write the answer in English.`,
+		structured: `Instead of markdown, answer with a single JSON object with the following fields:
+- "overview": a short summary of the conversation in 2-4 sentences;
+- "decisions": a list of the decisions made;
+- "action_items": a list of tasks, each with "task" (what to do), "assignee" (the owner as named in the conversation, or an empty string) and "due_date" (the deadline as YYYY-MM-DD, or an empty string);
+- "open_questions": a list of questions left unanswered;
+- "participants": the names of the conversation participants.
+Leave empty sections as empty lists. Write the values in English.`,
+		sections: sectionTitles{
+			overview:      "Overview",
+			decisions:     "Key decisions",
+			actionItems:   "Action items",
+			openQuestions: "Open questions",
+			participants:  "Participants",
+			due:           "due",
+		},
+		actions: `Today is %s. Below are the numbered messages of a team conversation.
+Find all tasks the participants agreed on and answer with a JSON object with the field "action_items", a list of tasks, each with:
+- "text": what needs to be done, in one sentence;
+- "owner": the username of the owner (@username from the conversation), or an empty string if nobody was assigned;
+- "due_date": the deadline as YYYY-MM-DD (count relative deadlines such as "on Friday" from the date of the message), or an empty string;
+- "source": the number of the message the task comes from.
+Do not invent tasks that are not in the conversation. If there are no tasks, return an empty list. Write the task texts in English.
+
+CONVERSATION:
+%s`,
+		brief: `Below are the unread messages of one channel.
+Tell the reader what matters most in 1–3 sentences: what was discussed, what was decided and what needs their attention. Do not add introductions, headings or lists. Write the answer in English.
+
+MESSAGES:
+%s`,
+	},
+	LanguageSpanish: {
+		chunk: `A continuación se muestra la parte %d de %d de una conversación larga de un equipo.
+Enumera brevemente los temas, las decisiones tomadas, las tareas asignadas (con responsables y plazos) y los participantes de esta parte.
+No añadas introducciones ni conclusiones, conserva los nombres y los datos concretos.
+
+CONVERSACIÓN:
+%s`,
+		merge: `A continuación se muestran resúmenes breves de partes consecutivas de una misma conversación de un equipo.
+Combínalos en un único resumen breve, conservando los temas, las decisiones, las tareas (con responsables y plazos) y los participantes.
+No añadas introducciones ni conclusiones.
+
+RESÚMENES DE LAS PARTES:
+%s`,
+		part:      "Parte %d:",
+		citations: `Los mensajes están numerados entre corchetes. Después de cada afirmación, cita los mensajes en los que se basa de la misma forma, por ejemplo [3] o [3, 7]. Cita solo números que aparezcan arriba y conserva las citas que ya estén en los resúmenes.`,
+		shorter:   `Haz el resumen bastante más corto de lo habitual: solo lo más importante, como máximo de tres a cinco puntos.`,
+		longer:    `Haz el resumen más detallado de lo habitual: describe el curso de la discusión, los argumentos y los detalles de cada decisión.`,
+		labels: conversationLabels{
+			threadStart: "inicio de hilo",
+			reply:       "respuesta en un hilo",
+			replyTo:     "respuesta a [%d]",
+			edited:      "(editado)",
+			file:        "archivo adjunto",
+			truncated:   "(truncado)",
+			image:       "imagen %d",
+			bot:         "bot",
+		},
+		final: `{{if .PreviousSummary -}}
+A continuación se muestra un resumen de la parte anterior de una conversación de un equipo y los mensajes nuevos publicados después.
+Escribe un resumen centrado en los mensajes nuevos, usando el resumen anterior como contexto:
+
+RESUMEN ANTERIOR:
+{{.PreviousSummary}}
+
+MENSAJES NUEVOS:
+{{.Conversation}}
+{{- else if .Partial -}}
+A continuación se muestran resúmenes breves de partes consecutivas de una misma conversación de un equipo.
+Analízalos y escribe un único resumen final de toda la conversación:
+
+RESÚMENES DE LAS PARTES:
+{{.Conversation}}
+{{- else -}}
+Analiza y resume la siguiente conversación de un equipo:
+
+CONVERSACIÓN:
+{{.Conversation}}
+{{- end}}
+
+Estructura del resumen:
+• **Resumen general:** temas principales y líneas de la discusión
+• **Decisiones clave:** decisiones tomadas y acuerdos alcanzados
+• **Plan de acción:** tareas asignadas y plazos
+• **Participantes:** participantes activos y su papel en la discusión
+
+Usa un formato markdown claro. Escribe la respuesta en español.`,
+		structured: `En lugar de markdown, responde con un único objeto JSON con los siguientes campos:
+- "overview": un breve resumen de la conversación en 2-4 frases;
+- "decisions": una lista de las decisiones tomadas;
+- "action_items": una lista de tareas, cada una con "task" (qué hacer), "assignee" (el responsable tal como se le nombra en la conversación, o una cadena vacía) y "due_date" (el plazo en formato AAAA-MM-DD, o una cadena vacía);
+- "open_questions": una lista de preguntas sin respuesta;
+- "participants": los nombres de los participantes de la conversación.
+Deja las secciones vacías como listas vacías. Escribe los valores en español.`,
+		sections: sectionTitles{
+			overview:      "Resumen",
+			decisions:     "Decisiones clave",
+			actionItems:   "Tareas",
+			openQuestions: "Preguntas abiertas",
+			participants:  "Participantes",
+			due:           "plazo",
+		},
+		actions: `Hoy es %s. A continuación se muestran los mensajes numerados de una conversación de equipo.
+Encuentra todas las tareas acordadas por los participantes y responde con un objeto JSON con el campo "action_items", una lista de tareas, cada una con:
+- "text": qué hay que hacer, en una frase;
+- "owner": el nombre de usuario del responsable (@username de la conversación), o una cadena vacía si no se asignó a nadie;
+- "due_date": el plazo en formato AAAA-MM-DD (cuenta los plazos relativos, como "el viernes", desde la fecha del mensaje), o una cadena vacía;
+- "source": el número del mensaje del que procede la tarea.
+No inventes tareas que no estén en la conversación. Si no hay tareas, devuelve una lista vacía. Escribe los textos de las tareas en español.
+
+CONVERSACIÓN:
+%s`,
+		brief: `A continuación se muestran los mensajes no leídos de un canal.
+Cuenta lo más importante en 1–3 frases: de qué se habló, qué se decidió y qué requiere la atención del lector. No añadas introducciones, títulos ni listas. Escribe la respuesta en español.
+
+MENSAJES:
+%s`,
+	},
+	LanguageFrench: {
+		chunk: `Voici la partie %d sur %d d'une longue conversation d'équipe.
+Énumérez brièvement les sujets, les décisions prises, les tâches attribuées (avec responsables et échéances) et les participants de cette partie.
+N'ajoutez ni introduction ni conclusion, conservez les noms et les faits précis.
+
+CONVERSATION :
+%s`,
+		merge: `Voici de courts résumés de parties successives d'une même conversation d'équipe.
+Fusionnez-les en un seul résumé court en conservant les sujets, les décisions, les tâches (avec responsables et échéances) et les participants.
+N'ajoutez ni introduction ni conclusion.
+
+RÉSUMÉS DES PARTIES :
+%s`,
+		part:      "Partie %d :",
+		citations: `Les messages sont numérotés entre crochets. Après chaque affirmation, citez les messages sur lesquels elle repose sous la même forme, par exemple [3] ou [3, 7]. Ne citez que des numéros qui figurent ci-dessus et conservez les citations déjà présentes dans les résumés.`,
+		shorter:   `Rendez le résumé nettement plus court que d'habitude : seulement l'essentiel, trois à cinq points au maximum.`,
+		longer:    `Rendez le résumé plus détaillé que d'habitude : décrivez le déroulement de la discussion, les arguments et les détails de chaque décision.`,
+		labels: conversationLabels{
+			threadStart: "début de fil",
+			reply:       "réponse dans un fil",
+			replyTo:     "réponse à [%d]",
+			edited:      "(modifié)",
+			file:        "fichier joint",
+			truncated:   "(tronqué)",
+			image:       "image %d",
+			bot:         "bot",
+		},
+		final: `{{if .PreviousSummary -}}
+Voici le résumé de la partie précédente d'une conversation d'équipe et les nouveaux messages publiés depuis.
+Rédigez un résumé centré sur les nouveaux messages en utilisant le résumé précédent comme contexte :
+
+RÉSUMÉ PRÉCÉDENT :
+{{.PreviousSummary}}
+
+NOUVEAUX MESSAGES :
+{{.Conversation}}
+{{- else if .Partial -}}
+Voici de courts résumés de parties successives d'une même conversation d'équipe.
+Analysez-les et rédigez un résumé final unique de toute la conversation :
+
+RÉSUMÉS DES PARTIES :
+{{.Conversation}}
+{{- else -}}
+Analysez et résumez la conversation d'équipe suivante :
+
+CONVERSATION :
+{{.Conversation}}
+{{- end}}
+
+Structure du résumé :
+• **Vue d'ensemble :** principaux sujets et axes de la discussion
+• **Décisions clés :** décisions prises et accords conclus
+• **Plan d'action :** tâches attribuées et échéances
+• **Participants :** participants actifs et leur rôle dans la discussion
+
+Utilisez une mise en forme markdown claire. Rédigez la réponse en français.`,
+		structured: `Au lieu du markdown, répondez avec un seul objet JSON contenant les champs suivants :
+- "overview" : un bref résumé de la conversation en 2 à 4 phrases ;
+- "decisions" : la liste des décisions prises ;
+- "action_items" : la liste des tâches, chacune avec "task" (ce qu'il faut faire), "assignee" (le responsable tel qu'il est nommé dans la conversation, ou une chaîne vide) et "due_date" (l'échéance au format AAAA-MM-JJ, ou une chaîne vide) ;
+- "open_questions" : la liste des questions restées sans réponse ;
+- "participants" : les noms des participants à la conversation.
+Laissez les sections vides sous forme de listes vides. Rédigez les valeurs en français.`,
+		sections: sectionTitles{
+			overview:      "Résumé",
+			decisions:     "Décisions clés",
+			actionItems:   "Actions",
+			openQuestions: "Questions ouvertes",
+			participants:  "Participants",
+			due:           "échéance",
+		},
+		actions: `Nous sommes le %s. Voici les messages numérotés d'une conversation d'équipe.
+Trouvez toutes les tâches convenues par les participants et répondez avec un objet JSON contenant le champ "action_items", une liste de tâches, chacune avec :
+- "text" : ce qu'il faut faire, en une phrase ;
+- "owner" : le nom d'utilisateur du responsable (@username de la conversation), ou une chaîne vide si personne n'a été désigné ;
+- "due_date" : l'échéance au format AAAA-MM-JJ (comptez les échéances relatives, comme « vendredi », à partir de la date du message), ou une chaîne vide ;
+- "source" : le numéro du message d'où provient la tâche.
+N'inventez pas de tâches absentes de la conversation. S'il n'y a aucune tâche, renvoyez une liste vide. Rédigez les textes des tâches en français.
+
+CONVERSATION :
+%s`,
+		brief: `Voici les messages non lus d'un canal.
+Résumez l'essentiel en 1 à 3 phrases : de quoi il a été question, ce qui a été décidé et ce qui demande l'attention du lecteur. N'ajoutez ni introduction, ni titres, ni listes. Rédigez la réponse en français.
+
+MESSAGES :
+%s`,
+	},
+	LanguageGerman: {
+		chunk: `Im Folgenden steht Teil %d von %d eines langen Teamgesprächs.
+Liste kurz die Themen, getroffenen Entscheidungen, vergebenen Aufgaben (mit Verantwortlichen und Fristen) und Teilnehmer dieses Teils auf.
+Füge keine Einleitung und kein Fazit hinzu, behalte Namen und konkrete Fakten bei.
+
+GESPRÄCH:
+%s`,
+		merge: `Im Folgenden stehen kurze Zusammenfassungen aufeinanderfolgender Teile eines Teamgesprächs.
+Fasse sie zu einer kurzen Zusammenfassung zusammen und behalte Themen, Entscheidungen, Aufgaben (mit Verantwortlichen und Fristen) und Teilnehmer bei.
+Füge keine Einleitung und kein Fazit hinzu.
+
+ZUSAMMENFASSUNGEN DER TEILE:
+%s`,
+		part:      "Teil %d:",
+		citations: `Die Nachrichten sind in eckigen Klammern nummeriert. Gib nach jeder Aussage die Nachrichten, auf denen sie beruht, in derselben Form an, zum Beispiel [3] oder [3, 7]. Zitiere nur Nummern, die oben vorkommen, und behalte Zitate bei, die in den Zusammenfassungen bereits stehen.`,
+		shorter:   `Fasse deutlich kürzer als üblich zusammen: nur das Wichtigste, höchstens drei bis fünf Punkte.`,
+		longer:    `Fasse ausführlicher als üblich zusammen: beschreibe den Verlauf der Diskussion, die Argumente und die Details jeder Entscheidung.`,
+		labels: conversationLabels{
+			threadStart: "Thread-Beginn",
+			reply:       "Antwort in einem Thread",
+			replyTo:     "Antwort auf [%d]",
+			edited:      "(bearbeitet)",
+			file:        "angehängte Datei",
+			truncated:   "(gekürzt)",
+			image:       "Bild %d",
+			bot:         "Bot",
+		},
+		final: `{{if .PreviousSummary -}}
+Im Folgenden stehen eine Zusammenfassung des früheren Teils eines Teamgesprächs und die danach veröffentlichten neuen Nachrichten.
+Schreibe eine Zusammenfassung, die sich auf die neuen Nachrichten konzentriert und die frühere Zusammenfassung als Kontext nutzt:
+
+FRÜHERE ZUSAMMENFASSUNG:
+{{.PreviousSummary}}
+
+NEUE NACHRICHTEN:
+{{.Conversation}}
+{{- else if .Partial -}}
+Im Folgenden stehen kurze Zusammenfassungen aufeinanderfolgender Teile eines Teamgesprächs.
+Analysiere sie und schreibe eine einzige abschließende Zusammenfassung des gesamten Gesprächs:
+
+ZUSAMMENFASSUNGEN DER TEILE:
+{{.Conversation}}
+{{- else -}}
+Analysiere und fasse das folgende Teamgespräch zusammen:
+
+GESPRÄCH:
+{{.Conversation}}
+{{- end}}
+
+Aufbau der Zusammenfassung:
+• **Überblick:** Hauptthemen und Richtungen der Diskussion
+• **Wichtige Entscheidungen:** getroffene Entscheidungen und erzielte Vereinbarungen
+• **Aufgaben:** vergebene Aufgaben und Fristen
+• **Teilnehmer:** aktive Teilnehmer und ihre Rolle in der Diskussion
+
+Verwende eine klare Markdown-Formatierung. Schreibe die Antwort auf Deutsch.`,
+		structured: `Antworte statt mit Markdown mit einem einzigen JSON-Objekt mit den folgenden Feldern:
+- "overview": eine kurze Zusammenfassung des Gesprächs in 2-4 Sätzen;
+- "decisions": eine Liste der getroffenen Entscheidungen;
+- "action_items": eine Liste von Aufgaben, jeweils mit "task" (was zu tun ist), "assignee" (die verantwortliche Person, wie sie im Gespräch genannt wird, oder eine leere Zeichenkette) und "due_date" (die Frist im Format JJJJ-MM-TT oder eine leere Zeichenkette);
+- "open_questions": eine Liste offener Fragen;
+- "participants": die Namen der Gesprächsteilnehmer.
+Lass leere Abschnitte als leere Listen. Schreibe die Werte auf Deutsch.`,
+		sections: sectionTitles{
+			overview:      "Überblick",
+			decisions:     "Wichtige Entscheidungen",
+			actionItems:   "Aufgaben",
+			openQuestions: "Offene Fragen",
+			participants:  "Teilnehmer",
+			due:           "Frist",
+		},
+		actions: `Heute ist der %s. Unten stehen die nummerierten Nachrichten eines Teamgesprächs.
+Finde alle Aufgaben, auf die sich die Teilnehmer geeinigt haben, und antworte mit einem JSON-Objekt mit dem Feld "action_items", einer Liste von Aufgaben, jeweils mit:
+- "text": was zu tun ist, in einem Satz;
+- "owner": der Benutzername der verantwortlichen Person (@username aus dem Gespräch) oder eine leere Zeichenkette, wenn niemand zugewiesen wurde;
+- "due_date": die Frist im Format JJJJ-MM-TT (relative Fristen wie "am Freitag" ab dem Datum der Nachricht gerechnet) oder eine leere Zeichenkette;
+- "source": die Nummer der Nachricht, aus der die Aufgabe stammt.
+Erfinde keine Aufgaben, die nicht im Gespräch vorkommen. Wenn es keine Aufgaben gibt, gib eine leere Liste zurück. Schreibe die Aufgabentexte auf Deutsch.
+
+GESPRÄCH:
+%s`,
+		brief: `Unten stehen die ungelesenen Nachrichten eines Kanals.
+Gib das Wichtigste in 1–3 Sätzen wieder: worüber gesprochen wurde, was entschieden wurde und was die Aufmerksamkeit des Lesers braucht. Füge keine Einleitungen, Überschriften oder Listen hinzu. Schreibe die Antwort auf Deutsch.
+
+NACHRICHTEN:
+%s`,
+	},
+}
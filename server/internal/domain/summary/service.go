@@ -2,6 +2,8 @@ package summary
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -11,50 +13,365 @@ import (
 type Service struct {
 	llm          llm
 	userProvider userProvider
+	cache        cache
+	channels     channelProvider
+	config       configProvider
+	files        fileProvider
+	templates    PromptTemplates
+	selections   templateSelections
+	language     string
+	structured   bool
+	maxImages    int
 }
 
-func NewService(llm llm, userProvider userProvider) *Service {
-	return &Service{
+type Option func(s *Service)
+
+// Params are per-request summary settings.
+type Params struct {
+	// Language overrides the configured summary language. LanguageAuto
+	// detects it from the conversation.
+	Language string
+	// Timezone is the IANA name of the time zone message times are shown
+	// in. UTC is used when it is empty or unknown.
+	Timezone string
+	// Length asks for a summary shorter or longer than usual, see
+	// LengthShort and LengthLong. Empty means the usual length.
+	Length string
+	// Fresh generates the summary even if it is cached, replacing the cached
+	// one.
+	Fresh bool
+}
+
+// Summary lengths other than the usual one.
+const (
+	LengthShort = "short"
+	LengthLong  = "long"
+)
+
+// WithCache enables reuse of previously generated summaries.
+func WithCache(cache cache) Option {
+	return func(s *Service) {
+		s.cache = cache
+	}
+}
+
+// WithChannels makes channel details such as the name available to prompt
+// templates and enables per-team template selection.
+func WithChannels(channels channelProvider) Option {
+	return func(s *Service) {
+		s.channels = channels
+	}
+}
+
+// WithAttachments includes the files attached to posts in the conversation:
+// the text of plain text, markdown, code, CSV and JSON files and of documents
+// the server has extracted text from, and the names of other files.
+func WithAttachments(files fileProvider) Option {
+	return func(s *Service) {
+		s.files = files
+	}
+}
+
+// WithImages passes up to maxImages images attached to the posts along with
+// the final summary request, if the model accepts images. It takes effect
+// together with WithAttachments. Structured summaries are requested without
+// images.
+func WithImages(maxImages int) Option {
+	return func(s *Service) {
+		s.maxImages = maxImages
+	}
+}
+
+// WithPermalinks makes citations in summaries link to the cited posts on the
+// configured site URL. Without it the links are relative to the server.
+func WithPermalinks(config configProvider) Option {
+	return func(s *Service) {
+		s.config = config
+	}
+}
+
+// WithPromptTemplates sets the admin-defined prompt templates and the store of
+// per-channel and per-team template selections.
+func WithPromptTemplates(templates PromptTemplates, selections templateSelections) Option {
+	return func(s *Service) {
+		if templates != nil {
+			s.templates = templates
+		}
+		s.selections = selections
+	}
+}
+
+// WithLanguage sets the summary language used unless a request overrides it.
+// LanguageAuto or an empty language detects it from the conversation.
+func WithLanguage(language string) Option {
+	return func(s *Service) {
+		s.language = language
+	}
+}
+
+// WithStructuredOutput requests summaries as JSON (see StructuredSummary),
+// which are then rendered to markdown. Structured summaries are not streamed:
+// the rendered summary is passed to onChunk at once.
+func WithStructuredOutput() Option {
+	return func(s *Service) {
+		s.structured = true
+	}
+}
+
+func NewService(llm llm, userProvider userProvider, options ...Option) *Service {
+	defaultTemplates, _ := ParseTemplates(nil)
+	s := &Service{
 		llm:          llm,
 		userProvider: userProvider,
+		templates:    defaultTemplates,
 	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// GenerateSummary summarizes posts. Conversations that do not fit into the
+// model context are split into chunks which are summarized separately (map)
+// and then combined into a single summary (reduce).
+func (s Service) GenerateSummary(ctx context.Context, posts []*model.Post) (Summary, error) {
+	return s.summarize(ctx, posts, Params{}, nil)
 }
 
-func (s Service) GenerateSummary(ctx context.Context, posts []*model.Post) (string, error) {
-	conversationText := strings.Builder{}
-	for _, post := range posts {
-		if post.DeleteAt != 0 && post.Message == "" {
-			continue
+// GenerateSummaryStream works like GenerateSummary but streams the final
+// summary to onChunk as it is produced. Intermediate map-reduce steps are not
+// streamed.
+func (s Service) GenerateSummaryStream(ctx context.Context, posts []*model.Post, params Params, onChunk func(chunk string) error) (Summary, error) {
+	return s.summarize(ctx, posts, params, onChunk)
+}
+
+// summarize returns the cached summary of posts if there is one and generates
+// and caches it otherwise. Cache failures never prevent summarization.
+func (s Service) summarize(ctx context.Context, posts []*model.Post, params Params, onChunk func(chunk string) error) (Summary, error) {
+	p := s.newPrompt(ctx, posts, params)
+	refs := citationsOf(posts)
+	if s.cache == nil {
+		summary, err := s.generateSummary(ctx, p, "", posts, onChunk)
+		if err != nil {
+			return Summary{}, err
 		}
+		return s.linkCitations(summary, refs), nil
+	}
 
-		user, err := s.userProvider.Get(post.UserId)
-		source := "unknown user"
-		if err == nil && user != nil {
-			source = user.FirstName + " " + user.LastName + " " + user.Position
+	scopeID := cacheScope(posts)
+	fingerprint := s.cacheFingerprint(p, posts)
+	if cached, err := s.cache.GetCachedSummary(scopeID, fingerprint); !params.Fresh && err == nil && cached != "" {
+		if summary, ok := s.decodeCached(p, cached); ok {
+			summary = s.linkCitations(summary, refs)
+			summary.SkippedAttachments = p.attachments.skipped
+			if onChunk != nil {
+				if err := onChunk(summary.Text); err != nil {
+					return Summary{}, err
+				}
+			}
+			return summary, nil
 		}
-		conversationText.WriteString(source + ":" + post.Message)
 	}
-	if conversationText.Len() == 0 {
-		return "", fmt.Errorf("no messages")
+
+	summary, err := s.generateSummary(ctx, p, "", posts, onChunk)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	// Citations are cached as numbers and linked on every use, so that the
+	// links follow changes of the site URL.
+	_ = s.cache.SetCachedSummary(scopeID, fingerprint, s.encodeCached(summary))
+	return s.linkCitations(summary, refs), nil
+}
+
+// encodeCached stores structured summaries as JSON so that the structured
+// form survives the cache, and plain summaries as text.
+func (s Service) encodeCached(summary Summary) string {
+	if summary.Structured == nil {
+		return summary.Text
+	}
+	data, err := json.Marshal(summary.Structured)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (s Service) decodeCached(p prompt, cached string) (Summary, bool) {
+	if !s.structured {
+		return Summary{Text: cached}, true
+	}
+	var structured StructuredSummary
+	if err := json.Unmarshal([]byte(cached), &structured); err != nil {
+		return Summary{}, false
+	}
+	return Summary{Text: structured.Markdown(p.data.Language), Structured: &structured}, true
+}
+
+// generateSummary summarizes posts with the final prompt p. A non-empty
+// previous summary of the earlier history is taken into account so the result
+// covers both.
+func (s Service) generateSummary(ctx context.Context, p prompt, previous string, posts []*model.Post, onChunk func(chunk string) error) (Summary, error) {
+	lines, participants := s.newConversation(p, citations{}).lines(posts)
+	summary, err := s.generateFromLines(ctx, p, previous, lines, participants, onChunk)
+	if err != nil {
+		return Summary{}, err
+	}
+	summary.SkippedAttachments = p.attachments.skipped
+	return summary, nil
+}
+
+// generateFromLines summarizes a conversation rendered one message per line,
+// splitting it into chunks when it does not fit into the model context.
+func (s Service) generateFromLines(ctx context.Context, p prompt, previous string, lines, participants []string, onChunk func(chunk string) error) (Summary, error) {
+	if len(lines) == 0 {
+		return Summary{}, fmt.Errorf("no messages")
 	}
+	p.data.Participants = participants
 
-	prompt := fmt.Sprintf(`Проанализируйте и обобщите следующую командную беседу:
+	budget := chunkBudget(s.llm.ContextSize())
+	chunks := splitIntoChunks(lines, budget)
+	if len(chunks) == 1 {
+		if previous == "" {
+			return s.generateFinal(ctx, p, chunks[0], "", false, onChunk)
+		}
+		if estimateTokens(previous)+estimateTokens(chunks[0]) <= budget {
+			return s.generateFinal(ctx, p, chunks[0], previous, false, onChunk)
+		}
+	}
 
-ПЕРЕПИСКА:
-%s
+	partials := make([]string, 0, len(chunks)+1)
+	if previous != "" {
+		partials = append(partials, previous)
+	}
+	for i, chunk := range chunks {
+		partial, err := s.generate(ctx, fmt.Sprintf(p.builtin.chunk, i+1, len(chunks), chunk)+"\n\n"+p.builtin.citations)
+		if err != nil {
+			return Summary{}, fmt.Errorf("failed to summarize chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		partials = append(partials, partial)
+	}
 
-Структура резюме:
-• **Краткое содержание:** основные темы и направления обсуждения
-• **Ключевые решения:** принятые решения и достигнутые договоренности
-• **План действий:** поставленные задачи и сроки выполнения
-• **Участники:** активные участники и их роль в обсуждении
+	return s.reduce(ctx, p, partials, budget, onChunk)
+}
+
+// reduce combines partial summaries into the final summary, merging them in
+// additional rounds while they do not fit into a single request.
+func (s Service) reduce(ctx context.Context, p prompt, partials []string, budget int, onChunk func(chunk string) error) (Summary, error) {
+	for {
+		chunks := splitIntoChunks(numberPartials(p.builtin.part, partials), budget)
+		if len(chunks) == 1 {
+			return s.generateFinal(ctx, p, chunks[0], "", true, onChunk)
+		}
+		if len(chunks) >= len(partials) {
+			return Summary{}, fmt.Errorf("partial summaries do not fit into the model context")
+		}
 
-Используйте четкое форматирование markdown.`, conversationText.String())
+		merged := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			partial, err := s.generate(ctx, fmt.Sprintf(p.builtin.merge, chunk)+"\n\n"+p.builtin.citations)
+			if err != nil {
+				return Summary{}, fmt.Errorf("failed to merge partial summaries %d of %d: %w", i+1, len(chunks), err)
+			}
+			merged = append(merged, partial)
+		}
+		partials = merged
+	}
+}
 
+func (s Service) generate(ctx context.Context, prompt string) (string, error) {
 	summary, err := s.llm.Generate(ctx, prompt)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate summary: %w", err)
 	}
+	return summary, nil
+}
+
+// generateFinal renders the final prompt and produces the summary returned to
+// the user, streaming it when onChunk is set.
+func (s Service) generateFinal(ctx context.Context, p prompt, conversation, previous string, partial bool, onChunk func(chunk string) error) (Summary, error) {
+	text, err := p.render(conversation, previous, partial)
+	if err != nil {
+		return Summary{}, err
+	}
+	text += "\n\n" + p.builtin.citations
+	switch p.length {
+	case LengthShort:
+		text += "\n\n" + p.builtin.shorter
+	case LengthLong:
+		text += "\n\n" + p.builtin.longer
+	}
+
+	if s.structured {
+		return s.generateStructured(ctx, p, text, onChunk)
+	}
+
+	if images := p.attachments.readImages(); len(images) > 0 {
+		return s.generateWithImages(ctx, text, images, onChunk)
+	}
+
+	if onChunk == nil {
+		summary, err := s.generate(ctx, text)
+		if err != nil {
+			return Summary{}, err
+		}
+		return Summary{Text: summary}, nil
+	}
+
+	summary, err := s.llm.GenerateStream(ctx, text, onChunk)
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to generate summary: %w", err)
+	}
+	return Summary{Text: summary}, nil
+}
+
+// generateWithImages produces the summary with a multimodal model. Attached
+// images are only loaded when the model accepts them, so s.llm is known to be
+// an imageGenerator.
+func (s Service) generateWithImages(ctx context.Context, text string, images [][]byte, onChunk func(chunk string) error) (Summary, error) {
+	if onChunk == nil {
+		onChunk = func(string) error { return nil }
+	}
+	summary, err := s.llm.(imageGenerator).GenerateWithImages(ctx, text, images, onChunk)
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to generate summary: %w", err)
+	}
+	return Summary{Text: summary}, nil
+}
+
+// generateStructured requests the summary as JSON and renders it to markdown.
+// An answer without any JSON, which models that ignore the requested format
+// produce, is kept as the overview.
+func (s Service) generateStructured(ctx context.Context, p prompt, text string, onChunk func(chunk string) error) (Summary, error) {
+	answer, err := s.llm.GenerateJSON(ctx, text+"\n\n"+p.builtin.structured, summarySchema)
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to generate summary: %w", err)
+	}
 
+	structured, err := parseStructuredSummary(answer)
+	if errors.Is(err, errNotJSON) && strings.TrimSpace(answer) != "" {
+		structured, err = StructuredSummary{Overview: strings.TrimSpace(answer)}, nil
+	}
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to parse structured summary: %w", err)
+	}
+	if len(structured.Participants) == 0 {
+		structured.Participants = p.data.Participants
+	}
+
+	summary := Summary{Text: structured.Markdown(p.data.Language), Structured: &structured}
+	if onChunk != nil {
+		if err := onChunk(summary.Text); err != nil {
+			return Summary{}, err
+		}
+	}
 	return summary, nil
 }
+
+func numberPartials(label string, partials []string) []string {
+	numbered := make([]string, 0, len(partials))
+	for i, partial := range partials {
+		numbered = append(numbered, fmt.Sprintf(label+"\n%s\n", i+1, strings.TrimSpace(partial)))
+	}
+	return numbered
+}
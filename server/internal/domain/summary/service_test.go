@@ -0,0 +1,150 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLLM struct {
+	contextSize int
+	prompts     []string
+	err         error
+	// answer is returned by GenerateJSON.
+	answer string
+}
+
+func (f *fakeLLM) Generate(_ context.Context, prompt string) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	if f.err != nil {
+		return "", f.err
+	}
+	return "summary", nil
+}
+
+func (f *fakeLLM) GenerateStream(ctx context.Context, prompt string, onChunk func(chunk string) error) (string, error) {
+	result, err := f.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	for _, chunk := range strings.SplitAfter(result, "m") {
+		if err := onChunk(chunk); err != nil {
+			return "", err
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeLLM) GenerateJSON(_ context.Context, prompt string, _ json.RawMessage) (string, error) {
+	f.prompts = append(f.prompts, prompt)
+	if f.err != nil {
+		return "", f.err
+	}
+	return f.answer, nil
+}
+
+func (f *fakeLLM) Model() string {
+	return "test-model"
+}
+
+func (f *fakeLLM) ContextSize() int {
+	return f.contextSize
+}
+
+type fakeUsers map[string]*model.User
+
+func (f fakeUsers) Get(userID string) (*model.User, error) {
+	user, ok := f[userID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return user, nil
+}
+
+func (f fakeUsers) GetByUsername(username string) (*model.User, error) {
+	for _, user := range f {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func newPosts(n, size int) []*model.Post {
+	posts := make([]*model.Post, 0, n)
+	for i := 0; i < n; i++ {
+		posts = append(posts, &model.Post{UserId: "u1", Message: strings.Repeat("x", size)})
+	}
+	return posts
+}
+
+func TestService_GenerateSummary(t *testing.T) {
+	users := fakeUsers{"u1": {FirstName: "Ivan", LastName: "Petrov"}}
+
+	t.Run("should_use_single_prompt_when_conversation_fits", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users)
+
+		result, err := service.GenerateSummary(context.Background(), newPosts(3, 10))
+
+		require.NoError(t, err)
+		assert.Equal(t, "summary", result.Text)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "Ivan Petrov")
+	})
+
+	t.Run("should_map_and_reduce_when_conversation_exceeds_context", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 1024}
+		service := NewService(llm, users, WithLanguage(LanguageRussian))
+
+		// 10 posts of ~600 tokens each against a 768 token budget.
+		result, err := service.GenerateSummary(context.Background(), newPosts(10, 1800))
+
+		require.NoError(t, err)
+		assert.Equal(t, "summary", result.Text)
+		require.Len(t, llm.prompts, 11)
+		assert.Contains(t, llm.prompts[0], "часть 1 из 10")
+		assert.Contains(t, llm.prompts[10], "Часть 10:")
+		for _, prompt := range llm.prompts {
+			assert.LessOrEqual(t, estimateTokens(prompt), 1024)
+		}
+	})
+
+	t.Run("should_fail_without_messages", func(t *testing.T) {
+		service := NewService(&fakeLLM{contextSize: 1024}, users)
+
+		_, err := service.GenerateSummary(context.Background(), []*model.Post{{DeleteAt: 1}})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should_wrap_llm_error", func(t *testing.T) {
+		service := NewService(&fakeLLM{contextSize: 1024, err: errors.New("boom")}, users)
+
+		_, err := service.GenerateSummary(context.Background(), newPosts(10, 1800))
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "chunk 1 of 10")
+	})
+
+	t.Run("should_stream_only_final_step", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 1024}
+		service := NewService(llm, users)
+
+		var chunks []string
+		result, err := service.GenerateSummaryStream(context.Background(), newPosts(10, 1800), Params{}, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "summary", result.Text)
+		assert.Equal(t, []string{"sum", "m", "ary"}, chunks)
+	})
+}
@@ -0,0 +1,319 @@
+package summary
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// errNotJSON reports a model answer that does not contain a JSON object at
+// all, as opposed to a malformed one.
+var errNotJSON = errors.New("response is not a JSON object")
+
+// StructuredSummary is the machine-readable form of a summary.
+type StructuredSummary struct {
+	Overview      string       `json:"overview"`
+	Decisions     []string     `json:"decisions"`
+	ActionItems   []ActionItem `json:"action_items"`
+	OpenQuestions []string     `json:"open_questions"`
+	Participants  []string     `json:"participants"`
+}
+
+// ActionItem is a task agreed on in the conversation.
+type ActionItem struct {
+	Task     string `json:"task"`
+	Assignee string `json:"assignee,omitempty"`
+	// DueDate is formatted as YYYY-MM-DD when the model's answer could be
+	// parsed as a date, and kept as written otherwise.
+	DueDate string `json:"due_date,omitempty"`
+}
+
+// summarySchema is the JSON schema of StructuredSummary requested from the model.
+var summarySchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"overview": {"type": "string"},
+		"decisions": {"type": "array", "items": {"type": "string"}},
+		"action_items": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"task": {"type": "string"},
+					"assignee": {"type": "string"},
+					"due_date": {"type": "string"}
+				},
+				"required": ["task", "assignee", "due_date"],
+				"additionalProperties": false
+			}
+		},
+		"open_questions": {"type": "array", "items": {"type": "string"}},
+		"participants": {"type": "array", "items": {"type": "string"}}
+	},
+	"required": ["overview", "decisions", "action_items", "open_questions", "participants"],
+	"additionalProperties": false
+}`)
+
+// sectionTitles are the localized headings of a rendered structured summary.
+type sectionTitles struct {
+	overview, decisions, actionItems, openQuestions, participants, due string
+}
+
+// Markdown renders the summary for a post, with headings in the given language.
+func (s StructuredSummary) Markdown(language string) string {
+	titles := builtinPrompts[LanguageEnglish].sections
+	if p, ok := builtinPrompts[language]; ok {
+		titles = p.sections
+	}
+
+	var sections []string
+	if s.Overview != "" {
+		sections = append(sections, fmt.Sprintf("**%s**\n%s", titles.overview, s.Overview))
+	}
+	if len(s.Decisions) > 0 {
+		sections = append(sections, fmt.Sprintf("**%s**\n%s", titles.decisions, bulletList(s.Decisions)))
+	}
+	if len(s.ActionItems) > 0 {
+		items := make([]string, 0, len(s.ActionItems))
+		for _, item := range s.ActionItems {
+			line := item.Task
+			if item.Assignee != "" {
+				line += " — " + item.Assignee
+			}
+			if item.DueDate != "" {
+				line += fmt.Sprintf(" (%s: %s)", titles.due, item.DueDate)
+			}
+			items = append(items, line)
+		}
+		sections = append(sections, fmt.Sprintf("**%s**\n%s", titles.actionItems, bulletList(items)))
+	}
+	if len(s.OpenQuestions) > 0 {
+		sections = append(sections, fmt.Sprintf("**%s**\n%s", titles.openQuestions, bulletList(s.OpenQuestions)))
+	}
+	if len(s.Participants) > 0 {
+		sections = append(sections, fmt.Sprintf("**%s**\n%s", titles.participants, strings.Join(s.Participants, ", ")))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+func bulletList(items []string) string {
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, "- "+item)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s StructuredSummary) empty() bool {
+	return s.Overview == "" && len(s.Decisions) == 0 && len(s.ActionItems) == 0 && len(s.OpenQuestions) == 0
+}
+
+// parseStructuredSummary validates a model answer against the summary schema.
+// It repairs common deviations: code fences and text around the JSON object,
+// trailing commas, differently spelled keys, single values instead of lists
+// and objects instead of strings. It returns errNotJSON if the answer does
+// not contain a JSON object.
+func parseStructuredSummary(answer string) (StructuredSummary, error) {
+	start := strings.Index(answer, "{")
+	if start < 0 {
+		return StructuredSummary{}, errNotJSON
+	}
+	end := strings.LastIndex(answer, "}")
+	if end < start {
+		return StructuredSummary{}, fmt.Errorf("invalid summary JSON: truncated object")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(removeTrailingCommas(answer[start:end+1])), &fields); err != nil {
+		return StructuredSummary{}, fmt.Errorf("invalid summary JSON: %w", err)
+	}
+
+	normalized := make(map[string]json.RawMessage, len(fields))
+	for key, value := range fields {
+		normalized[normalizeKey(key)] = value
+	}
+
+	summary := StructuredSummary{
+		Overview:      decodeText(firstField(normalized, "overview", "summary")),
+		Decisions:     decodeList(firstField(normalized, "decisions", "keydecisions")),
+		ActionItems:   decodeActionItems(firstField(normalized, "actionitems", "actions", "tasks")),
+		OpenQuestions: decodeList(firstField(normalized, "openquestions", "questions")),
+		Participants:  decodeList(firstField(normalized, "participants")),
+	}
+	if summary.empty() {
+		return StructuredSummary{}, fmt.Errorf("summary JSON has no content")
+	}
+	return summary, nil
+}
+
+// normalizeKey maps "Action Items", "action-items" and "actionItems" to the
+// same key.
+func normalizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '_', '-', ' ':
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+}
+
+func firstField(fields map[string]json.RawMessage, keys ...string) json.RawMessage {
+	for _, key := range keys {
+		if value, ok := fields[key]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+// decodeText returns a string value, joins a list of strings and takes the
+// first text of an object.
+func decodeText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return cleanValue(text)
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(decodeList(raw), " ")
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err == nil {
+		for _, key := range []string{"text", "description", "summary", "name", "title"} {
+			if value, ok := object[key]; ok {
+				return decodeText(value)
+			}
+		}
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return number.String()
+	}
+	return ""
+}
+
+// decodeList returns the non-empty texts of a list; a single value is treated
+// as a list of one.
+func decodeList(raw json.RawMessage) []string {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		if text := decodeText(raw); text != "" {
+			return []string{text}
+		}
+		return nil
+	}
+
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		if text := decodeText(item); text != "" {
+			items = append(items, text)
+		}
+	}
+	return items
+}
+
+func decodeActionItems(raw json.RawMessage) []ActionItem {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		list = []json.RawMessage{raw}
+	}
+
+	items := make([]ActionItem, 0, len(list))
+	for _, entry := range list {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(entry, &object); err != nil {
+			if task := decodeText(entry); task != "" {
+				items = append(items, ActionItem{Task: task})
+			}
+			continue
+		}
+
+		fields := make(map[string]json.RawMessage, len(object))
+		for key, value := range object {
+			fields[normalizeKey(key)] = value
+		}
+		item := ActionItem{
+			Task:     decodeText(firstField(fields, "task", "text", "action", "title", "description")),
+			Assignee: decodeText(firstField(fields, "assignee", "owner", "responsible")),
+			DueDate:  normalizeDueDate(decodeText(firstField(fields, "duedate", "due", "deadline"))),
+		}
+		if item.Task != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// cleanValue trims a value and drops placeholders models use for missing data.
+func cleanValue(value string) string {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "null", "none", "n/a", "-", "unknown", "tbd":
+		return ""
+	}
+	return value
+}
+
+var dueDateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"02.01.2006",
+	"2006/01/02",
+}
+
+// normalizeDueDate formats dates as YYYY-MM-DD and keeps anything else, such
+// as "next Friday", as written.
+func normalizeDueDate(value string) string {
+	for _, layout := range dueDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return value
+}
+
+// removeTrailingCommas drops commas directly before a closing bracket, which
+// JSON does not allow but models often produce. Commas inside strings are kept.
+func removeTrailingCommas(text string) string {
+	var out strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			next := strings.TrimLeft(text[i+1:], " \t\r\n")
+			if next != "" && (next[0] == '}' || next[0] == ']') {
+				continue
+			}
+		}
+		out.WriteByte(c)
+	}
+	return out.String()
+}
@@ -0,0 +1,181 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStructuredSummary(t *testing.T) {
+	tests := []struct {
+		name        string
+		answer      string
+		expected    StructuredSummary
+		expectError string
+	}{
+		{
+			name: "should_parse_valid_json",
+			answer: `{"overview": "Release planning.", "decisions": ["Ship on Friday"],
+				"action_items": [{"task": "Update API", "assignee": "Ivan", "due_date": "2025-03-14"}],
+				"open_questions": ["Who writes docs?"], "participants": ["Ivan", "Anna"]}`,
+			expected: StructuredSummary{
+				Overview:      "Release planning.",
+				Decisions:     []string{"Ship on Friday"},
+				ActionItems:   []ActionItem{{Task: "Update API", Assignee: "Ivan", DueDate: "2025-03-14"}},
+				OpenQuestions: []string{"Who writes docs?"},
+				Participants:  []string{"Ivan", "Anna"},
+			},
+		},
+		{
+			name:   "should_strip_code_fence_and_trailing_commas",
+			answer: "```json\n{\"overview\": \"Release, planning.\", \"decisions\": [\"Ship on Friday\",],}\n```",
+			expected: StructuredSummary{
+				Overview:  "Release, planning.",
+				Decisions: []string{"Ship on Friday"},
+			},
+		},
+		{
+			name: "should_accept_alternative_keys_and_shapes",
+			answer: `Here is the summary: {"Summary": ["Release", "planning."], "Key Decisions": "Ship on Friday",
+				"actionItems": ["Update API", {"text": "Write docs", "owner": "Anna", "deadline": "14.03.2025"}],
+				"open-questions": [{"text": "Budget?"}, ""]}`,
+			expected: StructuredSummary{
+				Overview:  "Release planning.",
+				Decisions: []string{"Ship on Friday"},
+				ActionItems: []ActionItem{
+					{Task: "Update API"},
+					{Task: "Write docs", Assignee: "Anna", DueDate: "2025-03-14"},
+				},
+				OpenQuestions: []string{"Budget?"},
+			},
+		},
+		{
+			name:   "should_drop_placeholder_values",
+			answer: `{"overview": "Planning.", "action_items": [{"task": "Update API", "assignee": "N/A", "due_date": "none"}, {"task": ""}]}`,
+			expected: StructuredSummary{
+				Overview:    "Planning.",
+				ActionItems: []ActionItem{{Task: "Update API"}},
+			},
+		},
+		{
+			name:        "should_fail_without_json",
+			answer:      "The team discussed the release.",
+			expectError: errNotJSON.Error(),
+		},
+		{
+			name:        "should_fail_on_truncated_json",
+			answer:      `{"overview": "Planning.`,
+			expectError: "truncated object",
+		},
+		{
+			name:        "should_fail_on_invalid_json",
+			answer:      `{"overview": Planning}`,
+			expectError: "invalid summary JSON",
+		},
+		{
+			name:        "should_fail_on_empty_summary",
+			answer:      `{"overview": "", "participants": ["Ivan"]}`,
+			expectError: "no content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := parseStructuredSummary(tt.answer)
+
+			if tt.expectError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected.Overview, summary.Overview)
+			assert.Equal(t, tt.expected.Decisions, summary.Decisions)
+			assert.ElementsMatch(t, tt.expected.ActionItems, summary.ActionItems)
+			assert.Equal(t, tt.expected.OpenQuestions, summary.OpenQuestions)
+			assert.Equal(t, tt.expected.Participants, summary.Participants)
+		})
+	}
+}
+
+func TestStructuredSummary_Markdown(t *testing.T) {
+	summary := StructuredSummary{
+		Overview:     "Release planning.",
+		Decisions:    []string{"Ship on Friday"},
+		ActionItems:  []ActionItem{{Task: "Update API", Assignee: "Ivan", DueDate: "2025-03-14"}, {Task: "Write docs"}},
+		Participants: []string{"Ivan", "Anna"},
+	}
+
+	expected := "**Overview**\nRelease planning.\n\n" +
+		"**Key decisions**\n- Ship on Friday\n\n" +
+		"**Action items**\n- Update API — Ivan (due: 2025-03-14)\n- Write docs\n\n" +
+		"**Participants**\nIvan, Anna"
+	assert.Equal(t, expected, summary.Markdown(LanguageEnglish))
+	assert.Contains(t, summary.Markdown(LanguageRussian), "**Ключевые решения**")
+}
+
+func TestService_StructuredOutput(t *testing.T) {
+	users := fakeUsers{"u1": {FirstName: "Ivan", LastName: "Petrov"}}
+	posts := func() []*model.Post {
+		return []*model.Post{{Id: "p1", ChannelId: "c1", UserId: "u1", Message: "Ship on Friday", CreateAt: 10}}
+	}
+
+	t.Run("should_render_structured_summary", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000, answer: `{"overview": "Release planning.", "decisions": ["Ship on Friday"]}`}
+		service := NewService(llm, users, WithLanguage(LanguageEnglish), WithStructuredOutput())
+
+		var chunks []string
+		summary, err := service.GenerateSummaryStream(context.Background(), posts(), Params{}, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.NotNil(t, summary.Structured)
+		assert.Equal(t, []string{"Ship on Friday"}, summary.Structured.Decisions)
+		assert.Equal(t, []string{"Ivan Petrov"}, summary.Structured.Participants, "participants are filled in from the posts")
+		assert.Contains(t, summary.Text, "**Key decisions**\n- Ship on Friday")
+		assert.Equal(t, []string{summary.Text}, chunks)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], `"action_items"`)
+	})
+
+	t.Run("should_keep_plain_text_answer_as_overview", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000, answer: "The team agreed to ship on Friday."}
+		service := NewService(llm, users, WithLanguage(LanguageEnglish), WithStructuredOutput())
+
+		summary, err := service.GenerateSummary(context.Background(), posts())
+
+		require.NoError(t, err)
+		require.NotNil(t, summary.Structured)
+		assert.Equal(t, "The team agreed to ship on Friday.", summary.Structured.Overview)
+	})
+
+	t.Run("should_fail_on_unrepairable_answer", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000, answer: `{"overview": }`}
+		service := NewService(llm, users, WithStructuredOutput())
+
+		_, err := service.GenerateSummary(context.Background(), posts())
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse structured summary")
+	})
+
+	t.Run("should_keep_structured_form_in_cache", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000, answer: `{"overview": "Release planning."}`}
+		service := NewService(llm, users, WithLanguage(LanguageEnglish), WithStructuredOutput(), WithCache(newMemCache()))
+
+		first, err := service.GenerateSummary(context.Background(), posts())
+		require.NoError(t, err)
+		second, err := service.GenerateSummary(context.Background(), posts())
+		require.NoError(t, err)
+
+		assert.Len(t, llm.prompts, 1)
+		assert.Equal(t, first, second)
+		require.NotNil(t, second.Structured)
+		assert.Equal(t, "Release planning.", second.Structured.Overview)
+	})
+}
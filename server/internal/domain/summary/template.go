@@ -0,0 +1,194 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// DefaultTemplate is the name of the built-in prompt template. It is used when
+// neither the channel nor its team selected another one.
+const DefaultTemplate = "default"
+
+// PromptData holds the variables available to prompt templates.
+type PromptData struct {
+	// Conversation is the rendered conversation or, when Partial is set, the
+	// numbered summaries of its parts.
+	Conversation string
+	// PreviousSummary is the summary of the earlier history the new messages
+	// continue, if any.
+	PreviousSummary string
+	// Partial reports that Conversation holds partial summaries of a
+	// conversation too long for a single request.
+	Partial bool
+	// ChannelName is the display name of the summarized channel.
+	ChannelName string
+	// Language is the code of the language the summary is written in.
+	Language string
+	// LanguageName is the name of the summary language in that language.
+	LanguageName string
+	// Participants are the names of the conversation authors in order of
+	// their first message.
+	Participants []string
+}
+
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// builtinTemplates are the localized variants of the default template by
+// language code.
+var builtinTemplates = func() map[string]*template.Template {
+	templates := make(map[string]*template.Template, len(builtinPrompts))
+	for language, prompts := range builtinPrompts {
+		templates[language] = template.Must(newTemplate(DefaultTemplate, prompts.final))
+	}
+	return templates
+}()
+
+// PromptTemplates is a set of named prompt templates that always contains
+// DefaultTemplate.
+type PromptTemplates map[string]*template.Template
+
+// ParseTemplates parses admin-defined templates by name and adds the built-in
+// default template, which is rendered in the summary language. Templates are
+// test-rendered so that references to unknown variables are reported here
+// rather than when a summary is requested.
+func ParseTemplates(definitions map[string]string) (PromptTemplates, error) {
+	templates := PromptTemplates{
+		DefaultTemplate: builtinTemplates[LanguageEnglish],
+	}
+
+	for name, text := range definitions {
+		if name == DefaultTemplate {
+			return nil, fmt.Errorf("template name %q is reserved", DefaultTemplate)
+		}
+		if name == "" || strings.ContainsAny(name, " \t\n") {
+			return nil, fmt.Errorf("invalid template name %q: must be a single word", name)
+		}
+
+		tmpl, err := newTemplate(name, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %q: %w", name, err)
+		}
+		if err := tmpl.Execute(io.Discard, PromptData{Participants: []string{}}); err != nil {
+			return nil, fmt.Errorf("failed to render template %q: %w", name, err)
+		}
+		templates[name] = tmpl
+	}
+
+	return templates, nil
+}
+
+// Names returns the template names in alphabetical order.
+func (t PromptTemplates) Names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// prompt is the final prompt template selected for a summary request
+// together with the request-wide variables and the built-in prompts for
+// intermediate steps in the summary language.
+type prompt struct {
+	name     string
+	tmpl     *template.Template
+	data     PromptData
+	builtin  localizedPrompts
+	location *time.Location
+	// length is the requested length of the summary, see Params.Length.
+	length string
+	// attachments are the files of the summarized posts.
+	attachments attachments
+}
+
+// render executes the template for the given conversation or partial summaries.
+func (p prompt) render(conversation, previous string, partial bool) (string, error) {
+	data := p.data
+	data.Conversation = conversation
+	data.PreviousSummary = previous
+	data.Partial = partial
+
+	var out strings.Builder
+	if err := p.tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %q: %w", p.name, err)
+	}
+	return out.String(), nil
+}
+
+// source identifies the template text so that editing a template invalidates
+// summaries cached with it.
+func (p prompt) source() string {
+	if p.tmpl == nil || p.tmpl.Tree == nil {
+		return p.name
+	}
+	return p.name + ":" + p.tmpl.Tree.Root.String()
+}
+
+// newPrompt selects the template for the channel the posts belong to: the
+// channel's own selection first, then its team's, then DefaultTemplate.
+// Selection failures fall back to the default rather than failing the summary.
+// The default template and intermediate prompts are localized to the summary
+// language resolved from the requested one. The prompt also carries the files
+// attached to the posts, including images unless the summary is structured.
+func (s Service) newPrompt(ctx context.Context, posts []*model.Post, params Params) prompt {
+	language := s.resolveLanguage(params.Language, posts)
+	p := prompt{
+		name:     DefaultTemplate,
+		location: loadLocation(params.Timezone),
+		length:   params.Length,
+		tmpl:     builtinTemplates[language],
+		data: PromptData{
+			Language:     language,
+			LanguageName: languageNames[language],
+		},
+		builtin:     builtinPrompts[language],
+		attachments: s.loadAttachments(ctx, posts, !s.structured),
+	}
+	if len(posts) == 0 {
+		return p
+	}
+
+	channelID := posts[0].ChannelId
+	var teamID string
+	if s.channels != nil {
+		if channel, err := s.channels.Get(channelID); err == nil && channel != nil {
+			p.data.ChannelName = channel.DisplayName
+			teamID = channel.TeamId
+		}
+	}
+
+	if s.selections == nil {
+		return p
+	}
+	for _, scopeID := range []string{channelID, teamID} {
+		if scopeID == "" {
+			continue
+		}
+		name, err := s.selections.GetPromptTemplate(scopeID)
+		if err != nil || name == "" {
+			continue
+		}
+		if name == DefaultTemplate {
+			return p
+		}
+		if tmpl, ok := s.templates[name]; ok {
+			p.name, p.tmpl = name, tmpl
+			return p
+		}
+	}
+	return p
+}
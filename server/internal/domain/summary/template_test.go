@@ -0,0 +1,172 @@
+package summary
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChannels map[string]*model.Channel
+
+func (f fakeChannels) Get(channelID string) (*model.Channel, error) {
+	channel, ok := f[channelID]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return channel, nil
+}
+
+type fakeSelections map[string]string
+
+func (f fakeSelections) GetPromptTemplate(scopeID string) (string, error) {
+	return f[scopeID], nil
+}
+
+func TestParseTemplates(t *testing.T) {
+	tests := []struct {
+		name        string
+		definitions map[string]string
+		expectError bool
+		errorMsg    string
+	}{
+		{
+			name:        "should_add_default_template",
+			definitions: nil,
+		},
+		{
+			name:        "should_parse_valid_template",
+			definitions: map[string]string{"short": "Summarize {{.ChannelName}} for {{join .Participants \", \"}}:\n{{.Conversation}}"},
+		},
+		{
+			name:        "should_reject_reserved_name",
+			definitions: map[string]string{DefaultTemplate: "{{.Conversation}}"},
+			expectError: true,
+			errorMsg:    "reserved",
+		},
+		{
+			name:        "should_reject_name_with_spaces",
+			definitions: map[string]string{"two words": "{{.Conversation}}"},
+			expectError: true,
+			errorMsg:    "must be a single word",
+		},
+		{
+			name:        "should_reject_syntax_error",
+			definitions: map[string]string{"broken": "{{.Conversation"},
+			expectError: true,
+			errorMsg:    "failed to parse template",
+		},
+		{
+			name:        "should_reject_unknown_variable",
+			definitions: map[string]string{"unknown": "{{.Messages}}"},
+			expectError: true,
+			errorMsg:    "failed to render template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := ParseTemplates(tt.definitions)
+
+			if tt.expectError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, templates, DefaultTemplate)
+			assert.Len(t, templates, len(tt.definitions)+1)
+		})
+	}
+}
+
+func TestService_PromptTemplates(t *testing.T) {
+	users := fakeUsers{
+		"u1": {FirstName: "Ivan", LastName: "Petrov"},
+		"u2": {Username: "anna"},
+	}
+	channels := fakeChannels{
+		"channel1": {Id: "channel1", TeamId: "team1", DisplayName: "Town Square"},
+		"channel2": {Id: "channel2", TeamId: "team1", DisplayName: "Off-Topic"},
+	}
+	templates, err := ParseTemplates(map[string]string{
+		"brief": "BRIEF {{.ChannelName}} [{{join .Participants \", \"}}] {{.Language}}\n{{.Conversation}}",
+		"team":  "TEAM {{.ChannelName}}\n{{.Conversation}}",
+	})
+	require.NoError(t, err)
+
+	posts := func(channelID string) []*model.Post {
+		return []*model.Post{
+			{Id: "p1", ChannelId: channelID, UserId: "u1", Message: "hello"},
+			{Id: "p2", ChannelId: channelID, UserId: "u2", Message: "hi"},
+			{Id: "p3", ChannelId: channelID, UserId: "u1", Message: "bye"},
+		}
+	}
+
+	t.Run("should_render_channel_template", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users,
+			WithChannels(channels),
+			WithPromptTemplates(templates, fakeSelections{"channel1": "brief", "team1": "team"}),
+			WithLanguage("en"),
+		)
+
+		_, err := service.GenerateSummary(context.Background(), posts("channel1"))
+
+		require.NoError(t, err)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "BRIEF Town Square [Ivan Petrov, anna] en\n")
+		assert.Contains(t, llm.prompts[0], "Ivan Petrov: hello")
+	})
+
+	t.Run("should_fall_back_to_team_template", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users,
+			WithChannels(channels),
+			WithPromptTemplates(templates, fakeSelections{"channel1": "brief", "team1": "team"}),
+		)
+
+		_, err := service.GenerateSummary(context.Background(), posts("channel2"))
+
+		require.NoError(t, err)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "TEAM Off-Topic")
+	})
+
+	t.Run("should_use_default_template_when_selection_is_removed", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users,
+			WithChannels(channels),
+			WithPromptTemplates(templates, fakeSelections{"channel1": "deleted"}),
+			WithLanguage(LanguageRussian),
+		)
+
+		_, err := service.GenerateSummary(context.Background(), posts("channel1"))
+
+		require.NoError(t, err)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "ПЕРЕПИСКА:")
+	})
+
+	t.Run("should_not_reuse_cached_summary_of_other_template", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		cache := newMemCache()
+		selections := fakeSelections{}
+		service := NewService(llm, users,
+			WithCache(cache),
+			WithChannels(channels),
+			WithPromptTemplates(templates, selections),
+		)
+
+		_, err := service.GenerateSummary(context.Background(), posts("channel1"))
+		require.NoError(t, err)
+		selections["channel1"] = "brief"
+		_, err = service.GenerateSummary(context.Background(), posts("channel1"))
+		require.NoError(t, err)
+
+		assert.Len(t, llm.prompts, 2)
+	})
+}
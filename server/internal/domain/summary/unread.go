@@ -0,0 +1,47 @@
+package summary
+
+import (
+	"context"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// GenerateUnreadSummaryStream summarizes the posts a user has not read yet.
+// When caching is enabled, the user's previous summary of the channel is used
+// as context for the earlier history, and the result is stored to serve as
+// context for the next call.
+func (s Service) GenerateUnreadSummaryStream(ctx context.Context, userID, channelID string, posts []*model.Post, params Params, onChunk func(chunk string) error) (Summary, error) {
+	firstCreateAt, lastCreateAt := postsTimeRange(posts)
+	p := s.newPrompt(ctx, posts, params)
+
+	var previous string
+	if s.cache != nil {
+		summary, lastPostAt, err := s.cache.GetLatestSummary(userID, channelID)
+		if err == nil && lastPostAt <= firstCreateAt {
+			previous = summary
+		}
+	}
+
+	summary, err := s.generateSummary(ctx, p, previous, posts, onChunk)
+	if err != nil {
+		return Summary{}, err
+	}
+	// The summary is stored with linked citations: its numbers would refer
+	// to other messages when it is used as context for the next call.
+	summary = s.linkCitations(summary, citationsOf(posts))
+
+	if s.cache != nil {
+		_ = s.cache.SetLatestSummary(userID, channelID, summary.Text, lastCreateAt)
+	}
+	return summary, nil
+}
+
+func postsTimeRange(posts []*model.Post) (first, last int64) {
+	for i, post := range posts {
+		if i == 0 || post.CreateAt < first {
+			first = post.CreateAt
+		}
+		last = max(last, post.CreateAt)
+	}
+	return first, last
+}
@@ -0,0 +1,63 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_GenerateUnreadSummaryStream(t *testing.T) {
+	users := fakeUsers{"u1": {FirstName: "Ivan"}}
+	onChunk := func(string) error { return nil }
+
+	t.Run("should_summarize_without_cache", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		service := NewService(llm, users, WithLanguage(LanguageRussian))
+
+		result, err := service.GenerateUnreadSummaryStream(context.Background(), "u1", "c1",
+			[]*model.Post{{UserId: "u1", Message: "hi", CreateAt: 10}}, Params{}, onChunk)
+
+		require.NoError(t, err)
+		assert.Equal(t, "summary", result.Text)
+		require.Len(t, llm.prompts, 1)
+		assert.NotContains(t, llm.prompts[0], "ПРЕДЫДУЩЕЕ РЕЗЮМЕ")
+	})
+
+	t.Run("should_build_on_previous_summary", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		cache := newMemCache()
+		require.NoError(t, cache.SetLatestSummary("u1", "c1", "earlier decisions", 5))
+		service := NewService(llm, users, WithCache(cache), WithLanguage(LanguageRussian))
+
+		_, err := service.GenerateUnreadSummaryStream(context.Background(), "u1", "c1",
+			[]*model.Post{
+				{UserId: "u1", Message: "hi", CreateAt: 10},
+				{UserId: "u1", Message: "bye", CreateAt: 20},
+			}, Params{}, onChunk)
+
+		require.NoError(t, err)
+		require.Len(t, llm.prompts, 1)
+		assert.Contains(t, llm.prompts[0], "ПРЕДЫДУЩЕЕ РЕЗЮМЕ:\nearlier decisions")
+
+		summary, lastPostAt, err := cache.GetLatestSummary("u1", "c1")
+		require.NoError(t, err)
+		assert.Equal(t, "summary", summary)
+		assert.Equal(t, int64(20), lastPostAt)
+	})
+
+	t.Run("should_ignore_previous_summary_overlapping_unread_posts", func(t *testing.T) {
+		llm := &fakeLLM{contextSize: 64000}
+		cache := newMemCache()
+		require.NoError(t, cache.SetLatestSummary("u1", "c1", "overlapping", 15))
+		service := NewService(llm, users, WithCache(cache))
+
+		_, err := service.GenerateUnreadSummaryStream(context.Background(), "u1", "c1",
+			[]*model.Post{{UserId: "u1", Message: "hi", CreateAt: 10}}, Params{}, onChunk)
+
+		require.NoError(t, err)
+		assert.NotContains(t, llm.prompts[0], "overlapping")
+	})
+}
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+)
+
+type (
+	store interface {
+		SaveJob(job *Job) error
+		GetJob(id string) (*Job, error)
+		PushJobID(id string) error
+		// PopJobID removes and returns the oldest queued job ID or "" if the queue is empty.
+		PopJobID() (string, error)
+		// AddRunningJobID, RemoveRunningJobID and ListRunningJobIDs track the
+		// jobs claimed by workers.
+		AddRunningJobID(id string) error
+		RemoveRunningJobID(id string) error
+		ListRunningJobIDs() ([]string, error)
+	}
+	locker interface {
+		LockWithContext(ctx context.Context) error
+		Unlock()
+	}
+	logger interface {
+		Error(message string, keyValuePairs ...any)
+	}
+)
+
+// Processor executes a job and returns its result.
+type Processor func(ctx context.Context, job *Job) (string, error)
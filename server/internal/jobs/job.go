@@ -0,0 +1,29 @@
+package jobs
+
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+// Job is a unit of background work persisted in the KV store until it is processed.
+type Job struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	UserID    string            `json:"user_id"`
+	Payload   map[string]string `json:"payload"`
+	Status    Status            `json:"status"`
+	Result    string            `json:"result,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	CreatedAt int64             `json:"created_at"`
+	UpdatedAt int64             `json:"updated_at"`
+	// StartedAt is when a worker last claimed the job, Deadline when that
+	// attempt times out and Attempts how many times it was claimed, so that
+	// jobs left running by a node that went away can be recovered.
+	StartedAt int64 `json:"started_at,omitempty"`
+	Deadline  int64 `json:"deadline,omitempty"`
+	Attempts  int   `json:"attempts,omitempty"`
+}
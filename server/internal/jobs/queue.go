@@ -0,0 +1,355 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	defaultWorkers = 2
+	defaultTimeout = 5 * time.Minute
+
+	// staleGrace is how long past the timeout a running job is given to save
+	// its result before Sweep considers its node gone.
+	staleGrace = time.Minute
+	// maxAttempts is how many times a job is claimed before a stalled job is
+	// marked as failed rather than queued again.
+	maxAttempts = 2
+)
+
+type Option func(q *Queue) (*Queue, error)
+
+func WithWorkers(n int) Option {
+	return func(q *Queue) (*Queue, error) {
+		if n <= 0 {
+			return q, fmt.Errorf("workers must be greater than 0, got %d", n)
+		}
+		q.workers = n
+		return q, nil
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(q *Queue) (*Queue, error) {
+		return q, q.SetTimeout(timeout)
+	}
+}
+
+// Queue is a cluster-safe job queue backed by the plugin KV store. Jobs are
+// claimed under a cluster mutex so every job is processed by exactly one
+// worker across all plugin instances, while a bounded number of local
+// workers limits concurrent LLM calls on each node.
+type Queue struct {
+	store   store
+	mutex   locker
+	log     logger
+	workers int
+	// timeout is the time.Duration a job may run; it changes with the
+	// configuration.
+	timeout atomic.Int64
+
+	processorsLock sync.RWMutex
+	processors     map[string]Processor
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(store store, mutex locker, log logger, options ...Option) (*Queue, error) {
+	q := &Queue{
+		store:      store,
+		mutex:      mutex,
+		log:        log,
+		workers:    defaultWorkers,
+		processors: map[string]Processor{},
+	}
+	q.timeout.Store(int64(defaultTimeout))
+
+	for _, opt := range options {
+		var err error
+		q, err = opt(q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	q.wake = make(chan struct{}, q.workers)
+
+	return q, nil
+}
+
+// SetTimeout changes how long jobs may run. Jobs already running keep the
+// deadline they were claimed with.
+func (q *Queue) SetTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("timeout must be greater than 0, got %v", timeout)
+	}
+	q.timeout.Store(int64(timeout))
+	return nil
+}
+
+// Timeout returns how long jobs claimed now may run.
+func (q *Queue) Timeout() time.Duration {
+	return time.Duration(q.timeout.Load())
+}
+
+// Register sets the processor for jobs of the given type.
+func (q *Queue) Register(jobType string, processor Processor) {
+	q.processorsLock.Lock()
+	defer q.processorsLock.Unlock()
+
+	q.processors[jobType] = processor
+}
+
+// Enqueue persists the job and wakes up a local worker to process it.
+func (q *Queue) Enqueue(job *Job) error {
+	now := model.GetMillis()
+	job.ID = model.NewId()
+	job.Status = StatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	if err := q.store.SaveJob(job); err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+
+	if err := q.store.PushJobID(job.ID); err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	q.Wake()
+	return nil
+}
+
+// Get returns the job with the given ID or nil if it does not exist or has expired.
+func (q *Queue) Get(id string) (*Job, error) {
+	job, err := q.store.GetJob(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// Wake signals the local workers to check the queue for pending jobs.
+func (q *Queue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches the worker pool.
+func (q *Queue) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+}
+
+// Close stops the workers and waits for them to exit. Running jobs are cancelled.
+func (q *Queue) Close() {
+	if q.cancel == nil {
+		return
+	}
+	q.cancel()
+	q.wg.Wait()
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		q.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		}
+	}
+}
+
+func (q *Queue) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := q.claim(ctx)
+		if err != nil {
+			q.log.Error("Failed to claim job", "error", err.Error())
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		q.run(ctx, job)
+	}
+}
+
+// claim pops the next pending job and marks it as running.
+func (q *Queue) claim(ctx context.Context) (*Job, error) {
+	if err := q.mutex.LockWithContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to lock queue: %w", err)
+	}
+	defer q.mutex.Unlock()
+
+	for {
+		id, err := q.store.PopJobID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to pop job: %w", err)
+		}
+		if id == "" {
+			return nil, nil
+		}
+
+		job, err := q.store.GetJob(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get job %s: %w", id, err)
+		}
+		// The job has expired or was already picked up.
+		if job == nil || job.Status != StatusPending {
+			continue
+		}
+
+		now := model.GetMillis()
+		job.Status = StatusRunning
+		job.StartedAt = now
+		job.Deadline = now + q.Timeout().Milliseconds()
+		job.UpdatedAt = now
+		job.Attempts++
+		if err := q.store.SaveJob(job); err != nil {
+			return nil, fmt.Errorf("failed to save job %s: %w", id, err)
+		}
+		if err := q.store.AddRunningJobID(id); err != nil {
+			return nil, fmt.Errorf("failed to track job %s: %w", id, err)
+		}
+
+		return job, nil
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job *Job) {
+	q.processorsLock.RLock()
+	processor, ok := q.processors[job.Type]
+	q.processorsLock.RUnlock()
+
+	var (
+		result string
+		err    error
+	)
+	if ok {
+		result, err = q.process(ctx, processor, job)
+	} else {
+		err = fmt.Errorf("no processor registered for job type %q", job.Type)
+	}
+
+	job.UpdatedAt = model.GetMillis()
+	if err != nil {
+		q.log.Error("Job failed", "job_id", job.ID, "type", job.Type, "error", err.Error())
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusDone
+		job.Result = result
+	}
+
+	if err := q.store.SaveJob(job); err != nil {
+		q.log.Error("Failed to save job", "job_id", job.ID, "error", err.Error())
+		return
+	}
+	if err := q.store.RemoveRunningJobID(job.ID); err != nil {
+		q.log.Error("Failed to untrack job", "job_id", job.ID, "error", err.Error())
+	}
+}
+
+// Sweep recovers jobs that are still running past their deadline, which
+// happens when the node running them stops mid-job. Such jobs are
+// queued again, or marked as failed once they have used up maxAttempts.
+func (q *Queue) Sweep(ctx context.Context) error {
+	if err := q.mutex.LockWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to lock queue: %w", err)
+	}
+	defer q.mutex.Unlock()
+
+	ids, err := q.store.ListRunningJobIDs()
+	if err != nil {
+		return fmt.Errorf("failed to list running jobs: %w", err)
+	}
+
+	staleBefore := model.GetMillis() - staleGrace.Milliseconds()
+	requeued := false
+	for _, id := range ids {
+		job, err := q.store.GetJob(id)
+		if err != nil {
+			return fmt.Errorf("failed to get job %s: %w", id, err)
+		}
+		if job != nil && job.Status == StatusRunning {
+			deadline := job.Deadline
+			if deadline == 0 {
+				// Claimed before deadlines were recorded.
+				deadline = job.StartedAt + q.Timeout().Milliseconds()
+			}
+			if deadline > staleBefore {
+				continue
+			}
+			queued, err := q.recover(job)
+			if err != nil {
+				return err
+			}
+			requeued = requeued || queued
+		}
+		// The job has finished, expired or was recovered.
+		if err := q.store.RemoveRunningJobID(id); err != nil {
+			return fmt.Errorf("failed to untrack job %s: %w", id, err)
+		}
+	}
+
+	if requeued {
+		q.Wake()
+	}
+	return nil
+}
+
+// recover queues a stalled job again or marks it as failed, and reports
+// whether it was queued.
+func (q *Queue) recover(job *Job) (bool, error) {
+	job.UpdatedAt = model.GetMillis()
+	if job.Attempts >= maxAttempts {
+		q.log.Error("Job stalled", "job_id", job.ID, "type", job.Type, "attempts", job.Attempts)
+		job.Status = StatusFailed
+		job.Error = "job did not finish in time"
+		if err := q.store.SaveJob(job); err != nil {
+			return false, fmt.Errorf("failed to save job %s: %w", job.ID, err)
+		}
+		return false, nil
+	}
+
+	job.Status = StatusPending
+	if err := q.store.SaveJob(job); err != nil {
+		return false, fmt.Errorf("failed to save job %s: %w", job.ID, err)
+	}
+	if err := q.store.PushJobID(job.ID); err != nil {
+		return false, fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+	}
+	return true, nil
+}
+
+func (q *Queue) process(ctx context.Context, processor Processor, job *Job) (result string, err error) {
+	ctx, cancel := context.WithDeadline(ctx, time.UnixMilli(job.Deadline))
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return processor(ctx, job)
+}
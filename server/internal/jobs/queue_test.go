@@ -0,0 +1,353 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	mu      sync.Mutex
+	jobs    map[string]Job
+	queue   []string
+	running []string
+}
+
+func newMemStore() *memStore {
+	return &memStore{jobs: map[string]Job{}}
+}
+
+func (s *memStore) SaveJob(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *memStore) GetJob(id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (s *memStore) PushJobID(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, id)
+	return nil
+}
+
+func (s *memStore) PopJobID() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return "", nil
+	}
+	id := s.queue[0]
+	s.queue = s.queue[1:]
+	return id, nil
+}
+
+func (s *memStore) AddRunningJobID(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = append(s.running, id)
+	return nil
+}
+
+func (s *memStore) RemoveRunningJobID(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = slices.DeleteFunc(s.running, func(running string) bool { return running == id })
+	return nil
+}
+
+func (s *memStore) ListRunningJobIDs() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.running), nil
+}
+
+type localMutex struct {
+	sync.Mutex
+}
+
+func (m *localMutex) LockWithContext(context.Context) error {
+	m.Lock()
+	return nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Error(string, ...any) {}
+
+func waitForStatus(t *testing.T, q *Queue, id string, status Status) *Job {
+	t.Helper()
+
+	var job *Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = q.Get(id)
+		return err == nil && job != nil && job.Status == status
+	}, time.Second, 5*time.Millisecond)
+	return job
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(newMemStore(), &localMutex{}, nopLogger{}, WithWorkers(0))
+	assert.Error(t, err)
+
+	_, err = New(newMemStore(), &localMutex{}, nopLogger{}, WithTimeout(0))
+	assert.Error(t, err)
+
+	q, err := New(newMemStore(), &localMutex{}, nopLogger{}, WithWorkers(3), WithTimeout(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 3, q.workers)
+	assert.Equal(t, time.Second, q.Timeout())
+	assert.Error(t, q.SetTimeout(0))
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("should_process_job_and_store_result", func(t *testing.T) {
+		q, err := New(newMemStore(), &localMutex{}, nopLogger{})
+		require.NoError(t, err)
+		q.Register("echo", func(_ context.Context, job *Job) (string, error) {
+			return job.Payload["text"], nil
+		})
+		q.Start()
+		defer q.Close()
+
+		job := &Job{Type: "echo", UserID: "user1", Payload: map[string]string{"text": "hello"}}
+		require.NoError(t, q.Enqueue(job))
+		assert.NotEmpty(t, job.ID)
+
+		done := waitForStatus(t, q, job.ID, StatusDone)
+		assert.Equal(t, "hello", done.Result)
+		assert.Equal(t, "user1", done.UserID)
+	})
+
+	t.Run("should_mark_failed_jobs", func(t *testing.T) {
+		q, err := New(newMemStore(), &localMutex{}, nopLogger{})
+		require.NoError(t, err)
+		q.Register("fail", func(context.Context, *Job) (string, error) {
+			return "", errors.New("boom")
+		})
+		q.Start()
+		defer q.Close()
+
+		failing := &Job{Type: "fail"}
+		unknown := &Job{Type: "unknown"}
+		require.NoError(t, q.Enqueue(failing))
+		require.NoError(t, q.Enqueue(unknown))
+
+		assert.Equal(t, "boom", waitForStatus(t, q, failing.ID, StatusFailed).Error)
+		assert.Contains(t, waitForStatus(t, q, unknown.ID, StatusFailed).Error, "no processor registered")
+	})
+
+	t.Run("should_recover_from_panics", func(t *testing.T) {
+		q, err := New(newMemStore(), &localMutex{}, nopLogger{})
+		require.NoError(t, err)
+		q.Register("panic", func(context.Context, *Job) (string, error) {
+			panic("oops")
+		})
+		q.Start()
+		defer q.Close()
+
+		job := &Job{Type: "panic"}
+		require.NoError(t, q.Enqueue(job))
+
+		assert.Contains(t, waitForStatus(t, q, job.ID, StatusFailed).Error, "oops")
+	})
+
+	t.Run("should_bound_concurrency", func(t *testing.T) {
+		q, err := New(newMemStore(), &localMutex{}, nopLogger{}, WithWorkers(2))
+		require.NoError(t, err)
+
+		var (
+			mu      sync.Mutex
+			running int
+			peak    int
+		)
+		q.Register("slow", func(context.Context, *Job) (string, error) {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return "", nil
+		})
+
+		ids := make([]string, 0, 6)
+		for i := 0; i < 6; i++ {
+			job := &Job{Type: "slow"}
+			require.NoError(t, q.Enqueue(job))
+			ids = append(ids, job.ID)
+		}
+
+		q.Start()
+		defer q.Close()
+
+		for _, id := range ids {
+			waitForStatus(t, q, id, StatusDone)
+		}
+		assert.LessOrEqual(t, peak, 2)
+	})
+
+	t.Run("should_skip_jobs_that_are_not_pending", func(t *testing.T) {
+		store := newMemStore()
+		q, err := New(store, &localMutex{}, nopLogger{})
+		require.NoError(t, err)
+
+		require.NoError(t, store.PushJobID("missing"))
+		require.NoError(t, store.SaveJob(&Job{ID: "done", Status: StatusDone}))
+		require.NoError(t, store.PushJobID("done"))
+
+		job, err := q.claim(context.Background())
+		require.NoError(t, err)
+		assert.Nil(t, job)
+	})
+
+	t.Run("should_cancel_running_jobs_on_close", func(t *testing.T) {
+		q, err := New(newMemStore(), &localMutex{}, nopLogger{})
+		require.NoError(t, err)
+
+		started := make(chan struct{})
+		q.Register("block", func(ctx context.Context, _ *Job) (string, error) {
+			close(started)
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		q.Start()
+
+		job := &Job{Type: "block"}
+		require.NoError(t, q.Enqueue(job))
+		<-started
+		q.Close()
+
+		stored, err := q.Get(job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, stored.Status)
+	})
+	t.Run("should_untrack_finished_jobs", func(t *testing.T) {
+		store := newMemStore()
+		q, err := New(store, &localMutex{}, nopLogger{})
+		require.NoError(t, err)
+		q.Register("echo", func(context.Context, *Job) (string, error) { return "", nil })
+		q.Start()
+		defer q.Close()
+
+		job := &Job{Type: "echo"}
+		require.NoError(t, q.Enqueue(job))
+		done := waitForStatus(t, q, job.ID, StatusDone)
+
+		assert.Equal(t, 1, done.Attempts)
+		assert.NotZero(t, done.StartedAt)
+		require.Eventually(t, func() bool {
+			running, _ := store.ListRunningJobIDs()
+			return len(running) == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestQueue_SetTimeout(t *testing.T) {
+	store := newMemStore()
+	q, err := New(store, &localMutex{}, nopLogger{}, WithTimeout(time.Minute))
+	require.NoError(t, err)
+	require.NoError(t, store.SaveJob(&Job{ID: "job1", Status: StatusPending}))
+	require.NoError(t, store.PushJobID("job1"))
+
+	require.NoError(t, q.SetTimeout(time.Hour))
+	job, err := q.claim(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour.Milliseconds(), job.Deadline-job.StartedAt)
+}
+
+func TestQueue_Sweep(t *testing.T) {
+	const timeout = time.Minute
+	stale := model.GetMillis() - (timeout + staleGrace + time.Second).Milliseconds()
+
+	tests := []struct {
+		name            string
+		job             *Job
+		expectedStatus  Status
+		expectedQueue   []string
+		expectedRunning []string
+	}{
+		{
+			name:           "should_requeue_job_left_running_by_a_stopped_node",
+			job:            &Job{ID: "job1", Status: StatusRunning, StartedAt: stale, Attempts: 1},
+			expectedStatus: StatusPending,
+			expectedQueue:  []string{"job1"},
+		},
+		{
+			name:           "should_fail_job_that_stalled_again",
+			job:            &Job{ID: "job1", Status: StatusRunning, StartedAt: stale, Attempts: maxAttempts},
+			expectedStatus: StatusFailed,
+		},
+		{
+			name:            "should_keep_job_that_is_still_running",
+			job:             &Job{ID: "job1", Status: StatusRunning, StartedAt: model.GetMillis(), Attempts: 1},
+			expectedStatus:  StatusRunning,
+			expectedRunning: []string{"job1"},
+		},
+		{
+			name:            "should_keep_job_within_the_deadline_it_was_claimed_with",
+			job:             &Job{ID: "job1", Status: StatusRunning, StartedAt: stale, Deadline: model.GetMillis() + time.Hour.Milliseconds(), Attempts: 1},
+			expectedStatus:  StatusRunning,
+			expectedRunning: []string{"job1"},
+		},
+		{
+			name:           "should_untrack_finished_job",
+			job:            &Job{ID: "job1", Status: StatusDone, StartedAt: stale, Attempts: 1},
+			expectedStatus: StatusDone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemStore()
+			require.NoError(t, store.SaveJob(tt.job))
+			require.NoError(t, store.AddRunningJobID(tt.job.ID))
+			q, err := New(store, &localMutex{}, nopLogger{}, WithTimeout(timeout))
+			require.NoError(t, err)
+
+			require.NoError(t, q.Sweep(context.Background()))
+
+			job, err := store.GetJob(tt.job.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, job.Status)
+			assert.ElementsMatch(t, tt.expectedQueue, store.queue)
+			running, _ := store.ListRunningJobIDs()
+			assert.ElementsMatch(t, tt.expectedRunning, running)
+		})
+	}
+
+	t.Run("should_untrack_expired_job", func(t *testing.T) {
+		store := newMemStore()
+		require.NoError(t, store.AddRunningJobID("expired"))
+		q, err := New(store, &localMutex{}, nopLogger{})
+		require.NoError(t, err)
+
+		require.NoError(t, q.Sweep(context.Background()))
+
+		running, _ := store.ListRunningJobIDs()
+		assert.Empty(t, running)
+	})
+}
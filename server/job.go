@@ -1,6 +1,36 @@
 package main
 
-// func (p *Plugin) runJob() {
-// 	// Include job logic here
-// 	// p.API.LogInfo("Job is currently running")
-// }
+import (
+	"context"
+	"time"
+)
+
+// jobTimeoutFactor scales RequestTimeout into the time budget of a whole
+// summary job, which may issue several LLM requests (map-reduce).
+const jobTimeoutFactor = 10
+
+// jobTimeout returns how long a summary job may run with the configuration.
+func jobTimeout(c *configuration) time.Duration {
+	return time.Duration(c.RequestTimeout) * time.Second * jobTimeoutFactor
+}
+
+// runJob is scheduled on a single node of the cluster and makes sure jobs
+// enqueued on nodes that went away are eventually processed, and jobs left
+// running by them are recovered.
+func (p *Plugin) runJob() {
+	if p.jobQueue == nil {
+		return
+	}
+	if err := p.jobQueue.Sweep(context.Background()); err != nil {
+		p.API.LogError("Failed to sweep job queue", "err", err.Error())
+	}
+	p.jobQueue.Wake()
+}
+
+// runDigests is scheduled on a single node of the cluster and enqueues the
+// channel digests that are due.
+func (p *Plugin) runDigests() {
+	if command := p.getCommand(); command != nil {
+		command.EnqueueDueDigests(time.Now())
+	}
+}
@@ -1,37 +1,81 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
-	"github.com/EgorTarasov/summary/server/infrustructure/llm/ollama"
+	"github.com/EgorTarasov/summary/server/infrustructure/llm/registry"
 	summaryCommand "github.com/EgorTarasov/summary/server/internal/commands/summary"
 	"github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+	"github.com/EgorTarasov/summary/server/store/kvstore"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+)
+
+const (
+	botUsername    = "summary"
+	botDisplayName = "Summary"
+	botDescription = "Generates summaries of threads and channels using LLMs."
+
+	jobQueueMutexKey  = "job_queue_mutex"
+	jobQueueSweepKey  = "JobQueueSweep"
+	jobQueueSweepTime = time.Minute
+
+	digestSchedulerKey      = "DigestScheduler"
+	digestSchedulerInterval = time.Minute
 )
 
 type Command interface {
 	Handle(args *model.CommandArgs) (*model.CommandResponse, error)
+	RequestSummary(request summaryCommand.SummaryRequest) (*jobs.Job, error)
+	HandleAction(userID string, request *model.PostActionIntegrationRequest) *model.PostActionIntegrationResponse
+	EnqueueDueDigests(now time.Time)
 }
 
 // Plugin implements the interface expected by the Mattermost server to communicate between the server and plugin processes.
 type Plugin struct {
 	plugin.MattermostPlugin
 
-	// // kvstore is the client used to read/write KV records for this plugin.
-	// kvstore kvstore.KVStore
+	// kvstore is the client used to read/write KV records for this plugin.
+	kvstore kvstore.KVStore
 
 	// client is the Mattermost server API client.
-	// client *pluginapi.Client
+	client *pluginapi.Client
 
-	// commandClient is the client used to register and execute slash commands.
+	// llm forwards LLM requests to the provider built from the active configuration.
+	llm *registry.Registry
+
+	// commandLock synchronizes access to commandClient, which is rebuilt on
+	// configuration changes.
+	commandLock sync.RWMutex
+
+	// commandClient executes slash commands and summary requests from the REST API.
 	commandClient Command
 
-	// backgroundJob *cluster.Job
+	// reloadLock serializes rebuilding the provider and summary handler.
+	reloadLock sync.Mutex
+
+	// authorizer checks that users can read the channels they summarize.
+	authorizer *summary.Authorizer
+
+	// botID is the user ID of the bot that authors summary posts.
+	botID string
+
+	// jobQueue processes summary requests in the background.
+	jobQueue *jobs.Queue
+
+	// backgroundJob periodically wakes up the job queue on one node of the cluster.
+	backgroundJob *cluster.Job
+
+	// digestJob periodically enqueues due channel digests on one node of the cluster.
+	digestJob *cluster.Job
 
 	// configurationLock synchronizes access to the configuration.
 	configurationLock sync.RWMutex
@@ -43,6 +87,9 @@ type Plugin struct {
 
 // OnActivate is invoked when the plugin is activated. If an error is returned, the plugin will be deactivated.
 func (p *Plugin) OnActivate() error {
+	p.reloadLock.Lock()
+	defer p.reloadLock.Unlock()
+
 	client := pluginapi.NewClient(p.API, p.Driver)
 	client.Log.Info("Plugin activation started")
 
@@ -54,66 +101,122 @@ func (p *Plugin) OnActivate() error {
 
 	c.SetDefaults()
 
-	client.Log.Info("ollama configuration", "model", c.OllamaModel, "baseURL", c.OllamaURL, "provider", c.LLMProvider)
+	client.Log.Info("llm configuration", "provider", c.LLMProvider, "ollamaModel", c.OllamaModel, "ollamaURL", c.OllamaURL, "openaiModel", c.OpenAIModel, "openaiBaseURL", c.OpenAIBaseURL)
 
 	if err := c.IsValid(); err != nil {
 		client.Log.Error("Invalid plugin configuration", "error", err.Error())
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	ollamaProvider, err := ollama.New(
-		ollama.WithHost(c.OllamaURL),
-		ollama.WithModel(c.OllamaModel),
-	)
+	provider, err := newProvider(c, &client.Log)
 	if err != nil {
-		client.Log.Error("Failed to initialize Ollama provider", "error", err.Error())
-		return fmt.Errorf("failed to init ollama: %w", err)
+		client.Log.Error("Failed to initialize LLM provider", "provider", c.LLMProvider, "error", err.Error())
+		return fmt.Errorf("failed to init llm provider: %w", err)
 	}
 
-	client.Log.Info("Ollama provider initialized successfully")
+	p.llm, err = registry.New(provider)
+	if err != nil {
+		return fmt.Errorf("failed to create provider registry: %w", err)
+	}
 
-	summaryService := summary.NewService(ollamaProvider, &client.User)
+	client.Log.Info("LLM provider initialized successfully", "provider", c.LLMProvider)
 
-	summaryHandler := summaryCommand.New(client, summaryService)
-	p.commandClient = summaryHandler
+	botID, err := client.Bot.EnsureBot(&model.Bot{
+		Username:    botUsername,
+		DisplayName: botDisplayName,
+		Description: botDescription,
+	})
+	if err != nil {
+		client.Log.Error("Failed to ensure bot", "error", err.Error())
+		return fmt.Errorf("failed to ensure bot: %w", err)
+	}
+	p.botID = botID
+	p.client = client
 
-	client.Log.Info("Plugin activated successfully")
+	p.kvstore = kvstore.NewKVStore(client)
+	p.authorizer = summary.NewAuthorizer(&client.User, &client.Channel, &client.Post)
+
+	mutex, err := cluster.NewMutex(p.API, jobQueueMutexKey)
+	if err != nil {
+		return fmt.Errorf("failed to create job queue mutex: %w", err)
+	}
+
+	p.jobQueue, err = jobs.New(p.kvstore, mutex, &client.Log,
+		jobs.WithTimeout(jobTimeout(c)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create job queue: %w", err)
+	}
+
+	templates, err := c.promptTemplates()
+	if err != nil {
+		return fmt.Errorf("failed to parse prompt templates: %w", err)
+	}
+	p.setCommand(p.newSummaryHandler(c, templates))
 
-	// p.kvstore = kvstore.NewKVStore(p.client)
+	job, err := cluster.Schedule(
+		p.API,
+		jobQueueSweepKey,
+		cluster.MakeWaitForInterval(jobQueueSweepTime),
+		p.runJob,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to schedule background job: %w", err)
+	}
 
-	// job, err := cluster.Schedule(
-	// 	p.API,
-	// 	"BackgroundJob",
-	// 	cluster.MakeWaitForRoundedInterval(1*time.Hour),
-	// 	p.runJob,
-	// )
-	// if err != nil {
-	// 	return errors.Wrap(err, "failed to schedule background job")
-	// }
+	p.backgroundJob = job
 
-	// p.backgroundJob = job
+	p.digestJob, err = cluster.Schedule(
+		p.API,
+		digestSchedulerKey,
+		cluster.MakeWaitForInterval(digestSchedulerInterval),
+		p.runDigests,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to schedule digest job: %w", err)
+	}
+
+	p.jobQueue.Start()
+
+	client.Log.Info("Plugin activated successfully")
 
 	return nil
 }
 
 // OnDeactivate is invoked when the plugin is deactivated.
 func (p *Plugin) OnDeactivate() error {
-	// if p.backgroundJob != nil {
-	// 	if err := p.backgroundJob.Close(); err != nil {
-	// 		p.API.LogError("Failed to close background job", "err", err)
-	// 	}
-	// }
+	if p.llm != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), providerDrainTimeout)
+		defer cancel()
+		if err := p.llm.Wait(ctx); err != nil {
+			p.API.LogWarn("Retired LLM providers did not finish in time", "error", err.Error())
+		}
+	}
+	if p.backgroundJob != nil {
+		if err := p.backgroundJob.Close(); err != nil {
+			p.API.LogError("Failed to close background job", "err", err)
+		}
+	}
+	if p.digestJob != nil {
+		if err := p.digestJob.Close(); err != nil {
+			p.API.LogError("Failed to close digest job", "err", err)
+		}
+	}
+	if p.jobQueue != nil {
+		p.jobQueue.Close()
+	}
 	return nil
 }
 
 // This will execute the commands that were registered in the NewCommandHandler function.
 func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*model.CommandResponse, *model.AppError) {
-	if p.commandClient == nil {
+	commandClient := p.getCommand()
+	if commandClient == nil {
 		p.API.LogError("Command client is not initialized")
 		return nil, model.NewAppError("ExecuteCommand", "plugin.command.not_initialized", nil, "command client not initialized", http.StatusInternalServerError)
 	}
 
-	response, err := p.commandClient.Handle(args)
+	response, err := commandClient.Handle(args)
 	if err != nil {
 		p.API.LogError("Failed to execute command", "error", err.Error(), "command", args.Command)
 		return nil, model.NewAppError("ExecuteCommand", "plugin.command.execute_command.app_error", nil, err.Error(), http.StatusInternalServerError)
@@ -132,4 +235,32 @@ func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
 	// Add any message post-processing logic here if needed
 }
 
+// MessageHasBeenUpdated is called after a message has been edited.
+func (p *Plugin) MessageHasBeenUpdated(c *plugin.Context, newPost, oldPost *model.Post) {
+	p.invalidateSummaries(newPost)
+}
+
+// MessageHasBeenDeleted is called after a message has been deleted.
+func (p *Plugin) MessageHasBeenDeleted(c *plugin.Context, post *model.Post) {
+	p.invalidateSummaries(post)
+}
+
+// invalidateSummaries drops cached summaries of the channel and thread the post belongs to.
+func (p *Plugin) invalidateSummaries(post *model.Post) {
+	if p.kvstore == nil {
+		return
+	}
+
+	scopes := []string{post.ChannelId, post.Id}
+	if post.RootId != "" {
+		scopes[1] = post.RootId
+	}
+
+	for _, scopeID := range scopes {
+		if err := p.kvstore.InvalidateSummaries(scopeID); err != nil {
+			p.API.LogError("Failed to invalidate cached summaries", "scope_id", scopeID, "error", err.Error())
+		}
+	}
+}
+
 // See https://developers.mattermost.com/extend/plugins/server/reference/
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm"
+	"github.com/EgorTarasov/summary/server/infrustructure/llm/ollama"
+	"github.com/EgorTarasov/summary/server/infrustructure/llm/openai"
+	"github.com/EgorTarasov/summary/server/infrustructure/llm/resilient"
+	"github.com/EgorTarasov/summary/server/infrustructure/ptr"
+)
+
+const (
+	providerOllama = "ollama"
+	providerOpenAI = "openai"
+
+	// modelPullTimeout bounds downloading a missing model, which takes far
+	// longer than a regular request.
+	modelPullTimeout = 30 * time.Minute
+)
+
+// modelChecker is implemented by providers that can verify that the
+// configured model exists on the server.
+type modelChecker interface {
+	EnsureModel(ctx context.Context) error
+}
+
+type warnLogger interface {
+	Warn(message string, keyValuePairs ...any)
+}
+
+// newProvider builds the llm.Provider selected by the LLMProvider setting,
+// wrapped with timeouts, retries and a circuit breaker. It fails if the
+// provider reports that the configured model is not available, and warns if
+// images are enabled for a provider that cannot pass them to the model.
+func newProvider(c *configuration, log warnLogger) (llm.Provider, error) {
+	provider, err := newBaseProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// The resilient wrapper implements llm.ImageProvider for any provider, so
+	// the provider is checked before it is wrapped.
+	if _, ok := provider.(llm.ImageProvider); !ok && ptr.Get(c.MaxImages) > 0 {
+		log.Warn("LLM provider does not support images, attached images are passed to the model by name only", "provider", c.LLMProvider)
+	}
+
+	if checker, ok := provider.(modelChecker); ok {
+		timeout := time.Duration(c.RequestTimeout) * time.Second
+		if c.OllamaPullModel {
+			timeout = modelPullTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := checker.EnsureModel(ctx); err != nil {
+			return nil, errors.Wrap(err, "configured model is not available")
+		}
+	}
+
+	resilientProvider, err := resilient.New(provider,
+		resilient.WithTimeout(time.Duration(c.RequestTimeout)*time.Second),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to init resilient provider")
+	}
+	return resilientProvider, nil
+}
+
+func newBaseProvider(c *configuration) (llm.Provider, error) {
+	switch c.LLMProvider {
+	case providerOllama:
+		provider, err := ollama.New(
+			ollama.WithHost(c.OllamaURL),
+			ollama.WithModel(c.OllamaModel),
+			ollama.WithSystemPrompt(c.SystemPrompt),
+			ollama.WithPull(c.OllamaPullModel),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to init ollama")
+		}
+		return provider, nil
+	case providerOpenAI:
+		provider, err := openai.New(
+			openai.WithBaseURL(c.OpenAIBaseURL),
+			openai.WithAPIKey(c.OpenAIAPIKey),
+			openai.WithModel(c.OpenAIModel),
+			openai.WithSystemPrompt(c.SystemPrompt),
+			openai.WithTemperature(c.Temperature),
+			openai.WithMaxTokens(c.MaxTokens),
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to init openai")
+		}
+		return provider, nil
+	default:
+		return nil, errors.Errorf("unsupported LLM provider: %s", c.LLMProvider)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/ptr"
+)
+
+type recordingLogger struct {
+	warnings []string
+}
+
+func (l *recordingLogger) Warn(message string, _ ...any) {
+	l.warnings = append(l.warnings, message)
+}
+
+func TestNewProvider_images(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxImages    *int
+		expectedWarn bool
+	}{
+		{
+			name:         "should_warn_when_images_are_enabled_by_default",
+			expectedWarn: true,
+		},
+		{
+			name:      "should_not_warn_when_images_are_disabled",
+			maxImages: ptr.To(0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &configuration{LLMProvider: providerOpenAI, OpenAIAPIKey: "key", MaxImages: tt.maxImages}
+			c.SetDefaults()
+			log := &recordingLogger{}
+
+			_, err := newProvider(c, log)
+
+			require.NoError(t, err)
+			if tt.expectedWarn {
+				assert.Len(t, log.warnings, 1)
+			} else {
+				assert.Empty(t, log.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/ptr"
+	summaryCommand "github.com/EgorTarasov/summary/server/internal/commands/summary"
+	"github.com/EgorTarasov/summary/server/internal/domain/summary"
+)
+
+// providerDrainTimeout bounds how long deactivation waits for requests still
+// served by replaced providers.
+const providerDrainTimeout = 30 * time.Second
+
+// newSummaryHandler builds the summary service and command handler for the
+// configuration and its parsed prompt templates on top of the provider
+// registry. The handler registers its slash command and job processor,
+// replacing those of a previous handler.
+func (p *Plugin) newSummaryHandler(c *configuration, templates summary.PromptTemplates) *summaryCommand.Handler {
+	serviceOptions := []summary.Option{
+		summary.WithChannels(&p.client.Channel),
+		summary.WithPermalinks(&p.client.Configuration),
+		summary.WithAttachments(&p.client.File),
+		summary.WithImages(ptr.Get(c.MaxImages)),
+		summary.WithPromptTemplates(templates, p.kvstore),
+		summary.WithLanguage(c.SummaryLanguage),
+	}
+	if c.EnableCaching {
+		serviceOptions = append(serviceOptions, summary.WithCache(p.kvstore))
+	}
+	if c.EnableStructuredSummary {
+		serviceOptions = append(serviceOptions, summary.WithStructuredOutput())
+	}
+
+	summaryService := summary.NewService(p.llm, &p.client.User, serviceOptions...)
+
+	return summaryCommand.New(p.client, summaryService, p.authorizer, p.jobQueue, p.kvstore, p.kvstore, p.kvstore, p.kvstore, summaryCommand.Config{
+		BotID:           p.botID,
+		MaxMessages:     c.MaxMessages,
+		PromptTemplates: templates.Names(),
+		ActionURL:       "/plugins/" + p.API.GetPluginID() + "/api/v1/summary/actions",
+	})
+}
+
+// reload switches the running plugin to a new configuration. The new provider
+// must pass its health check before it replaces the current one; otherwise the
+// previous provider and handler stay in place and an error is returned.
+// Requests already running finish on the provider they started with, and jobs
+// already running keep their timeout.
+func (p *Plugin) reload(c *configuration) error {
+	p.reloadLock.Lock()
+	defer p.reloadLock.Unlock()
+
+	if p.llm == nil {
+		// Not activated yet: OnActivate builds everything from the configuration.
+		return nil
+	}
+
+	provider, err := newProvider(c, &p.client.Log)
+	if err != nil {
+		return errors.Wrap(err, "failed to init llm provider")
+	}
+	// Everything that can reject the configuration runs before the swap, which
+	// retires the previous provider.
+	templates, err := c.promptTemplates()
+	if err != nil {
+		return errors.Wrap(err, "failed to parse prompt templates")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.RequestTimeout)*time.Second)
+	defer cancel()
+	if err := p.llm.Swap(ctx, provider); err != nil {
+		return errors.Wrap(err, "failed to switch llm provider")
+	}
+
+	p.setCommand(p.newSummaryHandler(c, templates))
+	// The configuration is validated, so the timeout is positive.
+	if err := p.jobQueue.SetTimeout(jobTimeout(c)); err != nil {
+		return errors.Wrap(err, "failed to update job timeout")
+	}
+
+	p.client.Log.Info("LLM provider reloaded", "provider", c.LLMProvider, "model", provider.Model())
+	return nil
+}
+
+func (p *Plugin) getCommand() Command {
+	p.commandLock.RLock()
+	defer p.commandLock.RUnlock()
+	return p.commandClient
+}
+
+func (p *Plugin) setCommand(command Command) {
+	p.commandLock.Lock()
+	defer p.commandLock.Unlock()
+	p.commandClient = command
+}
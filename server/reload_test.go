@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/EgorTarasov/summary/server/infrustructure/llm/registry"
+	"github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+	"github.com/EgorTarasov/summary/server/store/kvstore"
+)
+
+type staticProvider struct {
+	model string
+}
+
+func (s staticProvider) Generate(context.Context, string) (string, error) { return "", nil }
+func (s staticProvider) GenerateStream(context.Context, string, func(string) error) (string, error) {
+	return "", nil
+}
+func (s staticProvider) GenerateJSON(context.Context, string, json.RawMessage) (string, error) {
+	return "", nil
+}
+func (s staticProvider) HealthCheck(context.Context) error { return nil }
+func (s staticProvider) Model() string                     { return s.model }
+func (s staticProvider) ContextSize() int                  { return 1024 }
+
+func setupReloadTest(t *testing.T) (*Plugin, *plugintest.API) {
+	t.Helper()
+
+	api := &plugintest.API{}
+	api.On("RegisterCommand", mock.Anything).Return(nil).Maybe()
+	api.On("GetPluginID").Return("com.mattermost.plugin-llm-summary").Maybe()
+	api.On("LogInfo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Maybe()
+	client := pluginapi.NewClient(api, &plugintest.Driver{})
+
+	llm, err := registry.New(staticProvider{model: "previous-model"})
+	require.NoError(t, err)
+	queue, err := jobs.New(nil, nil, &client.Log)
+	require.NoError(t, err)
+
+	p := &Plugin{
+		client:     client,
+		llm:        llm,
+		kvstore:    kvstore.NewKVStore(client),
+		authorizer: summary.NewAuthorizer(&client.User, &client.Channel, &client.Post),
+		jobQueue:   queue,
+	}
+	p.SetAPI(api)
+	return p, api
+}
+
+// newOllamaServer fakes an Ollama server that answers /api/tags (health
+// check) and /api/show (model check) with the given statuses.
+func newOllamaServer(tagsStatus, showStatus int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/tags":
+			w.WriteHeader(tagsStatus)
+			_, _ = w.Write([]byte(`{"models":[{"name":"gemma3:12b"}]}`))
+		case "/api/show":
+			w.WriteHeader(showStatus)
+			if showStatus == http.StatusNotFound {
+				_, _ = w.Write([]byte(`{"error":"model not found"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+}
+
+func newOllamaConfiguration(url string) *configuration {
+	c := &configuration{
+		LLMProvider:    providerOllama,
+		OllamaURL:      url,
+		OllamaModel:    "gemma3:12b",
+		RequestTimeout: 1,
+	}
+	c.SetDefaults()
+	return c
+}
+
+func TestPlugin_reload(t *testing.T) {
+	t.Run("should_switch_to_healthy_provider", func(t *testing.T) {
+		p, _ := setupReloadTest(t)
+		server := newOllamaServer(http.StatusOK, http.StatusOK)
+		defer server.Close()
+
+		err := p.reload(newOllamaConfiguration(server.URL))
+
+		require.NoError(t, err)
+		assert.Equal(t, "gemma3:12b", p.llm.Model())
+		assert.NotNil(t, p.getCommand())
+		assert.Equal(t, time.Duration(jobTimeoutFactor)*time.Second, p.jobQueue.Timeout())
+	})
+
+	t.Run("should_keep_previous_provider_when_health_check_fails", func(t *testing.T) {
+		p, _ := setupReloadTest(t)
+		server := newOllamaServer(http.StatusInternalServerError, http.StatusOK)
+		defer server.Close()
+
+		err := p.reload(newOllamaConfiguration(server.URL))
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to switch llm provider")
+		assert.Equal(t, "previous-model", p.llm.Model())
+		assert.Nil(t, p.getCommand())
+	})
+
+	t.Run("should_keep_previous_provider_when_model_is_missing", func(t *testing.T) {
+		p, _ := setupReloadTest(t)
+		server := newOllamaServer(http.StatusOK, http.StatusNotFound)
+		defer server.Close()
+
+		err := p.reload(newOllamaConfiguration(server.URL))
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "model gemma3:12b is not available")
+		assert.Equal(t, "previous-model", p.llm.Model())
+	})
+
+	t.Run("should_keep_previous_provider_when_templates_are_invalid", func(t *testing.T) {
+		p, _ := setupReloadTest(t)
+		server := newOllamaServer(http.StatusOK, http.StatusOK)
+		defer server.Close()
+		c := newOllamaConfiguration(server.URL)
+		c.PromptTemplates = `{"brief": "{{.Messages"}`
+
+		err := p.reload(c)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to parse prompt templates")
+		assert.Equal(t, "previous-model", p.llm.Model())
+		assert.Nil(t, p.getCommand())
+	})
+
+	t.Run("should_skip_before_activation", func(t *testing.T) {
+		p := &Plugin{}
+
+		assert.NoError(t, p.reload(newOllamaConfiguration("http://localhost:11434")))
+	})
+}
@@ -0,0 +1,59 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/EgorTarasov/summary/server/internal/domain/summary"
+)
+
+const (
+	actionItemsKeyPrefix = "action_items-"
+
+	// maxActionItems is how many action items are kept per channel; the oldest
+	// are dropped first.
+	maxActionItems = 100
+)
+
+// GetActionItems returns the action items extracted in the channel, newest first.
+func (kv Client) GetActionItems(channelID string) ([]summary.ExtractedAction, error) {
+	var items []summary.ExtractedAction
+	if err := kv.client.KV.Get(actionItemsKeyPrefix+channelID, &items); err != nil {
+		return nil, errors.Wrap(err, "failed to get action items")
+	}
+	return items, nil
+}
+
+// SaveActionItems adds action items to the ones stored for the channel. An
+// item extracted again from the same post replaces the stored one.
+func (kv Client) SaveActionItems(channelID string, items []summary.ExtractedAction) error {
+	err := kv.client.KV.SetAtomicWithRetries(actionItemsKeyPrefix+channelID, func(oldValue []byte) (interface{}, error) {
+		var stored []summary.ExtractedAction
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &stored); err != nil {
+				return nil, errors.Wrap(err, "failed to decode action items")
+			}
+		}
+
+		merged := make([]summary.ExtractedAction, 0, len(items)+len(stored))
+		seen := make(map[string]struct{}, len(items)+len(stored))
+		for _, item := range append(append([]summary.ExtractedAction{}, items...), stored...) {
+			key := item.PostID + "\x00" + strings.ToLower(item.Text)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			merged = append(merged, item)
+		}
+		if len(merged) > maxActionItems {
+			merged = merged[:maxActionItems]
+		}
+		return merged, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to save action items")
+	}
+	return nil
+}
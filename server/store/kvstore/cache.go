@@ -0,0 +1,97 @@
+package kvstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	summaryCacheKeyPrefix        = "summary_cache-"
+	summaryCacheGenerationPrefix = "summary_cache_gen-"
+	latestSummaryKeyPrefix       = "latest_summary-"
+
+	// summaryCacheTTL is how long a generated summary is reused.
+	summaryCacheTTL = 24 * time.Hour
+
+	// latestSummaryTTL is how long a user's latest channel summary is kept as
+	// context for incremental summaries.
+	latestSummaryTTL = 7 * 24 * time.Hour
+)
+
+type latestSummary struct {
+	Summary    string `json:"summary"`
+	LastPostAt int64  `json:"last_post_at"`
+}
+
+// GetCachedSummary returns the summary cached for the scope (channel or thread
+// root ID) and fingerprint, or "" if there is none.
+func (kv Client) GetCachedSummary(scopeID, fingerprint string) (string, error) {
+	key, err := kv.summaryCacheKey(scopeID, fingerprint)
+	if err != nil {
+		return "", err
+	}
+
+	var summary string
+	if err := kv.client.KV.Get(key, &summary); err != nil {
+		return "", errors.Wrap(err, "failed to get cached summary")
+	}
+	return summary, nil
+}
+
+// SetCachedSummary caches the summary for the scope and fingerprint for summaryCacheTTL.
+func (kv Client) SetCachedSummary(scopeID, fingerprint, summary string) error {
+	key, err := kv.summaryCacheKey(scopeID, fingerprint)
+	if err != nil {
+		return err
+	}
+
+	if _, err := kv.client.KV.Set(key, summary, pluginapi.SetExpiry(summaryCacheTTL)); err != nil {
+		return errors.Wrap(err, "failed to cache summary")
+	}
+	return nil
+}
+
+// InvalidateSummaries drops every summary cached for the scope. Instead of
+// listing keys, the scope generation is rotated so old entries are no longer
+// addressable and expire on their own.
+func (kv Client) InvalidateSummaries(scopeID string) error {
+	_, err := kv.client.KV.Set(summaryCacheGenerationPrefix+scopeID, model.NewId(), pluginapi.SetExpiry(summaryCacheTTL))
+	if err != nil {
+		return errors.Wrap(err, "failed to invalidate cached summaries")
+	}
+	return nil
+}
+
+// GetLatestSummary returns the latest summary of the channel generated for the
+// user and the creation time of the newest post it covers.
+func (kv Client) GetLatestSummary(userID, channelID string) (string, int64, error) {
+	var latest latestSummary
+	if err := kv.client.KV.Get(latestSummaryKeyPrefix+userID+"-"+channelID, &latest); err != nil {
+		return "", 0, errors.Wrap(err, "failed to get latest summary")
+	}
+	return latest.Summary, latest.LastPostAt, nil
+}
+
+// SetLatestSummary stores the latest summary of the channel generated for the user.
+func (kv Client) SetLatestSummary(userID, channelID, summary string, lastPostAt int64) error {
+	latest := latestSummary{Summary: summary, LastPostAt: lastPostAt}
+	if _, err := kv.client.KV.Set(latestSummaryKeyPrefix+userID+"-"+channelID, latest, pluginapi.SetExpiry(latestSummaryTTL)); err != nil {
+		return errors.Wrap(err, "failed to save latest summary")
+	}
+	return nil
+}
+
+func (kv Client) summaryCacheKey(scopeID, fingerprint string) (string, error) {
+	var generation string
+	if err := kv.client.KV.Get(summaryCacheGenerationPrefix+scopeID, &generation); err != nil {
+		return "", errors.Wrap(err, "failed to get cache generation")
+	}
+
+	hash := sha256.Sum256([]byte(scopeID + ":" + generation + ":" + fingerprint))
+	return summaryCacheKeyPrefix + hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,176 @@
+package kvstore
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/EgorTarasov/summary/server/internal/domain/digest"
+)
+
+const (
+	digestScheduleKeyPrefix = "digest_schedule-"
+	digestSchedulesKey      = "digest_schedules"
+
+	personalDigestKeyPrefix = "personal_digest-"
+	personalDigestsKey      = "personal_digests"
+)
+
+// GetDigestSchedule returns the digest schedule of the channel or nil if it
+// has none.
+func (kv Client) GetDigestSchedule(channelID string) (*digest.Schedule, error) {
+	schedule, err := kv.getSchedule(digestScheduleKeyPrefix + channelID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get digest schedule")
+	}
+	return schedule, nil
+}
+
+// SaveDigestSchedule stores the schedule and adds the channel to the list of
+// scheduled channels.
+func (kv Client) SaveDigestSchedule(schedule *digest.Schedule) error {
+	return kv.saveSchedule(digestScheduleKeyPrefix, digestSchedulesKey, schedule.ChannelID, schedule)
+}
+
+// UpdateDigestSchedule atomically applies update to the schedule of the
+// channel. An error returned by update is passed through and leaves the
+// schedule unchanged.
+func (kv Client) UpdateDigestSchedule(channelID string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error) {
+	return kv.updateSchedule(digestScheduleKeyPrefix+channelID, update)
+}
+
+// DeleteDigestSchedule removes the schedule of the channel.
+func (kv Client) DeleteDigestSchedule(channelID string) error {
+	return kv.deleteSchedule(digestScheduleKeyPrefix, digestSchedulesKey, channelID)
+}
+
+// ListDigestChannels returns the IDs of the channels that have a digest schedule.
+func (kv Client) ListDigestChannels() ([]string, error) {
+	return kv.listScheduled(digestSchedulesKey)
+}
+
+// GetPersonalDigest returns the personal digest schedule of the user or nil
+// if the user has not opted in.
+func (kv Client) GetPersonalDigest(userID string) (*digest.Schedule, error) {
+	schedule, err := kv.getSchedule(personalDigestKeyPrefix + userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get personal digest schedule")
+	}
+	return schedule, nil
+}
+
+// SavePersonalDigest stores the personal digest schedule of its creator and
+// adds the user to the list of users with a personal digest.
+func (kv Client) SavePersonalDigest(schedule *digest.Schedule) error {
+	return kv.saveSchedule(personalDigestKeyPrefix, personalDigestsKey, schedule.CreatedBy, schedule)
+}
+
+// UpdatePersonalDigest atomically applies update to the personal digest
+// schedule of the user. An error returned by update is passed through and
+// leaves the schedule unchanged.
+func (kv Client) UpdatePersonalDigest(userID string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error) {
+	return kv.updateSchedule(personalDigestKeyPrefix+userID, update)
+}
+
+// DeletePersonalDigest removes the personal digest schedule of the user.
+func (kv Client) DeletePersonalDigest(userID string) error {
+	return kv.deleteSchedule(personalDigestKeyPrefix, personalDigestsKey, userID)
+}
+
+// ListPersonalDigestUsers returns the IDs of the users with a personal digest
+// schedule.
+func (kv Client) ListPersonalDigestUsers() ([]string, error) {
+	return kv.listScheduled(personalDigestsKey)
+}
+
+func (kv Client) getSchedule(key string) (*digest.Schedule, error) {
+	var schedule *digest.Schedule
+	if err := kv.client.KV.Get(key, &schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// saveSchedule stores the schedule under prefix+id and adds id to the index.
+func (kv Client) saveSchedule(prefix, indexKey, id string, schedule *digest.Schedule) error {
+	if _, err := kv.client.KV.Set(prefix+id, schedule); err != nil {
+		return errors.Wrap(err, "failed to save digest schedule")
+	}
+
+	err := kv.updateIndex(indexKey, func(ids []string) []string {
+		for _, existing := range ids {
+			if existing == id {
+				return ids
+			}
+		}
+		return append(ids, id)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to register digest schedule")
+	}
+	return nil
+}
+
+func (kv Client) updateSchedule(key string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error) {
+	var updated *digest.Schedule
+	err := kv.client.KV.SetAtomicWithRetries(key, func(oldValue []byte) (interface{}, error) {
+		if len(oldValue) == 0 {
+			return nil, errors.New("digest schedule does not exist")
+		}
+		var schedule digest.Schedule
+		if err := json.Unmarshal(oldValue, &schedule); err != nil {
+			return nil, errors.Wrap(err, "failed to decode digest schedule")
+		}
+		if err := update(&schedule); err != nil {
+			return nil, err
+		}
+		updated = &schedule
+		return schedule, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// deleteSchedule removes the schedule stored under prefix+id and drops id
+// from the index.
+func (kv Client) deleteSchedule(prefix, indexKey, id string) error {
+	if err := kv.client.KV.Delete(prefix + id); err != nil {
+		return errors.Wrap(err, "failed to delete digest schedule")
+	}
+
+	err := kv.updateIndex(indexKey, func(ids []string) []string {
+		kept := ids[:0]
+		for _, existing := range ids {
+			if existing != id {
+				kept = append(kept, existing)
+			}
+		}
+		return kept
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to unregister digest schedule")
+	}
+	return nil
+}
+
+func (kv Client) listScheduled(indexKey string) ([]string, error) {
+	var ids []string
+	if err := kv.client.KV.Get(indexKey, &ids); err != nil {
+		return nil, errors.Wrap(err, "failed to list digest schedules")
+	}
+	return ids, nil
+}
+
+func (kv Client) updateIndex(indexKey string, update func(ids []string) []string) error {
+	return kv.client.KV.SetAtomicWithRetries(indexKey, func(oldValue []byte) (interface{}, error) {
+		var ids []string
+		if len(oldValue) > 0 {
+			if err := json.Unmarshal(oldValue, &ids); err != nil {
+				return nil, errors.Wrap(err, "failed to decode digest schedules")
+			}
+		}
+		return update(ids), nil
+	})
+}
@@ -0,0 +1,135 @@
+package kvstore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
+const (
+	jobKeyPrefix = "job-"
+	jobQueueKey  = "job_queue"
+	// jobRunningKey holds the IDs of jobs claimed by workers.
+	jobRunningKey = "job_running"
+
+	// jobTTL is how long job records, including results, are kept.
+	jobTTL = 24 * time.Hour
+)
+
+var errQueueEmpty = errors.New("job queue is empty")
+
+// SaveJob stores the job record. Records expire after jobTTL.
+func (kv Client) SaveJob(job *jobs.Job) error {
+	if _, err := kv.client.KV.Set(jobKeyPrefix+job.ID, job, pluginapi.SetExpiry(jobTTL)); err != nil {
+		return errors.Wrap(err, "failed to save job")
+	}
+	return nil
+}
+
+// GetJob returns the job record or nil if it does not exist.
+func (kv Client) GetJob(id string) (*jobs.Job, error) {
+	var job *jobs.Job
+	if err := kv.client.KV.Get(jobKeyPrefix+id, &job); err != nil {
+		return nil, errors.Wrap(err, "failed to get job")
+	}
+	return job, nil
+}
+
+// PushJobID appends the job ID to the end of the queue.
+func (kv Client) PushJobID(id string) error {
+	err := kv.client.KV.SetAtomicWithRetries(jobQueueKey, func(oldValue []byte) (interface{}, error) {
+		queue, err := decodeQueue(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		return append(queue, id), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to push job id")
+	}
+	return nil
+}
+
+// PopJobID removes and returns the first job ID in the queue or "" if the queue is empty.
+func (kv Client) PopJobID() (string, error) {
+	var id string
+	err := kv.client.KV.SetAtomicWithRetries(jobQueueKey, func(oldValue []byte) (interface{}, error) {
+		queue, err := decodeQueue(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		if len(queue) == 0 {
+			return nil, errQueueEmpty
+		}
+		id = queue[0]
+		return queue[1:], nil
+	})
+	if errors.Is(err, errQueueEmpty) {
+		return "", nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "failed to pop job id")
+	}
+	return id, nil
+}
+
+// AddRunningJobID records that a worker claimed the job.
+func (kv Client) AddRunningJobID(id string) error {
+	err := kv.client.KV.SetAtomicWithRetries(jobRunningKey, func(oldValue []byte) (interface{}, error) {
+		ids, err := decodeQueue(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		return append(ids, id), nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to add running job id")
+	}
+	return nil
+}
+
+// RemoveRunningJobID forgets a claimed job once it has finished.
+func (kv Client) RemoveRunningJobID(id string) error {
+	err := kv.client.KV.SetAtomicWithRetries(jobRunningKey, func(oldValue []byte) (interface{}, error) {
+		ids, err := decodeQueue(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		remaining := ids[:0]
+		for _, running := range ids {
+			if running != id {
+				remaining = append(remaining, running)
+			}
+		}
+		return remaining, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to remove running job id")
+	}
+	return nil
+}
+
+// ListRunningJobIDs returns the IDs of the jobs claimed by workers.
+func (kv Client) ListRunningJobIDs() ([]string, error) {
+	var ids []string
+	if err := kv.client.KV.Get(jobRunningKey, &ids); err != nil {
+		return nil, errors.Wrap(err, "failed to list running job ids")
+	}
+	return ids, nil
+}
+
+func decodeQueue(data []byte) ([]string, error) {
+	if len(data) == 0 {
+		return []string{}, nil
+	}
+
+	var queue []string
+	if err := json.Unmarshal(data, &queue); err != nil {
+		return nil, errors.Wrap(err, "failed to decode job queue")
+	}
+	return queue, nil
+}
@@ -1,6 +1,43 @@
 package kvstore
 
+import (
+	"github.com/EgorTarasov/summary/server/internal/domain/digest"
+	"github.com/EgorTarasov/summary/server/internal/domain/summary"
+	"github.com/EgorTarasov/summary/server/internal/jobs"
+)
+
 type KVStore interface {
 	// Define your methods here. This package is used to access the KVStore pluginapi methods.
 	GetTemplateData(userID string) (string, error)
+
+	SaveJob(job *jobs.Job) error
+	GetJob(id string) (*jobs.Job, error)
+	PushJobID(id string) error
+	PopJobID() (string, error)
+	AddRunningJobID(id string) error
+	RemoveRunningJobID(id string) error
+	ListRunningJobIDs() ([]string, error)
+
+	GetCachedSummary(scopeID, fingerprint string) (string, error)
+	SetCachedSummary(scopeID, fingerprint, summary string) error
+	InvalidateSummaries(scopeID string) error
+	GetLatestSummary(userID, channelID string) (string, int64, error)
+	SetLatestSummary(userID, channelID, summary string, lastPostAt int64) error
+
+	GetPromptTemplate(scopeID string) (string, error)
+	SetPromptTemplate(scopeID, name string) error
+
+	GetActionItems(channelID string) ([]summary.ExtractedAction, error)
+	SaveActionItems(channelID string, items []summary.ExtractedAction) error
+
+	GetDigestSchedule(channelID string) (*digest.Schedule, error)
+	SaveDigestSchedule(schedule *digest.Schedule) error
+	UpdateDigestSchedule(channelID string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error)
+	DeleteDigestSchedule(channelID string) error
+	ListDigestChannels() ([]string, error)
+	GetPersonalDigest(userID string) (*digest.Schedule, error)
+	SavePersonalDigest(schedule *digest.Schedule) error
+	UpdatePersonalDigest(userID string, update func(schedule *digest.Schedule) error) (*digest.Schedule, error)
+	DeletePersonalDigest(userID string) error
+	ListPersonalDigestUsers() ([]string, error)
 }
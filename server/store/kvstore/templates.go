@@ -0,0 +1,33 @@
+package kvstore
+
+import (
+	"github.com/pkg/errors"
+)
+
+const promptTemplateKeyPrefix = "prompt_template-"
+
+// GetPromptTemplate returns the name of the prompt template selected for the
+// channel or team, or "" if none is selected.
+func (kv Client) GetPromptTemplate(scopeID string) (string, error) {
+	var name string
+	if err := kv.client.KV.Get(promptTemplateKeyPrefix+scopeID, &name); err != nil {
+		return "", errors.Wrap(err, "failed to get prompt template")
+	}
+	return name, nil
+}
+
+// SetPromptTemplate selects the prompt template for the channel or team. An
+// empty name clears the selection.
+func (kv Client) SetPromptTemplate(scopeID, name string) error {
+	if name == "" {
+		if err := kv.client.KV.Delete(promptTemplateKeyPrefix + scopeID); err != nil {
+			return errors.Wrap(err, "failed to reset prompt template")
+		}
+		return nil
+	}
+
+	if _, err := kv.client.KV.Set(promptTemplateKeyPrefix+scopeID, name); err != nil {
+		return errors.Wrap(err, "failed to set prompt template")
+	}
+	return nil
+}